@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+
+	"github.com/0xBow-io/base-eas-asp/core/aggregator"
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+	"github.com/pkg/errors"
+)
+
+func openReplayFeed(path string, speed float64) (*aggregator.ReplayReportFeed, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	feed, err := aggregator.NewReplayReportFeed(f, speed)
+	return feed, errors.Wrap(err, "failed to read "+path)
+}
+
+// readArchive returns the reports of an archive in their original order
+func readArchive(path string) ([]reportDB.Report, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries, err := reportDB.ReadArchive(f)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read "+path)
+	}
+	reports := make([]reportDB.Report, len(entries))
+	for i, e := range entries {
+		reports[i] = e.Report
+	}
+	return reports, nil
+}
+
+// exportReports writes the reports of rdb to an archive,
+// the archive is replaced once written
+func exportReports(rdb *reportDB.ReportDB, path string) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := rdb.Export(f); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
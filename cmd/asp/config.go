@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/0xBow-io/base-eas-asp/core/aggregator"
+	"github.com/0xBow-io/base-eas-asp/core/auditor"
+	"github.com/0xBow-io/base-eas-asp/core/verifier"
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	poa "github.com/0xBow-io/base-eas-asp/pkg/proof-of-audit"
+	quiknode "github.com/0xBow-io/base-eas-asp/pkg/quiknode"
+	"github.com/pkg/errors"
+)
+
+var errUsage = errors.New("invalid usage")
+
+// Config of the ASP node, set by flags
+type Config struct {
+	// JSON file with the privacy pool events loaded into StateDB
+	EventsFile string
+	// webhook secret & url path, the private inputs of the proof of audit,
+	// the secret is read from SecretFile when set
+	Secret     string
+	SecretFile string
+	URLPath    string
+
+	// JSON-RPC endpoint the EAS events are checked against before
+	// their membership changes are submitted, no check if empty
+	ChainCheckURL string
+	// membership of the accounts which attestation expired, EXCLUSION if
+	// empty. The expirations are read with -chain-check, it is required.
+	ExpiryDowngrade string
+	// JSON policy deriving the memberships, the membership of the last EAS
+	// event if empty. The attestations of its other schemas are read with
+	// -chain-check, it is required for them.
+	PolicyFile string
+
+	// use the mock report feed, webhook credentials & prover
+	Mock       bool
+	MockPeriod time.Duration
+
+	// brotli compressed report archives, replayed through the
+	// aggregator & written on shutdown
+	ReplayFile  string
+	ReplaySpeed float64
+	ExportFile  string
+
+	// feeds collected by the aggregator, the default QuickNode feeds if empty
+	Feeds feedsFlag
+
+	// address the webhook deliveries are received on
+	WebhookAddr string
+
+	// JSON-RPC endpoint polled for the EAS logs instead of the webhook feeds
+	RPCURL           string
+	RPCFromBlock     uint64
+	RPCConfirmations uint64
+	// JSON-RPC endpoint of the blocks missed between webhook deliveries
+	BackfillURL string
+
+	HTTPAddr string
+	GRPCAddr string
+	// bearer token of the gRPC report & change request services,
+	// they aren't served without it
+	GRPCToken       string
+	PublishInterval time.Duration
+}
+
+func (c *Config) registerCredentialFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Secret, "secret", os.Getenv("ASP_WEBHOOK_SECRET"), "webhook secret (default $ASP_WEBHOOK_SECRET)")
+	fs.StringVar(&c.SecretFile, "secret-file", "", "file holding the webhook secret, overrides -secret")
+	fs.StringVar(&c.URLPath, "url-path", os.Getenv("ASP_WEBHOOK_URL_PATH"), "webhook url path (default $ASP_WEBHOOK_URL_PATH)")
+}
+
+func (c *Config) registerNodeFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.EventsFile, "events", "", "JSON file of privacy pool events to load into StateDB")
+	c.registerCredentialFlags(fs)
+	fs.StringVar(&c.ChainCheckURL, "chain-check", "", "JSON-RPC endpoint of a Base node to check the EAS events against before submitting membership changes")
+	fs.StringVar(&c.ExpiryDowngrade, "expiry-downgrade", "", "membership of the accounts which attestation expired, exclusion or partial_inclusion (default exclusion), requires -chain-check which reads the expirations")
+	fs.StringVar(&c.PolicyFile, "policy", "", "JSON policy deriving the memberships from the attestations of the accounts (default the membership of their last EAS event), requires -chain-check for the schemas other than the account verification")
+	fs.BoolVar(&c.Mock, "mock", false, "use the mock report feed & prover and seed events for every reported account")
+}
+
+func (c *Config) registerServeFlags(fs *flag.FlagSet) {
+	c.registerNodeFlags(fs)
+	fs.DurationVar(&c.MockPeriod, "mock-period", 5*time.Second, "period of the mock report feed")
+	fs.StringVar(&c.ReplayFile, "replay", "", "report archive to replay instead of the mock report feed")
+	fs.Float64Var(&c.ReplaySpeed, "replay-speed", 0, "speed up of the time between replayed reports, 0 to replay without delay")
+	fs.StringVar(&c.ExportFile, "export", "", "file to export the reports to on shutdown")
+	fs.StringVar(&c.WebhookAddr, "webhook", ":8081", "address the QuickNode webhook deliveries are received on at -url-path, the default feed, empty to disable")
+	fs.StringVar(&c.RPCURL, "rpc", "", "JSON-RPC endpoint of a Base node to poll the EAS logs from instead of the webhook feeds")
+	fs.Uint64Var(&c.RPCFromBlock, "rpc-from", 0, "first block polled with -rpc, 0 for the head block")
+	fs.Uint64Var(&c.RPCConfirmations, "rpc-confirmations", 5, "blocks behind the head before the logs polled with -rpc are read")
+	fs.StringVar(&c.BackfillURL, "backfill", "", "JSON-RPC endpoint of a Base node to backfill the blocks missed between webhook deliveries from")
+	fs.Var(&c.Feeds, "feed", "notification ID of a feed to collect with its payload format, id[:quickalerts|streams|logs], repeatable (default the Coinbase EAS feed)")
+	fs.StringVar(&c.HTTPAddr, "http", ":8080", "address of the HTTP API, empty to disable")
+	fs.StringVar(&c.GRPCAddr, "grpc", "localhost:9090", "address of the gRPC services, empty to disable")
+	fs.StringVar(&c.GRPCToken, "grpc-token", os.Getenv("ASP_GRPC_TOKEN"), "bearer token of the gRPC report & change request services, served only with a token (default $ASP_GRPC_TOKEN)")
+	fs.DurationVar(&c.PublishInterval, "publish-interval", time.Minute, "interval between publications of the set roots")
+}
+
+// feedsFlag configures the feeds of the aggregator, by notification ID
+type feedsFlag map[string]aggregator.FeedConfig
+
+func (f *feedsFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	var feeds []string
+	for _, config := range *f {
+		feeds = append(feeds, config.NotificationID+":"+string(config.Format))
+	}
+	sort.Strings(feeds)
+	return strings.Join(feeds, ",")
+}
+
+func (f *feedsFlag) Set(v string) error {
+	id, name, _ := strings.Cut(v, ":")
+	if id == "" {
+		return errors.New("missing notification ID")
+	}
+	format, err := quiknode.ParseFormat(name)
+	if err != nil {
+		return err
+	}
+	if *f == nil {
+		*f = make(feedsFlag)
+	}
+	(*f)[id] = aggregator.FeedConfig{NotificationID: id, Format: format}
+	return nil
+}
+
+// notification ID of the reports polled with -rpc
+const rpcFeedID = "rpc"
+
+// feeds returns the configs of the feeds collected by the aggregator
+func (c *Config) feeds() map[string]aggregator.FeedConfig {
+	if c.RPCURL != "" {
+		return map[string]aggregator.FeedConfig{rpcFeedID: {NotificationID: rpcFeedID, Format: quiknode.FormatLogs}}
+	}
+	if len(c.Feeds) == 0 {
+		return aggregator.DefaultFeeds()
+	}
+	return c.Feeds
+}
+
+// credentials returns the webhook secret & url path,
+// the ones of the mock feed in mock mode
+func (c *Config) credentials() (string, string, error) {
+	secret := c.Secret
+	if c.SecretFile != "" {
+		b, err := os.ReadFile(c.SecretFile)
+		if err != nil {
+			return "", "", errors.Wrap(err, "failed to read the webhook secret")
+		}
+		secret = strings.TrimSpace(string(b))
+	}
+	if c.Mock && secret == "" && c.URLPath == "" {
+		return aggregator.MockSecret, aggregator.MockURLPath, nil
+	}
+	if secret == "" || c.URLPath == "" {
+		return "", "", errors.New("missing webhook secret or url path, set -secret & -url-path or use -mock")
+	}
+	return secret, c.URLPath, nil
+}
+
+// policy returns the policy of -policy, nil without
+func (c *Config) policy() (*eas.Policy, error) {
+	if c.PolicyFile == "" {
+		return nil, nil
+	}
+	f, err := os.Open(c.PolicyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the policy")
+	}
+	defer f.Close()
+	p, err := eas.ParsePolicy(f)
+	return p, errors.Wrap(err, c.PolicyFile)
+}
+
+func (c *Config) prover() (auditor.Prover, error) {
+	if c.Mock {
+		return auditor.NewMockProver(), nil
+	}
+	secret, urlPath, err := c.credentials()
+	if err != nil {
+		return nil, err
+	}
+	return auditor.NewSP1Prover(secret, urlPath), nil
+}
+
+// proofVerifier returns the SP1 verifier of the proofs of audit, the mock
+// proofs can't be verified so only their public values are checked
+func (c *Config) proofVerifier() verifier.ProofVerifier {
+	if c.Mock {
+		return verifier.ProofVerifierFunc(poa.CheckProof)
+	}
+	return verifier.ProofVerifierFunc(poa.VerifyProof)
+}
+
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("asp "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+// parse returns errUsage on invalid flags
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+func readJSON(path string, v interface{}) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(json.Unmarshal(b, v), "failed to decode "+path)
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,75 @@
+/*
+asp runs the Association Set Provider.
+
+Usage:
+
+	asp <command> [flags]
+
+Commands:
+
+	serve   run the aggregator, auditor & verifier and serve the HTTP & gRPC APIs
+	replay  audit the reports of a file or archive and print the outcomes & set roots
+	prove   generate the proofs of audit of a report file or run the Go checker
+	verify  verify the proofs of audit written by prove
+	root    query the set roots of a running server
+
+Run `asp <command> -h` for the flags of a command.
+*/
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `usage: asp <command> [flags]
+
+commands:
+  serve   run the aggregator, auditor & verifier and serve the HTTP & gRPC APIs
+  replay  audit the reports of a file or archive and print the outcomes & set roots
+  prove   generate the proofs of audit of a report file or run the Go checker
+  verify  verify the proofs of audit written by prove
+  root    query the set roots of a running server
+
+run "asp <command> -h" for the flags of a command
+`
+
+func run(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return errUsage
+	}
+
+	switch cmd, args := args[0], args[1:]; cmd {
+	case "serve":
+		return runServe(args, stdout, stderr)
+	case "replay":
+		return runReplay(args, stdout, stderr)
+	case "prove":
+		return runProve(args, stdout, stderr)
+	case "verify":
+		return runVerify(args, stdout, stderr)
+	case "root":
+		return runRoot(args, stdout, stderr)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return nil
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", cmd, usage)
+		return errUsage
+	}
+}
+
+func main() {
+	switch err := run(os.Args[1:], os.Stdout, os.Stderr); {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+	case errors.Is(err, errUsage):
+		os.Exit(2)
+	default:
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/0xBow-io/base-eas-asp/core/aggregator"
+	"github.com/0xBow-io/base-eas-asp/core/auditor"
+	"github.com/0xBow-io/base-eas-asp/core/verifier"
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	poa "github.com/0xBow-io/base-eas-asp/pkg/proof-of-audit"
+	quiknode "github.com/0xBow-io/base-eas-asp/pkg/quiknode"
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+	sDB "github.com/0xBow-io/base-eas-asp/pkg/statedb"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func mockReports(t *testing.T, n int) []reportDB.Report {
+	feed := make(chan reportDB.Report)
+	require.NoError(t, aggregator.NewMockReportFeedWithPeriod(time.Millisecond).SubscribeTo(aggregator.CoinbaseEASFeedID, feed))
+
+	reports := make([]reportDB.Report, n)
+	for i := range reports {
+		reports[i] = <-feed
+	}
+	return reports
+}
+
+func writeFile(t *testing.T, name string, v interface{}) string {
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, b, 0o600))
+	return path
+}
+
+func Test_Run_Usage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	require.ErrorIs(t, run(nil, &stdout, &stderr), errUsage)
+	require.ErrorIs(t, run([]string{"bogus"}, &stdout, &stderr), errUsage)
+	require.ErrorIs(t, run([]string{"replay", "-bogus"}, &stdout, &stderr), errUsage)
+	require.ErrorIs(t, run([]string{"replay"}, &stdout, &stderr), errUsage)
+
+	// the SP1 prover requires the webhook credentials
+	path := writeFile(t, "reports.json", mockReports(t, 1))
+	require.ErrorContains(t, run([]string{"replay", "-reports", path}, &stdout, &stderr), "missing webhook secret")
+	// the expirations are read from the chain
+	require.ErrorContains(t, run([]string{"replay", "-mock", "-expiry-downgrade", "exclusion", "-reports", path}, &stdout, &stderr), "requires -chain-check")
+}
+
+func Test_Config_Feeds(t *testing.T) {
+	var cfg Config
+	fs := newFlagSet("serve", io.Discard)
+	cfg.registerServeFlags(fs)
+	require.Equal(t, aggregator.DefaultFeeds(), cfg.feeds())
+
+	require.NoError(t, parse(fs, []string{"-feed", "alerts", "-feed", "stream:streams", "-feed", "logs:LOGS"}))
+	require.Equal(t, map[string]aggregator.FeedConfig{
+		"alerts": {NotificationID: "alerts", Format: quiknode.FormatQuickAlerts},
+		"stream": {NotificationID: "stream", Format: quiknode.FormatStreams},
+		"logs":   {NotificationID: "logs", Format: quiknode.FormatLogs},
+	}, cfg.feeds())
+	require.Equal(t, "alerts:quickalerts,logs:logs,stream:streams", cfg.Feeds.String())
+
+	for _, v := range []string{":logs", "feed:blocks"} {
+		require.Error(t, cfg.Feeds.Set(v), v)
+	}
+
+	// the logs polled with -rpc replace the webhook feeds
+	require.NoError(t, parse(fs, []string{"-rpc", "http://localhost:8545"}))
+	require.Equal(t, map[string]aggregator.FeedConfig{
+		rpcFeedID: {NotificationID: rpcFeedID, Format: quiknode.FormatLogs},
+	}, cfg.feeds())
+}
+
+func Test_Run_Replay(t *testing.T) {
+	reports := mockReports(t, 3)
+	path := writeFile(t, "reports.json", reports)
+
+	var stdout, stderr bytes.Buffer
+	require.NoError(t, run([]string{"replay", "-mock", "-reports", path}, &stdout, &stderr))
+
+	var res ReplayResult
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &res))
+	require.Equal(t, 3, res.Reports)
+	require.Empty(t, res.Errors)
+	require.Len(t, res.Outcomes, 3)
+	for _, o := range res.Outcomes {
+		require.Equal(t, verifier.APPLIED, o.Status)
+		require.Equal(t, sDB.NONE, o.From)
+		require.Equal(t, sDB.INCLUSION, o.To)
+	}
+	require.Equal(t, uint64(1), res.Roots.Version)
+	require.Equal(t, 3, res.Roots.Roots[sDB.INCLUSION].Size)
+	require.Equal(t, 0, res.Roots.Roots[sDB.EXCLUSION].Size)
+
+	// one report per line, with an unparsable report
+	var lines bytes.Buffer
+	for _, r := range append([]reportDB.Report{{Body: "{"}}, reports...) {
+		b, err := json.Marshal(r)
+		require.NoError(t, err)
+		lines.Write(append(b, '\n'))
+	}
+	path = filepath.Join(t.TempDir(), "reports.jsonl")
+	require.NoError(t, os.WriteFile(path, lines.Bytes(), 0o600))
+
+	got, err := readReports(path)
+	require.NoError(t, err)
+	require.Equal(t, reports, got[1:])
+
+	stdout.Reset()
+	require.NoError(t, run([]string{"replay", "-mock", "-reports", path}, &stdout, &stderr))
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &res))
+	require.Equal(t, 4, res.Reports)
+	require.Len(t, res.Errors, 1)
+	require.Len(t, res.Outcomes, 3)
+}
+
+func Test_Run_Policy(t *testing.T) {
+	path := writeFile(t, "reports.json", mockReports(t, 2))
+	var stdout, stderr bytes.Buffer
+	require.ErrorContains(t, run([]string{"replay", "-mock", "-policy", filepath.Join(t.TempDir(), "missing"), "-reports", path}, &stdout, &stderr), "failed to read the policy")
+	invalid := writeFile(t, "invalid.json", map[string]interface{}{"default": "member"})
+	require.ErrorIs(t, run([]string{"replay", "-mock", "-policy", invalid, "-reports", path}, &stdout, &stderr), eas.ErrInvalidPolicy)
+
+	// the country verifications are read from the chain
+	country := writeFile(t, "country.json", eas.Policy{
+		Rules:   []eas.Rule{{Membership: sDB.INCLUSION, When: eas.Condition{Attestation: &eas.Match{Schema: "country", State: eas.StateValid}}}},
+		Default: sDB.PARTIAL_INCLUSION,
+	})
+	require.ErrorContains(t, run([]string{"replay", "-mock", "-policy", country, "-reports", path}, &stdout, &stderr), "requires -chain-check")
+
+	// the verified accounts are only partially included
+	partial := writeFile(t, "partial.json", eas.Policy{
+		Rules:   []eas.Rule{{Name: "verified", Membership: sDB.PARTIAL_INCLUSION, When: eas.Condition{Attestation: &eas.Match{Schema: "account", State: eas.StateValid}}}},
+		Default: sDB.EXCLUSION,
+	})
+	require.NoError(t, run([]string{"replay", "-mock", "-policy", partial, "-reports", path}, &stdout, &stderr))
+	var res ReplayResult
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &res))
+	require.Empty(t, res.Errors)
+	require.Len(t, res.Outcomes, 2)
+	for _, o := range res.Outcomes {
+		require.Equal(t, verifier.APPLIED, o.Status)
+		require.Equal(t, sDB.PARTIAL_INCLUSION, o.To)
+	}
+}
+
+func Test_Replay_Events(t *testing.T) {
+	reports := mockReports(t, 2)
+
+	// only the account of the first report has events
+	cfg := Config{Mock: true}
+	n, err := newNode(cfg, func(rdb *reportDB.ReportDB) auditor.ReportDB { return auditDB{rdb} })
+	require.NoError(t, err)
+	require.NoError(t, seedEvents(n.sdb, reports[0]))
+	n.cfg.Mock = false
+
+	res, err := n.replay(reports)
+	require.NoError(t, err)
+	require.Len(t, res.Outcomes, 1)
+	require.Equal(t, 1, res.Roots.Roots[sDB.INCLUSION].Size)
+}
+
+func Test_Prove(t *testing.T) {
+	r := mockReports(t, 1)[0]
+	events, _, err := r.Parse()
+	require.NoError(t, err)
+
+	targets, err := auditEvents(r, events[0].Account.Hex(), "")
+	require.NoError(t, err)
+	require.Equal(t, []eas.EAS{events[0]}, targets)
+
+	proofs, err := prove(auditor.NewMockProver(), r, targets)
+	require.NoError(t, err)
+	require.Len(t, proofs, 1)
+	require.Equal(t, events[0], proofs[0].Event)
+	require.True(t, proofs[0].Committed)
+	require.NotEmpty(t, proofs[0].Proof.Proof)
+
+	_, err = auditEvents(r, "0x01", "")
+	require.Error(t, err)
+
+	// explicit IDs are audited even when they are not in the report
+	targets, err = auditEvents(r, "0x01", "0x02")
+	require.NoError(t, err)
+	require.Equal(t, common.HexToHash("0x01"), targets[0].Account)
+	require.Equal(t, common.HexToHash("0x02"), targets[0].UUID)
+
+	checked, err := check(aggregator.MockSecret, aggregator.MockURLPath, r, targets)
+	require.NoError(t, err)
+	require.False(t, checked[0].Committed)
+}
+
+func Test_Check_Compressed(t *testing.T) {
+	r := mockReports(t, 1)[0]
+	events, _, err := r.Parse()
+	require.NoError(t, err)
+
+	// the webhook signs the compressed body
+	body, err := reportDB.Encode(reportDB.EncodingGzip, []byte(r.Body))
+	require.NoError(t, err)
+	r.Header.ContentEncoding = reportDB.EncodingGzip
+	r.Body = body
+	bodyHash := sha256.Sum256([]byte(aggregator.MockURLPath + r.Body))
+	h := hmac.New(sha256.New, []byte(aggregator.MockSecret))
+	h.Write([]byte(r.Header.Nonce + hex.EncodeToString(bodyHash[:]) + r.Header.Timestamp))
+	r.Header.Signature = base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+	checked, err := check(aggregator.MockSecret, aggregator.MockURLPath, r, events)
+	require.NoError(t, err)
+	require.True(t, checked[0].Committed)
+
+	r.Header.Signature = "signature"
+	checked, err = check(aggregator.MockSecret, aggregator.MockURLPath, r, events)
+	require.NoError(t, err)
+	require.False(t, checked[0].Committed)
+}
+
+func Test_Verify(t *testing.T) {
+	r := mockReports(t, 1)[0]
+	events, _, err := r.Parse()
+	require.NoError(t, err)
+
+	proofs, err := prove(auditor.NewMockProver(), r, events)
+	require.NoError(t, err)
+
+	// the mock proofs are only valid once accepted by the verifier
+	for _, v := range verify(proofs, nil, "", "", verifier.ProofVerifierFunc(poa.VerifySP1)) {
+		require.False(t, v.Valid)
+		require.NotEmpty(t, v.Reason)
+	}
+
+	// the mock proofs stand for proofs accepted by the SP1 verifier
+	pv := verifier.ProofVerifierFunc(func(p poa.SP1Proof) error {
+		if !strings.HasPrefix(p.Proof, "mock:") {
+			return poa.ErrInvalidSP1
+		}
+		return nil
+	})
+	for _, v := range verify(proofs, nil, "", "", pv) {
+		require.True(t, v.Valid, v.Reason)
+	}
+	for _, v := range verify(proofs, &r, aggregator.MockSecret, aggregator.MockURLPath, pv) {
+		require.True(t, v.Valid, v.Reason)
+	}
+
+	// the Go checker disagrees with a wrong secret
+	for _, v := range verify(proofs, &r, "secret", aggregator.MockURLPath, pv) {
+		require.False(t, v.Valid)
+	}
+
+	// the proof commits to another event
+	other := proofs[0]
+	other.Event.UUID = common.HexToHash("0x01")
+	require.False(t, verify([]ProofOfAudit{other}, nil, "", "", pv)[0].Valid)
+
+	// a proof the program didn't commit to the event is valid
+	notCommitted := proofs[0]
+	values := poa.EventPublicValues(notCommitted.Event)
+	values.Included, values.Topic = false, ""
+	notCommitted.Committed = false
+	notCommitted.Proof = &poa.SP1Proof{Proof: "mock:0", Stdout: poa.StdIO{Buffer: poa.StdBuffer{Data: values.Encode()}}}
+	res := verify([]ProofOfAudit{notCommitted}, nil, "", "", pv)[0]
+	require.True(t, res.Valid, res.Reason)
+	notCommitted.Event.Account = common.HexToHash("0x01")
+	require.False(t, verify([]ProofOfAudit{notCommitted}, nil, "", "", pv)[0].Valid)
+	notCommitted.Proof.Proof = "proof"
+	require.False(t, verify([]ProofOfAudit{notCommitted}, nil, "", "", pv)[0].Valid)
+
+	tampered := proofs[0]
+	tampered.Committed = false
+	require.False(t, verify([]ProofOfAudit{tampered}, nil, "", "", pv)[0].Valid)
+	tampered.Proof = nil
+	require.False(t, verify([]ProofOfAudit{tampered}, nil, "", "", pv)[0].Valid)
+}
+
+func Test_Run_ProveVerify(t *testing.T) {
+	r := mockReports(t, 1)[0]
+	reportFile := writeFile(t, "report.json", r)
+	proofFile := filepath.Join(t.TempDir(), "proofs.json")
+
+	var stdout, stderr bytes.Buffer
+	require.ErrorIs(t, run([]string{"prove", "-report", reportFile, "-checker", "bogus"}, &stdout, &stderr), errUsage)
+	require.ErrorContains(t, run([]string{"prove", "-report", reportFile}, &stdout, &stderr), "missing webhook secret")
+
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(secretFile, []byte(aggregator.MockSecret+"\n"), 0o600))
+	require.NoError(t, run([]string{
+		"prove", "-checker", "go", "-report", reportFile, "-out", proofFile,
+		"-secret-file", secretFile, "-url-path", aggregator.MockURLPath,
+	}, &stdout, &stderr))
+
+	var proofs []ProofOfAudit
+	require.NoError(t, readJSON(proofFile, &proofs))
+	require.Len(t, proofs, 1)
+	for _, p := range proofs {
+		require.Equal(t, checkerGo, p.Checker)
+		require.True(t, p.Committed)
+		require.Nil(t, p.Proof)
+	}
+
+	var res []Verification
+	require.NoError(t, run([]string{"verify", "-proof", proofFile, "-report", reportFile, "-mock"}, &stdout, &stderr))
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &res))
+	require.Len(t, res, 1)
+
+	// the output of the Go checker requires the report
+	stdout.Reset()
+	require.ErrorIs(t, run([]string{"verify", "-proof", proofFile}, &stdout, &stderr), errVerifyFailed)
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &res))
+	require.False(t, res[0].Valid)
+}
+
+func Test_Run_Archive(t *testing.T) {
+	rdb := reportDB.NewReportDB()
+	for _, r := range mockReports(t, 3) {
+		events, ts, err := r.Parse()
+		require.NoError(t, err)
+		rdb.Set(events[0].Account.String(), ts, r)
+	}
+	archiveFile := filepath.Join(t.TempDir(), "reports.br")
+	require.NoError(t, exportReports(rdb, archiveFile))
+
+	var stdout, stderr bytes.Buffer
+	require.ErrorIs(t, run([]string{"replay", "-mock", "-archive", archiveFile, "-reports", archiveFile}, &stdout, &stderr), errUsage)
+	require.NoError(t, run([]string{"replay", "-mock", "-archive", archiveFile}, &stdout, &stderr))
+
+	var res ReplayResult
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &res))
+	require.Equal(t, 3, res.Reports)
+	require.Len(t, res.Outcomes, 3)
+
+	// serve replays the archive & exports the reports on shutdown
+	exportFile := filepath.Join(t.TempDir(), "export.br")
+	n, err := newNode(Config{
+		Mock:            true,
+		ReplayFile:      archiveFile,
+		ExportFile:      exportFile,
+		PublishInterval: time.Hour,
+	}, func(rdb *reportDB.ReportDB) auditor.ReportDB { return rdb })
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- n.serve(ctx, io.Discard) }()
+	require.Eventually(t, func() bool { return len(n.rdb.Entries()) == 3 }, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	exported, err := readArchive(exportFile)
+	require.NoError(t, err)
+	reports, err := readArchive(archiveFile)
+	require.NoError(t, err)
+	require.Equal(t, reports, exported)
+}
+
+func Test_Serve_Feed(t *testing.T) {
+	newServeNode := func(cfg Config) *node {
+		cfg.PublishInterval = time.Hour
+		n, err := newNode(cfg, func(rdb *reportDB.ReportDB) auditor.ReportDB { return rdb })
+		require.NoError(t, err)
+		return n
+	}
+
+	// a feed is required
+	cfg := Config{Secret: aggregator.MockSecret, URLPath: aggregator.MockURLPath}
+	require.ErrorContains(t, newServeNode(cfg).serve(context.Background(), io.Discard), "no report feed")
+
+	// the webhook deliveries are collected by default
+	cfg.WebhookAddr = "localhost:0"
+	var stdout bytes.Buffer
+	n := newServeNode(cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- n.serve(ctx, &stdout) }()
+	cancel()
+	require.NoError(t, <-done)
+	require.Contains(t, stdout.String(), "receiving the webhook deliveries on localhost:0"+aggregator.MockURLPath)
+}
+
+// reportsFeed sends its reports to the subscriber
+type reportsFeed []reportDB.Report
+
+func (f reportsFeed) SubscribeTo(id string, feedChan chan<- reportDB.Report) error {
+	go func() {
+		defer close(feedChan)
+		for _, r := range f {
+			feedChan <- r
+		}
+	}()
+	return nil
+}
+
+func Test_SeedingFeed(t *testing.T) {
+	sdb, err := sDB.NewStateDB()
+	require.NoError(t, err)
+	reports := append([]reportDB.Report{{Body: "{"}}, mockReports(t, 1)...)
+
+	errs := make(chan error, 1)
+	out := make(chan reportDB.Report)
+	require.NoError(t, (&seedingFeed{feed: reportsFeed(reports), sdb: sdb, errs: errs}).SubscribeTo(aggregator.CoinbaseEASFeedID, out))
+
+	// the report failing to seed is still forwarded
+	var got []reportDB.Report
+	for r := range out {
+		got = append(got, r)
+	}
+	require.Equal(t, reports, got)
+	require.ErrorContains(t, <-errs, "failed to seed the events of report "+reports[0].ID())
+
+	events, _, err := reports[1].Parse()
+	require.NoError(t, err)
+	require.True(t, sdb.NsExists(events[0].Account.Hex()))
+}
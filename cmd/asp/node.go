@@ -0,0 +1,203 @@
+package main
+
+import (
+	"github.com/0xBow-io/base-eas-asp/core/aggregator"
+	"github.com/0xBow-io/base-eas-asp/core/asp"
+	"github.com/0xBow-io/base-eas-asp/core/auditor"
+	"github.com/0xBow-io/base-eas-asp/core/verifier"
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	pp "github.com/0xBow-io/base-eas-asp/pkg/privacy_pool"
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+	sDB "github.com/0xBow-io/base-eas-asp/pkg/statedb"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/pkg/errors"
+)
+
+// node composes the components of the ASP
+type node struct {
+	cfg      Config
+	sdb      *sDB.StateDB
+	rdb      *reportDB.ReportDB
+	verifier *verifier.Verifier
+	asp      *asp.ASP
+	auditor  *auditor.Auditor
+	// client of -chain-check, nil without
+	chain *ethclient.Client
+	// errors which don't stop the node, printed by serve
+	errs chan error
+}
+
+// Close the connections of the node
+func (n *node) Close() {
+	if n.chain != nil {
+		n.chain.Close()
+	}
+}
+
+// newNode builds the node, the auditor is notified
+// of new reports by notifDB
+func newNode(cfg Config, notifDB func(*reportDB.ReportDB) auditor.ReportDB) (*node, error) {
+	// the expirations are only known from the chain
+	if cfg.ExpiryDowngrade != "" && cfg.ChainCheckURL == "" {
+		return nil, errors.New("-expiry-downgrade requires -chain-check")
+	}
+	policy, err := cfg.policy()
+	if err != nil {
+		return nil, err
+	}
+	// the other attestations of the policy are read on chain
+	if policy != nil && len(policy.EvidenceSchemas()) > 0 && cfg.ChainCheckURL == "" {
+		return nil, errors.New("-policy matching schemas other than the account verification requires -chain-check")
+	}
+	downgrade := sDB.MEMBERSHIP_TYPE(cfg.ExpiryDowngrade)
+	if downgrade == "" {
+		downgrade = sDB.EXCLUSION
+	}
+	if downgrade != sDB.EXCLUSION && downgrade != sDB.PARTIAL_INCLUSION {
+		return nil, errors.Wrap(sDB.ErrInvalidMembership, "expiry downgrade "+cfg.ExpiryDowngrade)
+	}
+	prover, err := cfg.prover()
+	if err != nil {
+		return nil, err
+	}
+
+	sdb, err := sDB.NewStateDB()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create StateDB")
+	}
+	if cfg.EventsFile != "" {
+		if err := loadEvents(sdb, cfg.EventsFile); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		chain    *ethclient.Client
+		evidence eas.EvidenceSource
+	)
+	if cfg.ChainCheckURL != "" {
+		if chain, err = ethclient.Dial(cfg.ChainCheckURL); err != nil {
+			return nil, errors.Wrap(err, "failed to dial "+cfg.ChainCheckURL)
+		}
+		evidence = eas.NewChainEvidence(chain)
+	}
+
+	errs := make(chan error, 16)
+	v, err := verifier.NewVerifierWithConfig(sdb, cfg.proofVerifier(), verifier.VerifierConfig{Policy: policy, Evidence: evidence})
+	if err != nil {
+		if chain != nil {
+			chain.Close()
+		}
+		return nil, err
+	}
+	n := &node{
+		cfg:      cfg,
+		sdb:      sdb,
+		rdb:      reportDB.NewReportDBWithConfig(reportDB.ReportDBConfig{Policy: policy, Evidence: evidence, Errors: errs}),
+		verifier: v,
+		asp:      asp.NewASP(sdb, nil, asp.DefaultZeroValue()),
+		chain:    chain,
+		errs:     errs,
+	}
+	acfg := auditor.AuditorConfig{Policy: policy, Evidence: evidence, Errors: errs}
+	if chain != nil {
+		acfg.Checker = auditor.NewChainVerifier(chain)
+		acfg.Expiry = auditor.NewExpiryScheduler(auditor.SystemClock)
+		acfg.Downgrade = downgrade
+	}
+	n.auditor = auditor.NewAuditorWithConfig(n.verifier, notifDB(n.rdb), sdb, prover, acfg)
+	return n, nil
+}
+
+// discardErrors drops the errors reported to the node
+func (n *node) discardErrors() {
+	for {
+		select {
+		case <-n.errs:
+		default:
+			return
+		}
+	}
+}
+
+func loadEvents(sdb *sDB.StateDB, path string) error {
+	var events []pp.Event
+	if err := readJSON(path, &events); err != nil {
+		return err
+	}
+	for i, e := range events {
+		if err := addEvent(sdb, e); err != nil {
+			return errors.Wrapf(err, "event %d of %s", i, path)
+		}
+	}
+	return nil
+}
+
+func addEvent(sdb *sDB.StateDB, e pp.Event) error {
+	se, err := e.Serialize()
+	if err != nil {
+		return err
+	}
+	return sdb.AddEvent(se)
+}
+
+// seedEvents adds a mock deposit & withdrawal to StateDB
+// for every account of the report without events
+func seedEvents(sdb *sDB.StateDB, r reportDB.Report) error {
+	events, _, err := r.Parse()
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		if sdb.NsExists(e.Account.Hex()) {
+			continue
+		}
+		for i := uint8(0); i < 2; i++ {
+			if err := addEvent(sdb, pp.Event{
+				TxHash:   crypto.Keccak256Hash(e.UUID.Bytes()),
+				LogIndex: i,
+				From:     e.Account,
+				Amount:   common.BigToHash(common.Big1),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// seedingFeed seeds the events of the reported accounts
+// before forwarding the reports of feed, the seeding
+// errors are sent to errs
+type seedingFeed struct {
+	feed aggregator.ReportFeed
+	sdb  *sDB.StateDB
+	errs chan<- error
+}
+
+func (f *seedingFeed) SubscribeTo(id string, feedChan chan<- reportDB.Report) error {
+	in := make(chan reportDB.Report)
+	if err := f.feed.SubscribeTo(id, in); err != nil {
+		return err
+	}
+	go func() {
+		defer close(feedChan)
+		for r := range in {
+			if err := seedEvents(f.sdb, r); err != nil {
+				f.errs <- errors.Wrapf(err, "failed to seed the events of report %s", r.ID())
+			}
+			feedChan <- r
+		}
+	}()
+	return nil
+}
+
+// auditDB is a ReportDB without notifications,
+// reports are audited by the caller
+type auditDB struct {
+	*reportDB.ReportDB
+}
+
+func (auditDB) SubscribeToTransitionNotif(chan reportDB.Transition) {}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/0xBow-io/base-eas-asp/core/auditor"
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	poa "github.com/0xBow-io/base-eas-asp/pkg/proof-of-audit"
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+const (
+	checkerSP1 = "sp1"
+	checkerGo  = "go"
+)
+
+// ProofOfAudit is the output of the proof-of-audit program for an EAS event,
+// Proof is only set by the SP1 checker
+type ProofOfAudit struct {
+	Event     eas.EAS       `json:"event"`
+	Checker   string        `json:"checker"`
+	Committed bool          `json:"committed"`
+	Proof     *poa.SP1Proof `json:"proof,omitempty"`
+}
+
+func runProve(args []string, stdout, stderr io.Writer) error {
+	var (
+		cfg          Config
+		reportFile   string
+		publicID     string
+		commitmentID string
+		checker      string
+		outFile      string
+	)
+	fs := newFlagSet("prove", stderr)
+	fs.StringVar(&reportFile, "report", "", "JSON file of the report")
+	fs.StringVar(&publicID, "public-id", "", "public ID (attested account) to prove, defaults to every account of the report")
+	fs.StringVar(&commitmentID, "commitment", "", "commitment ID (EAS UUID) to prove, defaults to every event of the report")
+	fs.StringVar(&checker, "checker", checkerSP1, `"sp1" to generate the SP1 proofs or "go" to run the Go checker`)
+	fs.StringVar(&outFile, "out", "", "output file, defaults to stdout")
+	cfg.registerCredentialFlags(fs)
+	fs.BoolVar(&cfg.Mock, "mock", false, "use the webhook credentials of the mock report feed")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if reportFile == "" || (checker != checkerSP1 && checker != checkerGo) {
+		fs.Usage()
+		return errUsage
+	}
+
+	var r reportDB.Report
+	if err := readJSON(reportFile, &r); err != nil {
+		return err
+	}
+	events, err := auditEvents(r, publicID, commitmentID)
+	if err != nil {
+		return err
+	}
+
+	secret, urlPath, err := cfg.credentials()
+	if err != nil {
+		return err
+	}
+	var proofs []ProofOfAudit
+	if checker == checkerGo {
+		proofs, err = check(secret, urlPath, r, events)
+	} else {
+		proofs, err = prove(auditor.NewSP1Prover(secret, urlPath), r, events)
+	}
+	if err != nil {
+		return err
+	}
+
+	if outFile == "" {
+		return writeJSON(stdout, proofs)
+	}
+	f, err := os.Create(outFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return writeJSON(f, proofs)
+}
+
+// auditEvents returns the EAS events of the report matching the public ID
+// & commitment ID, any value matches an empty ID.
+// When both IDs are set but not found in the report, e.g. when the parser
+// disagrees with the program, the event is audited as is.
+func auditEvents(r reportDB.Report, publicID, commitmentID string) ([]eas.EAS, error) {
+	explicit := publicID != "" && commitmentID != ""
+	target := eas.EAS{
+		UUID:    common.HexToHash(commitmentID),
+		Account: common.HexToHash(publicID),
+		Type:    eas.EAS_UNKNOWN,
+	}
+
+	parsed, _, err := r.Parse()
+	if err != nil && !explicit {
+		return nil, errors.Wrap(err, "failed to parse report")
+	}
+
+	var events []eas.EAS
+	for _, e := range parsed {
+		if (publicID == "" || e.Account == target.Account) && (commitmentID == "" || e.UUID == target.UUID) {
+			events = append(events, e)
+		}
+	}
+	if len(events) == 0 {
+		if explicit {
+			return []eas.EAS{target}, nil
+		}
+		return nil, errors.New("no matching EAS event in the report")
+	}
+	return events, nil
+}
+
+// prove generates the SP1 proofs of audit of the events
+func prove(prover auditor.Prover, r reportDB.Report, events []eas.EAS) ([]ProofOfAudit, error) {
+	proofs := make([]ProofOfAudit, len(events))
+	for i, e := range events {
+		p, err := prover.Prove(r, e)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to prove event "+e.UUID.Hex())
+		}
+		committed, err := p.Committed()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to prove event "+e.UUID.Hex())
+		}
+		proofs[i] = ProofOfAudit{Event: e, Checker: checkerSP1, Committed: committed, Proof: p}
+	}
+	return proofs, nil
+}
+
+// check runs the Go checker of the proof-of-audit program on the events
+func check(secret, urlPath string, r reportDB.Report, events []eas.EAS) ([]ProofOfAudit, error) {
+	proofs := make([]ProofOfAudit, len(events))
+	for i, e := range events {
+		committed, err := checkEvent(secret, urlPath, r, e)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to check event "+e.UUID.Hex())
+		}
+		proofs[i] = ProofOfAudit{Event: e, Checker: checkerGo, Committed: committed}
+	}
+	return proofs, nil
+}
+
+// checkEvent runs the Go checker for the event, the signature is verified
+// against the raw body & the commitment is looked up in the decoded body
+func checkEvent(secret, urlPath string, r reportDB.Report, e eas.EAS) (bool, error) {
+	if len(r.Header.Encodings()) == 0 {
+		return poa.Check(
+			secret,
+			urlPath,
+			r.Header.Nonce,
+			r.Header.Timestamp,
+			r.Body,
+			r.Header.Signature,
+			e.UUID.Hex(),
+			e.Account.Hex(),
+		)
+	}
+
+	if !poa.VerifySignature(secret, urlPath, r.Header.Nonce, r.Header.Timestamp, r.Body, r.Header.Signature) {
+		return false, nil
+	}
+	body, err := r.Decoded()
+	if err != nil {
+		return false, err
+	}
+	return poa.FindCommitment(string(body), e.UUID.Hex(), e.Account.Hex())
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/0xBow-io/base-eas-asp/core/asp"
+	"github.com/0xBow-io/base-eas-asp/core/auditor"
+	"github.com/0xBow-io/base-eas-asp/core/verifier"
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+	"github.com/pkg/errors"
+)
+
+type ReplayResult struct {
+	Reports  int                `json:"reports"`
+	Errors   []string           `json:"errors,omitempty"`
+	Outcomes []verifier.Outcome `json:"outcomes"`
+	Roots    asp.RootVersion    `json:"roots"`
+}
+
+func runReplay(args []string, stdout, stderr io.Writer) error {
+	var (
+		cfg         Config
+		reportsFile string
+		archiveFile string
+	)
+	fs := newFlagSet("replay", stderr)
+	cfg.registerNodeFlags(fs)
+	fs.StringVar(&reportsFile, "reports", "", "JSON file of reports, as an array or one report per line")
+	fs.StringVar(&archiveFile, "archive", "", "report archive written by serve -export")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if (reportsFile == "") == (archiveFile == "") {
+		fs.Usage()
+		return errUsage
+	}
+
+	var (
+		reports []reportDB.Report
+		err     error
+	)
+	if archiveFile != "" {
+		reports, err = readArchive(archiveFile)
+	} else {
+		reports, err = readReports(reportsFile)
+	}
+	if err != nil {
+		return err
+	}
+	n, err := newNode(cfg, func(rdb *reportDB.ReportDB) auditor.ReportDB { return auditDB{rdb} })
+	if err != nil {
+		return err
+	}
+	defer n.Close()
+
+	res, err := n.replay(reports)
+	if err != nil {
+		return err
+	}
+	return writeJSON(stdout, res)
+}
+
+// replay stores & audits the reports in order,
+// then publishes the set roots
+func (n *node) replay(reports []reportDB.Report) (ReplayResult, error) {
+	res := ReplayResult{Reports: len(reports)}
+	for i, r := range reports {
+		if n.cfg.Mock {
+			if err := seedEvents(n.sdb, r); err != nil {
+				res.Errors = append(res.Errors, fmt.Sprintf("report %d: %v", i, err))
+				continue
+			}
+		}
+
+		events, ts, err := r.Parse()
+		if err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("report %d: %v", i, err))
+			continue
+		}
+
+		publicIDs := reportDB.PublicIDs(events)
+		for _, publicID := range publicIDs {
+			n.rdb.Set(publicID, ts, r)
+			// the memberships which can't be derived fail the audit below
+			n.discardErrors()
+		}
+		for _, publicID := range publicIDs {
+			if err := n.auditor.Audit(publicID); err != nil {
+				res.Errors = append(res.Errors, fmt.Sprintf("report %d: %v", i, err))
+			}
+		}
+	}
+
+	v, err := n.asp.Publish()
+	if err != nil {
+		return res, errors.Wrap(err, "failed to publish set roots")
+	}
+	res.Outcomes = n.verifier.Outcomes()
+	res.Roots = v
+	return res, nil
+}
+
+// readReports reads a JSON array of reports or one JSON report per line
+func readReports(path string) ([]reportDB.Report, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var reports []reportDB.Report
+	if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '[' {
+		return reports, errors.Wrap(json.Unmarshal(b, &reports), "failed to decode "+path)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	scanner.Buffer(nil, len(b)+1)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var r reportDB.Report
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode line %d of %s", line, path)
+		}
+		reports = append(reports, r)
+	}
+	return reports, scanner.Err()
+}
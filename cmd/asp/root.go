@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/0xBow-io/base-eas-asp/core/rpc"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func runRoot(args []string, stdout, stderr io.Writer) error {
+	var (
+		addr    string
+		version uint64
+		timeout time.Duration
+	)
+	fs := newFlagSet("root", stderr)
+	fs.StringVar(&addr, "addr", "localhost:9090", "address of the gRPC services of a running server")
+	fs.Uint64Var(&version, "version", 0, "published version, 0 for the latest one")
+	fs.DurationVar(&timeout, "timeout", 10*time.Second, "request timeout")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to "+addr)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	v, err := rpc.NewClient(conn).GetRoot(ctx, version)
+	if err != nil {
+		return errors.Wrap(err, "failed to get set roots")
+	}
+	return writeJSON(stdout, v)
+}
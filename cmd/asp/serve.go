@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/0xBow-io/base-eas-asp/core/aggregator"
+	"github.com/0xBow-io/base-eas-asp/core/api"
+	"github.com/0xBow-io/base-eas-asp/core/auditor"
+	"github.com/0xBow-io/base-eas-asp/core/rpc"
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+// rederiveInterval between the retries of the memberships
+// which evidence couldn't be read
+const rederiveInterval = time.Minute
+
+func runServe(args []string, stdout, stderr io.Writer) error {
+	var cfg Config
+	fs := newFlagSet("serve", stderr)
+	cfg.registerServeFlags(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	n, err := newNode(cfg, func(rdb *reportDB.ReportDB) auditor.ReportDB { return rdb })
+	if err != nil {
+		return err
+	}
+	defer n.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	return n.serve(ctx, stdout)
+}
+
+// serve runs the node until ctx is done or a server fails
+func (n *node) serve(ctx context.Context, stdout io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errChan := make(chan error, 3)
+
+	go n.auditor.HandleIncomingReports()
+	go n.auditor.HandleExpirations(ctx)
+	go n.auditor.HandleRetries(ctx)
+	go n.rdb.HandleRederive(ctx, rederiveInterval)
+
+	var feed aggregator.ReportFeed
+	switch {
+	case n.cfg.RPCURL != "":
+		client, err := ethclient.DialContext(ctx, n.cfg.RPCURL)
+		if err != nil {
+			return errors.Wrap(err, "failed to dial "+n.cfg.RPCURL)
+		}
+		defer client.Close()
+		logs := aggregator.NewLogReportFeed(client, aggregator.LogFeedConfig{
+			FromBlock:     n.cfg.RPCFromBlock,
+			Confirmations: n.cfg.RPCConfirmations,
+			Errors:        n.errs,
+		})
+		defer logs.Close()
+		feed = logs
+		fmt.Fprintln(stdout, "polling the EAS logs of", n.cfg.RPCURL)
+	case n.cfg.ReplayFile != "":
+		replay, err := openReplayFeed(n.cfg.ReplayFile, n.cfg.ReplaySpeed)
+		if err != nil {
+			return err
+		}
+		feed = replay
+		fmt.Fprintln(stdout, "replaying reports of", n.cfg.ReplayFile)
+	case n.cfg.Mock:
+		feed = aggregator.NewMockReportFeedWithPeriod(n.cfg.MockPeriod)
+		fmt.Fprintln(stdout, "mock report feed started")
+	case n.cfg.WebhookAddr != "":
+		secret, urlPath, err := n.cfg.credentials()
+		if err != nil {
+			return err
+		}
+		webhook := aggregator.NewWebhookReportFeed(secret, urlPath)
+		go func() { errChan <- errors.Wrap(webhook.Serve(ctx, n.cfg.WebhookAddr), "webhook server") }()
+		feed = webhook
+		fmt.Fprintln(stdout, "receiving the webhook deliveries on", n.cfg.WebhookAddr+urlPath)
+	default:
+		return errors.New("no report feed, set -webhook, -rpc, -replay or -mock")
+	}
+	if n.cfg.Mock {
+		feed = &seedingFeed{feed: feed, sdb: n.sdb, errs: n.errs}
+	}
+	var backfill aggregator.HistoricalSource
+	if n.cfg.BackfillURL != "" && n.cfg.RPCURL == "" {
+		client, err := ethclient.DialContext(ctx, n.cfg.BackfillURL)
+		if err != nil {
+			return errors.Wrap(err, "failed to dial "+n.cfg.BackfillURL)
+		}
+		defer client.Close()
+		backfill = aggregator.NewLogReportFeed(client, aggregator.LogFeedConfig{})
+		fmt.Fprintln(stdout, "backfilling the missed blocks from", n.cfg.BackfillURL)
+	}
+	aggregator.NewReportAggregatorWithConfig(feed, n.rdb, aggregator.AggregatorConfig{
+		Feeds:    n.cfg.feeds(),
+		Backfill: backfill,
+		Errors:   n.errs,
+	})
+	if n.cfg.ExportFile != "" {
+		defer func() {
+			if err := exportReports(n.rdb, n.cfg.ExportFile); err != nil {
+				fmt.Fprintln(stdout, "failed to export reports:", err)
+				return
+			}
+			fmt.Fprintln(stdout, "reports exported to", n.cfg.ExportFile)
+		}()
+	}
+
+	if n.cfg.GRPCAddr != "" {
+		lis, err := net.Listen("tcp", n.cfg.GRPCAddr)
+		if err != nil {
+			return errors.Wrap(err, "failed to listen on "+n.cfg.GRPCAddr)
+		}
+		srv := grpc.NewServer(grpc.UnaryInterceptor(rpc.RequireToken(n.cfg.GRPCToken, rpc.WriteServices...)))
+		if n.cfg.GRPCToken != "" {
+			secret, urlPath, err := n.cfg.credentials()
+			if err != nil {
+				return err
+			}
+			rpc.NewReportServer(n.rdb, secret, urlPath).Register(srv)
+			rpc.NewChangeRequestServer(n.verifier).Register(srv)
+		} else {
+			fmt.Fprintln(stdout, "no -grpc-token, the gRPC report & change request services are disabled")
+		}
+		rpc.NewMembershipServer(n.sdb, n.asp).Register(srv)
+		go func() { errChan <- errors.Wrap(srv.Serve(lis), "gRPC server") }()
+		defer srv.GracefulStop()
+		fmt.Fprintln(stdout, "gRPC services listening on", lis.Addr())
+	}
+
+	if n.cfg.HTTPAddr != "" {
+		go func() {
+			errChan <- errors.Wrap(api.NewServer(n.sdb, n.rdb, n.asp).Serve(ctx, n.cfg.HTTPAddr), "HTTP server")
+		}()
+		fmt.Fprintln(stdout, "HTTP API listening on", n.cfg.HTTPAddr)
+	}
+
+	ticker := time.NewTicker(n.cfg.PublishInterval)
+	defer ticker.Stop()
+	var latest uint64
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errChan:
+			return err
+		case err := <-n.errs:
+			fmt.Fprintln(stdout, err)
+			continue
+		case <-ticker.C:
+		}
+
+		v, err := n.asp.Publish()
+		if err != nil {
+			fmt.Fprintln(stdout, "failed to publish set roots:", err)
+			continue
+		}
+		if v.Version != latest {
+			latest = v.Version
+			fmt.Fprintf(stdout, "published set roots version %d\n", v.Version)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/0xBow-io/base-eas-asp/core/verifier"
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	poa "github.com/0xBow-io/base-eas-asp/pkg/proof-of-audit"
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+	"github.com/pkg/errors"
+)
+
+var errVerifyFailed = errors.New("verification failed")
+
+// Verification of a proof of audit
+type Verification struct {
+	Event  eas.EAS `json:"event"`
+	Valid  bool    `json:"valid"`
+	Reason string  `json:"reason,omitempty"`
+}
+
+func runVerify(args []string, stdout, stderr io.Writer) error {
+	var (
+		cfg        Config
+		proofFile  string
+		reportFile string
+	)
+	fs := newFlagSet("verify", stderr)
+	fs.StringVar(&proofFile, "proof", "", "JSON file written by prove")
+	fs.StringVar(&reportFile, "report", "", "JSON file of the report, to re-run the Go checker against")
+	cfg.registerCredentialFlags(fs)
+	fs.BoolVar(&cfg.Mock, "mock", false, "use the webhook credentials of the mock report feed")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if proofFile == "" {
+		fs.Usage()
+		return errUsage
+	}
+
+	var proofs []ProofOfAudit
+	if err := readJSON(proofFile, &proofs); err != nil {
+		return err
+	}
+
+	var (
+		r               *reportDB.Report
+		secret, urlPath string
+	)
+	if reportFile != "" {
+		r = new(reportDB.Report)
+		if err := readJSON(reportFile, r); err != nil {
+			return err
+		}
+		var err error
+		if secret, urlPath, err = cfg.credentials(); err != nil {
+			return err
+		}
+	}
+
+	res := verify(proofs, r, secret, urlPath, verifier.ProofVerifierFunc(poa.VerifySP1))
+	if err := writeJSON(stdout, res); err != nil {
+		return err
+	}
+	for _, v := range res {
+		if !v.Valid {
+			return errVerifyFailed
+		}
+	}
+	return nil
+}
+
+// verify runs the SP1 verifier pv on the SP1 proofs & checks that they commit
+// to their event & output. When r is set, the Go checker is re-run against
+// the report and has to agree with every proof.
+// A proof is only valid once the SP1 verifier or the Go checker accepted it.
+func verify(proofs []ProofOfAudit, r *reportDB.Report, secret, urlPath string, pv verifier.ProofVerifier) []Verification {
+	res := make([]Verification, len(proofs))
+	for i, p := range proofs {
+		res[i] = Verification{Event: p.Event}
+		if err := verifyProof(p, r, secret, urlPath, pv); err != nil {
+			res[i].Reason = err.Error()
+			continue
+		}
+		res[i].Valid = true
+	}
+	return res
+}
+
+func verifyProof(p ProofOfAudit, r *reportDB.Report, secret, urlPath string, pv verifier.ProofVerifier) error {
+	switch p.Checker {
+	case checkerSP1:
+		if p.Proof == nil || p.Proof.Proof == "" {
+			return poa.ErrEmptyProof
+		}
+		v, err := p.Proof.PublicValues()
+		if err != nil {
+			return err
+		}
+		if v.Included != p.Committed {
+			return fmt.Errorf("proof output %v does not match %v", v.Included, p.Committed)
+		}
+		// the program commits to the account & event it was given,
+		// to the type of the event only if it found it
+		if v.Account() != p.Event.Account || v.UUID() != p.Event.UUID || (v.Included && !v.Matches(p.Event)) {
+			return errors.New("proof does not commit to the event")
+		}
+		if err := pv.VerifyProof(*p.Proof); err != nil {
+			return errors.Wrap(err, "SP1 verifier")
+		}
+	case checkerGo:
+		if r == nil {
+			return errors.New("the output of the Go checker can only be verified against the report")
+		}
+	default:
+		return fmt.Errorf("unknown checker %q", p.Checker)
+	}
+
+	if r == nil {
+		return nil
+	}
+	committed, err := checkEvent(secret, urlPath, *r, p.Event)
+	if err != nil {
+		return err
+	}
+	if committed != p.Committed {
+		return fmt.Errorf("the Go checker outputs %v for the report, expected %v", committed, p.Committed)
+	}
+	return nil
+}
@@ -2,17 +2,40 @@ package aggregator
 
 import (
 	"fmt"
+	"sync"
 
+	quiknode "github.com/0xBow-io/base-eas-asp/pkg/quiknode"
 	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
 	"github.com/pkg/errors"
 )
 
+// notification ID of the Coinbase EAS feed
+const CoinbaseEASFeedID = "604ab59e-f362-413e-a04e-64723176595a"
+
+// FeedConfig of a feed, the format of its reports is set from the config
+type FeedConfig struct {
+	NotificationID string
+	Format         quiknode.Format
+	// highest block processed before the first report, 0 if unknown,
+	// the blocks after it are backfilled
+	LastBlock uint64
+}
+
 var (
-	quiknodeFeedIDs = map[string]string{
-		"base-eas-attest": "604ab59e-f362-413e-a04e-64723176595a", // Coinbase EAS Feed
+	quiknodeFeeds = map[string]FeedConfig{
+		"base-eas-attest": {NotificationID: CoinbaseEASFeedID, Format: quiknode.FormatQuickAlerts}, // Coinbase EAS Feed
 	}
 )
 
+// DefaultFeeds returns the configs of the QuickNode feeds by name
+func DefaultFeeds() map[string]FeedConfig {
+	feeds := make(map[string]FeedConfig, len(quiknodeFeeds))
+	for name, feed := range quiknodeFeeds {
+		feeds[name] = feed
+	}
+	return feeds
+}
+
 type ReportFeed interface {
 	SubscribeTo(NotificationID string, feedChan chan<- reportDB.Report) error
 }
@@ -23,14 +46,57 @@ type ReportDB interface {
 }
 
 type ReportAggregator struct {
-	feed ReportFeed
+	feed  ReportFeed
+	feeds map[string]FeedConfig
 
 	// map of latest report for a public ID
 	rDb ReportDB
+
+	// blocks processed for the webhook feeds & source of the missed ones
+	backfill HistoricalSource
+	states   map[string]*feedState
+	mut      sync.Mutex
+
+	errs chan<- error
+}
+
+// AggregatorConfig of a ReportAggregator
+type AggregatorConfig struct {
+	// feeds collected, the QuickNode feeds if nil
+	Feeds map[string]FeedConfig
+	// source of the blocks missed between webhook deliveries, nil to not backfill
+	Backfill HistoricalSource
+	// receives the errors which don't stop the collection, the malformed
+	// reports & the failed backfills, nil to discard them
+	Errors chan<- error
 }
 
 func NewReportAggregator(feed ReportFeed, rDb ReportDB) *ReportAggregator {
-	a := &ReportAggregator{feed: feed, rDb: rDb}
+	return NewReportAggregatorWithFeeds(feed, rDb, quiknodeFeeds)
+}
+
+// NewReportAggregatorWithFeeds collects the reports of the configured feeds
+func NewReportAggregatorWithFeeds(feed ReportFeed, rDb ReportDB, feeds map[string]FeedConfig) *ReportAggregator {
+	return NewReportAggregatorWithConfig(feed, rDb, AggregatorConfig{Feeds: feeds})
+}
+
+// NewReportAggregatorWithConfig collects the reports of the configured feeds
+// & backfills the blocks missed between webhook deliveries
+func NewReportAggregatorWithConfig(feed ReportFeed, rDb ReportDB, cfg AggregatorConfig) *ReportAggregator {
+	if cfg.Feeds == nil {
+		cfg.Feeds = quiknodeFeeds
+	}
+	a := &ReportAggregator{
+		feed:     feed,
+		feeds:    cfg.Feeds,
+		rDb:      rDb,
+		backfill: cfg.Backfill,
+		states:   make(map[string]*feedState),
+		errs:     cfg.Errors,
+	}
+	for _, config := range cfg.Feeds {
+		a.states[config.NotificationID] = &feedState{highest: config.LastBlock}
+	}
 	go a.aggregate()
 	return a
 }
@@ -38,7 +104,8 @@ func NewReportAggregator(feed ReportFeed, rDb ReportDB) *ReportAggregator {
 /*
 Collect all the reports from the feed and store them in the reportDB
 */
-func (a *ReportAggregator) collect(notificationID string, errChan chan error) {
+func (a *ReportAggregator) collect(config FeedConfig, errChan chan error) {
+	notificationID := config.NotificationID
 	feed := make(chan reportDB.Report)
 	err := a.feed.SubscribeTo(notificationID, feed)
 	if err != nil {
@@ -48,24 +115,53 @@ func (a *ReportAggregator) collect(notificationID string, errChan chan error) {
 	fmt.Println("subscribed to feed for id: ", notificationID)
 
 	for report := range feed {
-		publicIDs, ts, err := report.Parse()
-		if err != nil {
-			errChan <- errors.Wrap(err, "failed to parse report for id: "+notificationID)
+		// reports replayed from archives keep their format
+		if report.Header.Format == "" {
+			report.Header.Format = config.Format
 		}
-		for _, id := range publicIDs {
-			a.rDb.Set(id.Account.String(), ts, report)
+
+		// the gaps between webhook deliveries are stored first
+		if report.Header.Signed() {
+			if blocks, err := report.Blocks(); err == nil && !blocks.IsZero() {
+				for _, r := range a.track(notificationID, blocks) {
+					if err := a.store(r); err != nil {
+						a.report(errors.Wrap(err, "failed to store backfilled report for id: "+notificationID))
+					}
+				}
+			}
+		}
+
+		// a malformed report is skipped, the next ones are still collected
+		if err := a.store(report); err != nil {
+			a.report(errors.Wrap(err, "failed to parse report for id: "+notificationID))
 		}
 	}
 
 }
 
+// store the report for each of its accounts
+func (a *ReportAggregator) store(report reportDB.Report) error {
+	publicIDs, ts, err := report.Parse()
+	for _, publicID := range reportDB.PublicIDs(publicIDs) {
+		a.rDb.Set(publicID, ts, report)
+	}
+	return err
+}
+
+// report an error which doesn't stop the collection
+func (a *ReportAggregator) report(err error) {
+	if a.errs != nil {
+		a.errs <- err
+	}
+}
+
 func (a *ReportAggregator) aggregate() {
 	errChan := make(chan error)
-	for _, id := range quiknodeFeedIDs {
-		go a.collect(id, errChan)
+	for _, config := range a.feeds {
+		go a.collect(config, errChan)
 	}
 
-	// simply panic if eror occurs
+	// simply panic if a feed can't be subscribed to
 	for err := range errChan {
 		panic(err)
 	}
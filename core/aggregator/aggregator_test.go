@@ -0,0 +1,100 @@
+package aggregator
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	quiknode "github.com/0xBow-io/base-eas-asp/pkg/quiknode"
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Aggregator_FeedFormats(t *testing.T) {
+	mock := NewMockReportFeedWithPeriod(0)
+
+	alerts := mock.genRandReport("alerts")
+	alertEvents, _, err := alerts.Parse()
+	require.NoError(t, err)
+
+	// the logs of a QuickAlerts payload as an eth_getLogs result
+	logs := mock.genRandReport("logs")
+	var payload struct {
+		MatchedReceipts []struct {
+			Logs []json.RawMessage `json:"logs"`
+		} `json:"matchedReceipts"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(logs.Body), &payload))
+	body, err := json.Marshal(payload.MatchedReceipts[0].Logs)
+	require.NoError(t, err)
+	logs.Body = string(body)
+	_, _, err = logs.Parse()
+	require.ErrorIs(t, err, quiknode.ErrInvalidPayload)
+	logs.Header.Format = quiknode.FormatLogs
+	logEvents, _, err := logs.Parse()
+	require.NoError(t, err)
+	require.Len(t, logEvents, 1)
+	logs.Header.Format = ""
+
+	var archive bytes.Buffer
+	require.NoError(t, reportDB.WriteArchive(&archive, []reportDB.ArchiveEntry{{Report: alerts}, {Report: logs}}))
+	replay, err := NewReplayReportFeed(&archive, 0)
+	require.NoError(t, err)
+
+	rDB := reportDB.NewReportDB()
+	NewReportAggregatorWithFeeds(replay, rDB, map[string]FeedConfig{
+		"alerts": {NotificationID: "alerts", Format: quiknode.FormatQuickAlerts},
+		"logs":   {NotificationID: "logs", Format: quiknode.FormatLogs},
+	})
+
+	publicID := logEvents[0].Account.String()
+	require.Eventually(t, func() bool {
+		return rDB.Get(publicID).Body != "" && rDB.Get(alertEvents[0].Account.String()).Body != ""
+	}, time.Second, time.Millisecond)
+
+	stored := rDB.Get(publicID)
+	require.Equal(t, quiknode.FormatLogs, stored.Header.Format)
+	_, events, err := rDB.GetEvents(publicID)
+	require.NoError(t, err)
+	require.Equal(t, logEvents[0], events[0].EAS)
+	require.Equal(t, quiknode.FormatQuickAlerts, rDB.Get(alertEvents[0].Account.String()).Header.Format)
+
+	require.Equal(t, CoinbaseEASFeedID, DefaultFeeds()["base-eas-attest"].NotificationID)
+}
+
+// subFeed hands the subscribed channel to the test
+type subFeed chan chan<- reportDB.Report
+
+func (f subFeed) SubscribeTo(id string, feedChan chan<- reportDB.Report) error {
+	f <- feedChan
+	return nil
+}
+
+func Test_Aggregator_Malformed(t *testing.T) {
+	feed := make(subFeed, 1)
+	rDB := reportDB.NewReportDB()
+	errs := make(chan error, 2)
+	NewReportAggregatorWithConfig(feed, rDB, AggregatorConfig{Errors: errs})
+	reports := <-feed
+
+	// the malformed reports are skipped & reported
+	mock := NewMockReportFeedWithPeriod(0)
+	malformed := mock.genRandReport(CoinbaseEASFeedID)
+	malformed.Header.Nonce = ""
+	reports <- malformed
+	malformed = mock.genRandReport(CoinbaseEASFeedID)
+	malformed.Body = "{"
+	reports <- malformed
+
+	r := mock.genRandReport(CoinbaseEASFeedID)
+	events, _, err := r.Parse()
+	require.NoError(t, err)
+	reports <- r
+	require.Eventually(t, func() bool {
+		return rDB.Get(events[0].Account.String()).Body == r.Body
+	}, time.Second, time.Millisecond)
+	for i := 0; i < 2; i++ {
+		require.ErrorContains(t, <-errs, "failed to parse report for id: "+CoinbaseEASFeedID)
+	}
+}
@@ -0,0 +1,99 @@
+package aggregator
+
+import (
+	quiknode "github.com/0xBow-io/base-eas-asp/pkg/quiknode"
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+	"github.com/pkg/errors"
+)
+
+// HistoricalSource reads the reports of the EAS logs of past blocks,
+// LogReportFeed reads them from a JSON-RPC node
+type HistoricalSource interface {
+	Backfill(notificationID string, from, to uint64) ([]reportDB.Report, error)
+}
+
+// maxGaps kept per feed, the oldest gaps are dropped
+const maxGaps = 1024
+
+// feedState tracks the blocks processed for a webhook feed
+type feedState struct {
+	highest uint64
+	// gaps not backfilled yet, retried on the next report
+	gaps []quiknode.BlockRange
+}
+
+func (s *feedState) trim() {
+	if len(s.gaps) > maxGaps {
+		s.gaps = s.gaps[len(s.gaps)-maxGaps:]
+	}
+}
+
+func (a *ReportAggregator) state(notificationID string) *feedState {
+	s, ok := a.states[notificationID]
+	if !ok {
+		s = &feedState{}
+		a.states[notificationID] = s
+	}
+	return s
+}
+
+// Highest returns the highest block processed for the feed, 0 if none
+func (a *ReportAggregator) Highest(notificationID string) uint64 {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	return a.state(notificationID).highest
+}
+
+// Gaps returns the blocks missed by the feed which are not backfilled yet
+func (a *ReportAggregator) Gaps(notificationID string) []quiknode.BlockRange {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	return append([]quiknode.BlockRange(nil), a.state(notificationID).gaps...)
+}
+
+// track the blocks of a webhook report & return the reports of the blocks
+// missed since the highest block, marked as backfilled.
+// QuickAlerts only delivers the blocks with matches, every gap is read
+// from the historical source which has no report for the blocks without.
+func (a *ReportAggregator) track(notificationID string, blocks quiknode.BlockRange) []reportDB.Report {
+	a.mut.Lock()
+	s := a.state(notificationID)
+	if s.highest != 0 && blocks.First > s.highest+1 {
+		s.gaps = append(s.gaps, quiknode.BlockRange{First: s.highest + 1, Last: blocks.First - 1})
+	}
+	if blocks.Last > s.highest {
+		s.highest = blocks.Last
+	}
+	if a.backfill == nil {
+		s.trim()
+		a.mut.Unlock()
+		return nil
+	}
+	gaps := s.gaps
+	s.gaps = nil
+	a.mut.Unlock()
+
+	var (
+		reports []reportDB.Report
+		failed  []quiknode.BlockRange
+	)
+	for _, gap := range gaps {
+		backfilled, err := a.backfill.Backfill(notificationID, gap.First, gap.Last)
+		if err != nil {
+			a.report(errors.Wrapf(err, "failed to backfill blocks %d-%d for id: %s", gap.First, gap.Last, notificationID))
+			failed = append(failed, gap)
+			continue
+		}
+		for _, r := range backfilled {
+			r.Header.NotificationID = notificationID
+			r.Header.Source = reportDB.SourceBackfill
+			reports = append(reports, r)
+		}
+	}
+
+	a.mut.Lock()
+	s.gaps = append(failed, s.gaps...)
+	s.trim()
+	a.mut.Unlock()
+	return reports
+}
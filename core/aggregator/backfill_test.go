@@ -0,0 +1,113 @@
+package aggregator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	quiknode "github.com/0xBow-io/base-eas-asp/pkg/quiknode"
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+	"github.com/stretchr/testify/require"
+)
+
+// chanFeed delivers the reports sent to it
+type chanFeed chan reportDB.Report
+
+func (f chanFeed) SubscribeTo(_ string, feedChan chan<- reportDB.Report) error {
+	go func() {
+		for r := range f {
+			feedChan <- r
+		}
+	}()
+	return nil
+}
+
+// deliver a webhook report of the block & wait for it to be stored
+func deliver(t *testing.T, feed chanFeed, rDB *reportDB.ReportDB, block string) {
+	r := NewMockReportFeedWithPeriod(0).genRandReport("alerts")
+	r.Body = strings.ReplaceAll(r.Body, "0xb2bbad", block)
+	events, _, err := r.Parse()
+	require.NoError(t, err)
+
+	feed <- r
+	require.Eventually(t, func() bool {
+		return rDB.Get(events[0].Account.String()).Body == r.Body
+	}, time.Second, time.Millisecond)
+}
+
+func Test_Aggregator_Backfill(t *testing.T) {
+	var (
+		client = newFixtureClient(t)
+		feed   = make(chanFeed)
+		rDB    = reportDB.NewReportDB()
+		errs   = make(chan error, 1)
+		a      = NewReportAggregatorWithConfig(feed, rDB, AggregatorConfig{
+			Feeds:    map[string]FeedConfig{"alerts": {NotificationID: "alerts", LastBlock: 0xb2bbac}},
+			Backfill: NewLogReportFeed(client, LogFeedConfig{MaxRange: 1}),
+			Errors:   errs,
+		})
+	)
+	require.Equal(t, uint64(0xb2bbac), a.Highest("alerts"))
+
+	// no gap
+	deliver(t, feed, rDB, "0xb2bbad")
+	require.Equal(t, uint64(0xb2bbad), a.Highest("alerts"))
+	require.Empty(t, client.queries)
+
+	// the blocks ae-af are backfilled, a query per block
+	deliver(t, feed, rDB, "0xb2bbb0")
+	require.Equal(t, uint64(0xb2bbb0), a.Highest("alerts"))
+	require.Empty(t, a.Gaps("alerts"))
+	require.Len(t, client.queries, 2)
+	require.Equal(t, uint64(0xb2bbae), client.queries[0].FromBlock.Uint64())
+	require.Equal(t, uint64(0xb2bbaf), client.queries[1].ToBlock.Uint64())
+
+	backfilled := rDB.Get("0x0000000000000000000000005906afa8e5d168c788d93d82b0180205ba2cd340")
+	require.Equal(t, "alerts", backfilled.Header.NotificationID)
+	require.Equal(t, reportDB.SourceBackfill, backfilled.Header.Source)
+	require.Equal(t, quiknode.FormatLogs, backfilled.Header.Format)
+	require.False(t, backfilled.Header.Signed())
+	require.True(t, backfilled.Header.ChainVerified())
+
+	// a failed backfill is retried with the next report
+	client.mut.Lock()
+	client.fail = 1
+	client.mut.Unlock()
+	deliver(t, feed, rDB, "0xb2bbb3")
+	require.Equal(t, []quiknode.BlockRange{{First: 0xb2bbb1, Last: 0xb2bbb2}}, a.Gaps("alerts"))
+	require.ErrorContains(t, <-errs, "failed to backfill blocks 11713457-11713458 for id: alerts")
+
+	deliver(t, feed, rDB, "0xb2bbb4")
+	require.Empty(t, a.Gaps("alerts"))
+	require.Len(t, client.queries, 5)
+	require.Equal(t, uint64(0xb2bbb1), client.queries[3].FromBlock.Uint64())
+
+	// a late delivery doesn't move the highest block back
+	deliver(t, feed, rDB, "0xb2bbae")
+	require.Equal(t, uint64(0xb2bbb4), a.Highest("alerts"))
+	require.Empty(t, a.Gaps("alerts"))
+}
+
+func Test_Aggregator_Gaps(t *testing.T) {
+	var (
+		feed = make(chanFeed)
+		rDB  = reportDB.NewReportDB()
+		a    = NewReportAggregatorWithConfig(feed, rDB, AggregatorConfig{
+			Feeds: map[string]FeedConfig{"alerts": {NotificationID: "alerts"}},
+		})
+	)
+
+	// the first report sets the highest block when it is unknown
+	deliver(t, feed, rDB, "0xb2bbad")
+	require.Empty(t, a.Gaps("alerts"))
+
+	// the gaps are kept without a historical source
+	deliver(t, feed, rDB, "0xb2bbb0")
+	deliver(t, feed, rDB, "0xb2bbb1")
+	deliver(t, feed, rDB, "0xb2bbc0")
+	require.Equal(t, []quiknode.BlockRange{
+		{First: 0xb2bbae, Last: 0xb2bbaf},
+		{First: 0xb2bbb2, Last: 0xb2bbbf},
+	}, a.Gaps("alerts"))
+	require.Equal(t, uint64(0xb2bbc0), a.Highest("alerts"))
+}
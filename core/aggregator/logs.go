@@ -0,0 +1,223 @@
+package aggregator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"time"
+
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	quiknode "github.com/0xBow-io/base-eas-asp/pkg/quiknode"
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+// LogClient is the subset of ethclient.Client polled by LogReportFeed
+type LogClient interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
+}
+
+// LogFeedConfig of a LogReportFeed
+type LogFeedConfig struct {
+	// first block read, the head block if 0
+	FromBlock uint64
+	// blocks behind the head before the logs of a block are read
+	Confirmations uint64
+	// interval between the polls of the head, 2s if 0
+	PollInterval time.Duration
+	// maximum number of blocks of a query, 1000 if 0
+	MaxRange uint64
+	// receives the errors of the polls, which are retried, nil to discard them
+	Errors chan<- error
+}
+
+// LogReportFeed polls the Coinbase EAS logs of a JSON-RPC node,
+// the logs of a block are delivered as an unsigned report in the logs format.
+// It is an alternative to the QuickNode webhook which may miss deliveries.
+type LogReportFeed struct {
+	client LogClient
+	cfg    LogFeedConfig
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewLogReportFeed(client LogClient, cfg LogFeedConfig) *LogReportFeed {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 2 * time.Second
+	}
+	if cfg.MaxRange == 0 {
+		cfg.MaxRange = 1000
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &LogReportFeed{client: client, cfg: cfg, ctx: ctx, cancel: cancel}
+}
+
+// EASFilterQuery returns the query of the Coinbase attestations &
+// revocations of the blocks from-to
+func EASFilterQuery(from, to uint64) ethereum.FilterQuery {
+	return ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from),
+		ToBlock:   new(big.Int).SetUint64(to),
+		Addresses: []common.Address{common.HexToAddress(eas.BASE_EAS_ADDR)},
+		Topics: [][]common.Hash{
+			{common.HexToHash(eas.COINBASE_EAS_ATTEST_TOPIC), common.HexToHash(eas.COINBASE_EAS_REVOKE_TOPIC)},
+			nil,
+			{common.HexToHash(eas.COINBASE_EAS_HASH)},
+			{common.HexToHash(eas.COINBASE_EAS_SCHEMA_ID)},
+		},
+	}
+}
+
+// SubscribeTo polls the logs until Close is called, feedChan is closed then.
+// The reports are sent with NotificationID as notification ID.
+func (f *LogReportFeed) SubscribeTo(NotificationID string, feedChan chan<- reportDB.Report) error {
+	next := f.cfg.FromBlock
+	if next == 0 {
+		head, err := f.head()
+		if err != nil {
+			return err
+		}
+		next = head
+	}
+	go f.poll(NotificationID, next, feedChan)
+	return nil
+}
+
+// Close stops the polling
+func (f *LogReportFeed) Close() {
+	f.cancel()
+}
+
+func (f *LogReportFeed) poll(id string, next uint64, feedChan chan<- reportDB.Report) {
+	defer close(feedChan)
+	for {
+		head, err := f.head()
+		for err == nil && next <= head {
+			to := head
+			if to-next >= f.cfg.MaxRange {
+				to = next + f.cfg.MaxRange - 1
+			}
+			var reports []reportDB.Report
+			if reports, err = f.read(id, next, to); err != nil {
+				break
+			}
+			for _, r := range reports {
+				select {
+				case feedChan <- r:
+				case <-f.ctx.Done():
+					return
+				}
+			}
+			next = to + 1
+		}
+		if err != nil && f.ctx.Err() == nil && f.cfg.Errors != nil {
+			select {
+			case f.cfg.Errors <- errors.Wrap(err, "failed to read the EAS logs"):
+			case <-f.ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-time.After(f.cfg.PollInterval):
+		case <-f.ctx.Done():
+			return
+		}
+	}
+}
+
+// head returns the last confirmed block
+func (f *LogReportFeed) head() (uint64, error) {
+	head, err := f.client.BlockNumber(f.ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read the head block")
+	}
+	if head < f.cfg.Confirmations {
+		return 0, nil
+	}
+	return head - f.cfg.Confirmations, nil
+}
+
+// read the reports of the blocks from-to
+func (f *LogReportFeed) read(id string, from, to uint64) ([]reportDB.Report, error) {
+	logs, err := f.client.FilterLogs(f.ctx, EASFilterQuery(from, to))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to filter the logs of blocks %d-%d", from, to)
+	}
+	var reports []reportDB.Report
+	for _, block := range groupByBlock(logs) {
+		r, err := f.report(id, block)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, r)
+	}
+	return reports, nil
+}
+
+// Backfill reads the reports of the blocks from-to, MaxRange blocks per query
+func (f *LogReportFeed) Backfill(notificationID string, from, to uint64) ([]reportDB.Report, error) {
+	var reports []reportDB.Report
+	for from <= to {
+		last := to
+		if last-from >= f.cfg.MaxRange {
+			last = from + f.cfg.MaxRange - 1
+		}
+		r, err := f.read(notificationID, from, last)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, r...)
+		from = last + 1
+	}
+	return reports, nil
+}
+
+// groupByBlock returns the logs of each block in order, removed logs are dropped
+func groupByBlock(logs []types.Log) [][]types.Log {
+	var blocks [][]types.Log
+	for _, l := range logs {
+		if l.Removed {
+			continue
+		}
+		if n := len(blocks); n > 0 && blocks[n-1][0].BlockNumber == l.BlockNumber {
+			blocks[n-1] = append(blocks[n-1], l)
+			continue
+		}
+		blocks = append(blocks, []types.Log{l})
+	}
+	return blocks
+}
+
+// report of the logs of a block, timestamped with the block time
+func (f *LogReportFeed) report(id string, logs []types.Log) (reportDB.Report, error) {
+	number := logs[0].BlockNumber
+	header, err := f.client.HeaderByNumber(f.ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		return reportDB.Report{}, errors.Wrapf(err, "failed to read the header of block %d", number)
+	}
+	body, err := json.Marshal(logs)
+	if err != nil {
+		return reportDB.Report{}, err
+	}
+	hash := sha256.Sum256(body)
+
+	return reportDB.Report{
+		Header: reportDB.ReportHeader{
+			NotificationID: id,
+			ContentHash:    hex.EncodeToString(hash[:]),
+			Timestamp:      time.Unix(int64(header.Time), 0).UTC().Format(reportDB.Header_Time_Layout),
+			Format:         quiknode.FormatLogs,
+			Source:         reportDB.SourceRPC,
+		},
+		Body: string(body),
+	}, nil
+}
@@ -0,0 +1,235 @@
+package aggregator
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	quiknode "github.com/0xBow-io/base-eas-asp/pkg/quiknode"
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// fixtureClient serves the recorded logs & headers of testdata/eas_logs.json
+// & filters the logs like a node
+type fixtureClient struct {
+	mut     sync.Mutex
+	head    uint64
+	headers map[uint64]uint64
+	logs    []types.Log
+	queries []ethereum.FilterQuery
+	fail    int
+}
+
+func newFixtureClient(t *testing.T) *fixtureClient {
+	b, err := os.ReadFile("testdata/eas_logs.json")
+	require.NoError(t, err)
+	var fixture struct {
+		Head    hexutil.Uint64                    `json:"head"`
+		Headers map[hexutil.Uint64]hexutil.Uint64 `json:"headers"`
+		Logs    []types.Log                       `json:"logs"`
+	}
+	require.NoError(t, json.Unmarshal(b, &fixture))
+
+	c := &fixtureClient{head: uint64(fixture.Head), headers: make(map[uint64]uint64), logs: fixture.Logs}
+	for number, time := range fixture.Headers {
+		c.headers[uint64(number)] = uint64(time)
+	}
+	return c
+}
+
+func (c *fixtureClient) BlockNumber(context.Context) (uint64, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.head, nil
+}
+
+func (c *fixtureClient) HeaderByNumber(_ context.Context, number *big.Int) (*types.Header, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	time, ok := c.headers[number.Uint64()]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return &types.Header{Number: number, Time: time}, nil
+}
+
+func (c *fixtureClient) FilterLogs(_ context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.queries = append(c.queries, q)
+	if c.fail > 0 {
+		c.fail--
+		return nil, errors.New("rate limited")
+	}
+
+	var out []types.Log
+	for _, l := range c.logs {
+		if l.BlockNumber >= q.FromBlock.Uint64() && l.BlockNumber <= q.ToBlock.Uint64() && matches(q, l) {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+
+func matches(q ethereum.FilterQuery, l types.Log) bool {
+	if len(q.Addresses) > 0 && !contains(q.Addresses, l.Address) {
+		return false
+	}
+	for i, topics := range q.Topics {
+		if len(topics) > 0 && (i >= len(l.Topics) || !contains(topics, l.Topics[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains[T comparable](values []T, v T) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// add a block with the log at the head
+func (c *fixtureClient) add(l types.Log) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.head++
+	c.headers[c.head] = c.headers[c.head-1] + 2
+	l.BlockNumber = c.head
+	c.logs = append(c.logs, l)
+}
+
+func receive(t *testing.T, feedChan chan reportDB.Report) reportDB.Report {
+	select {
+	case r := <-feedChan:
+		return r
+	case <-time.After(time.Second):
+		require.FailNow(t, "missing report")
+		return reportDB.Report{}
+	}
+}
+
+func Test_LogReportFeed(t *testing.T) {
+	client := newFixtureClient(t)
+	feed := NewLogReportFeed(client, LogFeedConfig{FromBlock: 0xb2bbad, PollInterval: time.Millisecond, MaxRange: 2})
+	feedChan := make(chan reportDB.Report)
+	require.NoError(t, feed.SubscribeTo("rpc", feedChan))
+
+	// a report per block with EAS logs, the other logs are filtered out
+	r := receive(t, feedChan)
+	require.Equal(t, reportDB.ReportHeader{
+		NotificationID: "rpc",
+		ContentHash:    r.Header.ContentHash,
+		Timestamp:      "2024-03-12 04:04:14 +0000 UTC",
+		Format:         quiknode.FormatLogs,
+		Source:         reportDB.SourceRPC,
+	}, r.Header)
+	require.False(t, r.Header.Signed())
+	events, ts, err := r.Parse()
+	require.NoError(t, err)
+	require.Equal(t, int64(0x65efd43e), ts)
+	require.Equal(t, []eas.EAS{{
+		UUID:    common.HexToHash("0x8133f214f7bdaf516f03655db7406ba3c7945e5e4849238e43fdea6ef7a25cd4"),
+		Account: common.HexToHash("0xff9418c67d18c8e067141bd77be43e32c4c3abe7"),
+		Type:    eas.EAS_ATTEST,
+	}}, events)
+
+	r = receive(t, feedChan)
+	events, _, err = r.Parse()
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, eas.EAS_REVOKE, events[0].Type)
+	require.Equal(t, eas.EAS_ATTEST, events[1].Type)
+
+	// new blocks are polled
+	l := client.logs[0]
+	l.Data = common.HexToHash("0x01").Bytes()
+	client.add(l)
+	r = receive(t, feedChan)
+	events, _, err = r.Parse()
+	require.NoError(t, err)
+	require.Equal(t, common.HexToHash("0x01"), events[0].UUID)
+
+	// the queries are bounded by MaxRange & cover every block once
+	client.mut.Lock()
+	var next uint64 = 0xb2bbad
+	for _, q := range client.queries {
+		require.Equal(t, next, q.FromBlock.Uint64())
+		require.LessOrEqual(t, q.ToBlock.Uint64()-q.FromBlock.Uint64(), uint64(1))
+		require.Equal(t, EASFilterQuery(0, 0).Topics, q.Topics)
+		next = q.ToBlock.Uint64() + 1
+	}
+	client.mut.Unlock()
+
+	feed.Close()
+	require.Eventually(t, func() bool {
+		_, ok := <-feedChan
+		return !ok
+	}, time.Second, time.Millisecond)
+}
+
+func Test_LogReportFeed_Head(t *testing.T) {
+	client := newFixtureClient(t)
+	client.fail = 2
+
+	// starts at the head & waits for the confirmations
+	errs := make(chan error, 2)
+	feed := NewLogReportFeed(client, LogFeedConfig{Confirmations: 2, PollInterval: time.Millisecond, Errors: errs})
+	defer feed.Close()
+	feedChan := make(chan reportDB.Report)
+	require.NoError(t, feed.SubscribeTo("rpc", feedChan))
+
+	// the block 0xb2bbaf is confirmed by a new block,
+	// the failed queries are reported & retried
+	l := client.logs[0]
+	client.add(l)
+	r := receive(t, feedChan)
+	events, _, err := r.Parse()
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	for i := 0; i < 2; i++ {
+		require.ErrorContains(t, <-errs, "failed to read the EAS logs")
+	}
+
+	select {
+	case r := <-feedChan:
+		require.FailNow(t, "unconfirmed report", r.Header.Timestamp)
+	case <-time.After(20 * time.Millisecond):
+	}
+	client.add(client.logs[1])
+	client.add(client.logs[1])
+
+	r = receive(t, feedChan)
+	events, _, err = r.Parse()
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+}
+
+func Test_LogReportFeed_Aggregator(t *testing.T) {
+	client := newFixtureClient(t)
+	feed := NewLogReportFeed(client, LogFeedConfig{FromBlock: 0xb2bbad, PollInterval: time.Millisecond})
+	defer feed.Close()
+
+	rDB := reportDB.NewReportDB()
+	NewReportAggregatorWithFeeds(feed, rDB, map[string]FeedConfig{"rpc": {NotificationID: "rpc"}})
+
+	account := common.HexToHash("0xff9418c67d18c8e067141bd77be43e32c4c3abe7").String()
+	require.Eventually(t, func() bool {
+		_, events, err := rDB.GetEvents(account)
+		return err == nil && len(events) == 1 && events[0].Type == eas.EAS_REVOKE
+	}, time.Second, time.Millisecond)
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	quiknode "github.com/0xBow-io/base-eas-asp/pkg/quiknode"
 	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -24,6 +25,12 @@ const (
 	mock_base_commitmentID = `0x8133f214f7bdaf516f03655db7406ba3c7945e5e4849238e43fdea6ef7a25cd4`
 )
 
+// webhook secret & url path signing the mock reports
+const (
+	MockSecret  = mock_secret
+	MockURLPath = mock_url_path
+)
+
 type MockReportFeed struct {
 	reportPeriod time.Duration `default:"5s"`
 }
@@ -32,6 +39,11 @@ func NewMockReportFeed() *MockReportFeed {
 	return &MockReportFeed{}
 }
 
+// NewMockReportFeedWithPeriod generates a report every period
+func NewMockReportFeedWithPeriod(period time.Duration) *MockReportFeed {
+	return &MockReportFeed{reportPeriod: period}
+}
+
 func genPayloadHash(payload string) string {
 	// payloadHash is the hash of the SHA256 hash of url_path + payload
 	hash := sha256.Sum256([]byte(mock_url_path + payload))
@@ -76,6 +88,7 @@ func (m *MockReportFeed) genRandReport(id string) (r reportDB.Report) {
 		NotificationID: id,
 		Nonce:          mock_nonce,
 		Timestamp:      time.Now().Format(reportDB.Header_Time_Layout),
+		Format:         quiknode.FormatQuickAlerts,
 	}
 
 	// generate random payload from basePayload
@@ -0,0 +1,48 @@
+package aggregator
+
+import (
+	"io"
+	"time"
+
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+)
+
+// ReplayReportFeed feeds the reports of an archive in their original order.
+// Reports are spaced by the time between their timestamps divided by speed,
+// they are fed without delay when speed <= 0.
+type ReplayReportFeed struct {
+	entries []reportDB.ArchiveEntry
+	speed   float64
+}
+
+// NewReplayReportFeed reads the archive written by reportDB.WriteArchive
+func NewReplayReportFeed(archive io.Reader, speed float64) (*ReplayReportFeed, error) {
+	entries, err := reportDB.ReadArchive(archive)
+	if err != nil {
+		return nil, err
+	}
+	return &ReplayReportFeed{entries: entries, speed: speed}, nil
+}
+
+// SubscribeTo feeds the reports of the notification ID,
+// feedChan is closed once every report is fed
+func (f *ReplayReportFeed) SubscribeTo(id string, feedChan chan<- reportDB.Report) error {
+	go func() {
+		defer close(feedChan)
+
+		var last time.Time
+		for _, e := range f.entries {
+			if e.Report.Header.NotificationID != id {
+				continue
+			}
+			if t, err := e.Report.GetTime(); err == nil {
+				if f.speed > 0 && !last.IsZero() && t.After(last) {
+					time.Sleep(time.Duration(float64(t.Sub(last)) / f.speed))
+				}
+				last = t
+			}
+			feedChan <- e.Report
+		}
+	}()
+	return nil
+}
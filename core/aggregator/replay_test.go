@@ -0,0 +1,83 @@
+package aggregator
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ReplayReportFeed(t *testing.T) {
+	feed := NewMockReportFeedWithPeriod(0)
+	start := time.Now()
+
+	// reports 100ms apart, the last one from another feed
+	var entries []reportDB.ArchiveEntry
+	for i := 0; i < 4; i++ {
+		r := feed.genRandReport("feed")
+		r.Header.Timestamp = start.Add(time.Duration(i) * 100 * time.Millisecond).Format(reportDB.Header_Time_Layout)
+		if i == 3 {
+			r.Header.NotificationID = "other"
+		}
+		entries = append(entries, reportDB.ArchiveEntry{Report: r})
+	}
+	var archive bytes.Buffer
+	require.NoError(t, reportDB.WriteArchive(&archive, entries))
+
+	for _, tc := range []struct {
+		speed    float64
+		min, max time.Duration
+	}{
+		{speed: 0, min: 0, max: 100 * time.Millisecond},
+		{speed: 10, min: 20 * time.Millisecond, max: 150 * time.Millisecond},
+		{speed: 1, min: 200 * time.Millisecond, max: time.Second},
+	} {
+		replay, err := NewReplayReportFeed(bytes.NewReader(archive.Bytes()), tc.speed)
+		require.NoError(t, err)
+
+		feedChan := make(chan reportDB.Report)
+		begin := time.Now()
+		require.NoError(t, replay.SubscribeTo("feed", feedChan))
+
+		var got []reportDB.Report
+		for r := range feedChan {
+			got = append(got, r)
+		}
+		elapsed := time.Since(begin)
+
+		require.Equal(t, []reportDB.Report{entries[0].Report, entries[1].Report, entries[2].Report}, got)
+		require.GreaterOrEqual(t, elapsed, tc.min, "speed %v", tc.speed)
+		require.Less(t, elapsed, tc.max, "speed %v", tc.speed)
+	}
+}
+
+func Test_Replay_Aggregator(t *testing.T) {
+	notifChan := make(chan string)
+	rDB := reportDB.NewReportDB()
+	rDB.SubscribeToNotif(notifChan)
+
+	NewReportAggregator(NewMockReportFeedWithPeriod(time.Millisecond), rDB)
+	for i := 0; i < 5; i++ {
+		<-notifChan
+	}
+
+	var archive bytes.Buffer
+	require.NoError(t, reportDB.WriteArchive(&archive, rDB.Entries()[:5]))
+	entries, err := reportDB.ReadArchive(bytes.NewReader(archive.Bytes()))
+	require.NoError(t, err)
+
+	// replaying the archive restores the reports
+	replayNotif := make(chan string)
+	replayDB := reportDB.NewReportDB()
+	replayDB.SubscribeToNotif(replayNotif)
+	replay, err := NewReplayReportFeed(&archive, 0)
+	require.NoError(t, err)
+	NewReportAggregator(replay, replayDB)
+	for range entries {
+		<-replayNotif
+	}
+
+	require.Equal(t, entries, replayDB.Entries())
+}
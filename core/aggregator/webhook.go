@@ -0,0 +1,116 @@
+package aggregator
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	poa "github.com/0xBow-io/base-eas-asp/pkg/proof-of-audit"
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+)
+
+// max size of a webhook delivery
+const maxWebhookBody = 16 << 20
+
+/*
+WebhookReportFeed receives the reports QuickNode delivers to the webhook.
+
+The deliveries are posted to the url path of the webhook, their body is
+stored as received & their signature is checked with the webhook secret.
+Deliveries with an invalid signature or an unsubscribed notification ID
+are rejected, QuickNode retries them.
+*/
+type WebhookReportFeed struct {
+	secret  string
+	urlPath string
+
+	mut  sync.Mutex
+	subs map[string]chan<- reportDB.Report
+}
+
+func NewWebhookReportFeed(secret, urlPath string) *WebhookReportFeed {
+	return &WebhookReportFeed{secret: secret, urlPath: urlPath, subs: make(map[string]chan<- reportDB.Report)}
+}
+
+// SubscribeTo feeds the deliveries of the notification ID
+func (f *WebhookReportFeed) SubscribeTo(id string, feedChan chan<- reportDB.Report) error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.subs[id] = feedChan
+	return nil
+}
+
+func (f *WebhookReportFeed) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != f.urlPath {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody+1))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxWebhookBody {
+		http.Error(w, "body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	report := reportDB.Report{
+		Header: reportDB.ReportHeader{
+			NotificationID:  r.Header.Get("x-qn-notification-id"),
+			ContentHash:     r.Header.Get("x-qn-content-hash"),
+			Nonce:           r.Header.Get("x-qn-nonce"),
+			Signature:       r.Header.Get("x-qn-signature"),
+			Timestamp:       r.Header.Get("x-qn-timestamp"),
+			ContentEncoding: r.Header.Get("Content-Encoding"),
+		},
+		Body: string(body),
+	}
+	h := report.Header
+	if !poa.VerifySignature(f.secret, f.urlPath, h.Nonce, h.Timestamp, report.Body, h.Signature) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	f.mut.Lock()
+	feed, ok := f.subs[h.NotificationID]
+	f.mut.Unlock()
+	if !ok {
+		http.Error(w, "unknown notification id", http.StatusNotFound)
+		return
+	}
+	select {
+	case feed <- report:
+		w.WriteHeader(http.StatusOK)
+	case <-r.Context().Done():
+		http.Error(w, "report not collected", http.StatusServiceUnavailable)
+	}
+}
+
+// Serve receives the deliveries on addr until ctx is done
+func (f *WebhookReportFeed) Serve(ctx context.Context, addr string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           f,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errChan := make(chan error, 1)
+	go func() { errChan <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
@@ -0,0 +1,61 @@
+package aggregator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+	"github.com/stretchr/testify/require"
+)
+
+// post delivers the report to the webhook & returns the status code
+func post(t *testing.T, url string, r reportDB.Report) int {
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(r.Body))
+	require.NoError(t, err)
+	req.Header.Set("x-qn-notification-id", r.Header.NotificationID)
+	req.Header.Set("x-qn-content-hash", r.Header.ContentHash)
+	req.Header.Set("x-qn-nonce", r.Header.Nonce)
+	req.Header.Set("x-qn-signature", r.Header.Signature)
+	req.Header.Set("x-qn-timestamp", r.Header.Timestamp)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func Test_WebhookReportFeed(t *testing.T) {
+	feed := NewWebhookReportFeed(MockSecret, MockURLPath)
+	srv := httptest.NewServer(feed)
+	defer srv.Close()
+
+	notifChan := make(chan string, 1)
+	rDB := reportDB.NewReportDB()
+	rDB.SubscribeToNotif(notifChan)
+	id := CoinbaseEASFeedID
+	NewReportAggregator(feed, rDB)
+	require.Eventually(t, func() bool {
+		feed.mut.Lock()
+		defer feed.mut.Unlock()
+		_, ok := feed.subs[id]
+		return ok
+	}, time.Second, time.Millisecond)
+
+	r := new(MockReportFeed).genRandReport(id)
+	require.Equal(t, http.StatusOK, post(t, srv.URL+MockURLPath, r))
+	publicID := <-notifChan
+	stored := rDB.Get(publicID)
+	require.Equal(t, r.Body, stored.Body)
+	require.Equal(t, r.Header.Signature, stored.Header.Signature)
+	require.True(t, stored.Header.Signed())
+
+	// the signature covers the body & url path
+	forged := new(MockReportFeed).genRandReport(id)
+	forged.Body = r.Body
+	require.Equal(t, http.StatusUnauthorized, post(t, srv.URL+MockURLPath, forged))
+	require.Equal(t, http.StatusNotFound, post(t, srv.URL+"/other", r))
+	require.Equal(t, http.StatusNotFound, post(t, srv.URL+MockURLPath, new(MockReportFeed).genRandReport("unknown")))
+	require.Empty(t, notifChan)
+}
@@ -0,0 +1,257 @@
+package api
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/0xBow-io/base-eas-asp/core/asp"
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	nmt "github.com/0xBow-io/base-eas-asp/pkg/nmt"
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+	sDB "github.com/0xBow-io/base-eas-asp/pkg/statedb"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+)
+
+//go:embed openapi.yaml
+var OpenAPI []byte
+
+var ErrReportNotFound = errors.New("report not found")
+
+type StateDB interface {
+	NsExists(ns string) bool
+	GetMembership(ns string) sDB.MEMBERSHIP_TYPE
+}
+
+type ReportDB interface {
+	Get(publicID string) reportDB.Report
+}
+
+type ASP interface {
+	ZeroValue() nmt.Element
+	Latest() (asp.RootVersion, error)
+	Version(v uint64) (asp.RootVersion, error)
+	Prove(account string, m sDB.MEMBERSHIP_TYPE) (asp.MembershipProof, error)
+}
+
+type MembershipResponse struct {
+	Account    common.Hash         `json:"account"`
+	Membership sDB.MEMBERSHIP_TYPE `json:"membership"`
+	// false when the account has no events in StateDB
+	Known bool `json:"known"`
+}
+
+// ProofResponse is a membership proof with the zero value
+// needed to verify it
+type ProofResponse struct {
+	asp.MembershipProof
+	ZeroValue hexutil.Bytes `json:"zeroValue"`
+}
+
+type ReportResponse struct {
+	Account common.Hash     `json:"account"`
+	Report  reportDB.Report `json:"report"`
+	Ts      int64           `json:"ts"`
+	// events of the account parsed from the report
+	Events []eas.EAS `json:"events"`
+	// set when the report body could not be parsed
+	ParseErr string `json:"parseError,omitempty"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+/*
+Server exposes the memberships, association set roots,
+membership proofs & latest reports of accounts over HTTP/JSON.
+
+Accounts are either 20 byte addresses or 32 byte namespaces in hex.
+The endpoints are described in openapi.yaml.
+*/
+type Server struct {
+	stateDB  StateDB
+	reportDB ReportDB
+	asp      ASP
+}
+
+func NewServer(stateDB StateDB, rDb ReportDB, a ASP) *Server {
+	return &Server{stateDB: stateDB, reportDB: rDb, asp: a}
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/membership/", get(s.handleMembership))
+	mux.HandleFunc("/proof/", get(s.handleProof))
+	mux.HandleFunc("/root", get(s.handleRoot))
+	mux.HandleFunc("/reports/", get(s.handleReports))
+	mux.HandleFunc("/openapi.yaml", get(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(OpenAPI)
+	}))
+	return mux
+}
+
+// Serve serves the API on addr until ctx is done
+func (s *Server) Serve(ctx context.Context, addr string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errChan := make(chan error, 1)
+	go func() { errChan <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+func get(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+		h(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, ErrorResponse{Error: err.Error()})
+}
+
+// errorStatus maps ASP errors to HTTP status codes
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, asp.ErrInvalidMember), errors.Is(err, asp.ErrUnknownSet):
+		return http.StatusBadRequest
+	case errors.Is(err, asp.ErrNoVersion), errors.Is(err, asp.ErrNotPublished),
+		errors.Is(err, asp.ErrEmptySet), errors.Is(err, ErrReportNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// account parses the account at the end of the path
+func account(r *http.Request, prefix string) (common.Hash, error) {
+	acc := strings.TrimPrefix(r.URL.Path, prefix)
+	b, err := hexutil.Decode(acc)
+	if err != nil || (len(b) != common.AddressLength && len(b) != common.HashLength) {
+		return common.Hash{}, errors.Wrap(asp.ErrInvalidMember, acc)
+	}
+	return common.BytesToHash(b), nil
+}
+
+func (s *Server) handleMembership(w http.ResponseWriter, r *http.Request) {
+	acc, err := account(r, "/membership/")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, MembershipResponse{
+		Account:    acc,
+		Membership: s.stateDB.GetMembership(acc.Hex()),
+		Known:      s.stateDB.NsExists(acc.Hex()),
+	})
+}
+
+// handleProof proves the account against the set given by
+// the `set` query parameter, defaults to its current membership
+func (s *Server) handleProof(w http.ResponseWriter, r *http.Request) {
+	acc, err := account(r, "/proof/")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	set := sDB.MEMBERSHIP_TYPE(r.URL.Query().Get("set"))
+	if set == "" {
+		if set = s.stateDB.GetMembership(acc.Hex()); set == sDB.NONE {
+			writeError(w, http.StatusNotFound, errors.New("account has no membership"))
+			return
+		}
+	}
+	if !set.IsValid() {
+		writeError(w, http.StatusBadRequest, errors.Wrap(asp.ErrUnknownSet, string(set)))
+		return
+	}
+
+	proof, err := s.asp.Prove(acc.Hex(), set)
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+	zero := s.asp.ZeroValue()
+	writeJSON(w, http.StatusOK, ProofResponse{MembershipProof: proof, ZeroValue: zero[:]})
+}
+
+// handleRoot returns the latest published roots
+// or the ones of the version given by the `version` query parameter
+func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
+	var (
+		v   asp.RootVersion
+		err error
+	)
+	if q := r.URL.Query().Get("version"); q != "" {
+		n, perr := strconv.ParseUint(q, 10, 64)
+		if perr != nil {
+			writeError(w, http.StatusBadRequest, errors.Wrap(perr, "invalid version"))
+			return
+		}
+		v, err = s.asp.Version(n)
+	} else {
+		v, err = s.asp.Latest()
+	}
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func (s *Server) handleReports(w http.ResponseWriter, r *http.Request) {
+	acc, err := account(r, "/reports/")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	report := s.reportDB.Get(acc.String())
+	if report.Body == "" {
+		writeError(w, http.StatusNotFound, errors.Wrap(ErrReportNotFound, acc.Hex()))
+		return
+	}
+
+	resp := ReportResponse{Account: acc, Report: report, Ts: report.GetTimeStamp(), Events: []eas.EAS{}}
+	events, _, err := report.Parse()
+	if err != nil {
+		resp.ParseErr = err.Error()
+	}
+	for _, e := range events {
+		if e.Account == acc {
+			resp.Events = append(resp.Events, e)
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,241 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/0xBow-io/base-eas-asp/core/asp"
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	mock "github.com/0xBow-io/base-eas-asp/pkg/mock"
+	nmt "github.com/0xBow-io/base-eas-asp/pkg/nmt"
+	pp "github.com/0xBow-io/base-eas-asp/pkg/privacy_pool"
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+	sDB "github.com/0xBow-io/base-eas-asp/pkg/statedb"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// genReport returns a report with a single EAS attestation for account
+func genReport(t *testing.T, account common.Hash) reportDB.Report {
+	log := map[string]interface{}{
+		"address":         eas.BASE_EAS_ADDR,
+		"topics":          []string{eas.COINBASE_EAS_ATTEST_TOPIC, account.Hex(), eas.COINBASE_EAS_HASH, eas.COINBASE_EAS_SCHEMA_ID},
+		"data":            common.BytesToHash(mock.GenRandomHash(32)).Hex(),
+		"blockNumber":     "0xb2bbad",
+		"transactionHash": common.BytesToHash(mock.GenRandomHash(32)).Hex(),
+		"logIndex":        "0x1",
+		"removed":         false,
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"matchedReceipts":     []interface{}{map[string]interface{}{"logs": []interface{}{log}}},
+		"matchedTransactions": []interface{}{map[string]interface{}{"chainId": eas.BASE_CHAIN_ID}},
+	})
+	require.NoError(t, err)
+
+	return reportDB.Report{
+		Header: reportDB.ReportHeader{
+			NotificationID: "id",
+			ContentHash:    "hash",
+			Nonce:          "nonce",
+			Signature:      "signature",
+			Timestamp:      time.Now().Format(reportDB.Header_Time_Layout),
+		},
+		Body: string(body),
+	}
+}
+
+type testEnv struct {
+	srv      *httptest.Server
+	sdb      *sDB.StateDB
+	rdb      *reportDB.ReportDB
+	asp      *asp.ASP
+	included common.Hash
+	excluded common.Hash
+	unknown  common.Hash
+}
+
+func setup(t *testing.T) *testEnv {
+	sdb, err := sDB.NewStateDB()
+	require.NoError(t, err)
+
+	env := &testEnv{
+		sdb:      sdb,
+		rdb:      reportDB.NewReportDB(),
+		included: common.BytesToHash(mock.GenRandomHash(20)),
+		excluded: common.BytesToHash(mock.GenRandomHash(20)),
+		unknown:  common.BytesToHash(mock.GenRandomHash(20)),
+	}
+	for account, m := range map[common.Hash]sDB.MEMBERSHIP_TYPE{
+		env.included: sDB.INCLUSION,
+		env.excluded: sDB.EXCLUSION,
+		env.unknown:  sDB.NONE,
+	} {
+		for i := 0; i < 2; i++ {
+			e := pp.Event{TxHash: common.BytesToHash(mock.GenRandomHash(31)), From: account}
+			se, err := e.Serialize()
+			require.NoError(t, err)
+			require.NoError(t, sdb.AddEvent(se))
+		}
+		if m != sDB.NONE {
+			require.NoError(t, sdb.CompareAndSetMembership(account.Hex(), sDB.NONE, m))
+		}
+	}
+
+	env.asp = asp.NewASP(sdb, nmt.NsPoseidonHasher, asp.DefaultZeroValue())
+	env.srv = httptest.NewServer(NewServer(sdb, env.rdb, env.asp).Handler())
+	t.Cleanup(env.srv.Close)
+	return env
+}
+
+func (env *testEnv) get(t *testing.T, path string, status int, out interface{}) {
+	resp, err := http.Get(env.srv.URL + path)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, status, resp.StatusCode, path)
+	if out != nil {
+		require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+	}
+}
+
+func Test_Server_Membership(t *testing.T) {
+	env := setup(t)
+
+	var m MembershipResponse
+	env.get(t, "/membership/"+env.included.Hex(), http.StatusOK, &m)
+	require.Equal(t, env.included, m.Account)
+	require.Equal(t, sDB.INCLUSION, m.Membership)
+	require.True(t, m.Known)
+
+	// 20 byte addresses are accepted
+	env.get(t, "/membership/"+common.BytesToAddress(env.excluded.Bytes()).Hex(), http.StatusOK, &m)
+	require.Equal(t, env.excluded, m.Account)
+	require.Equal(t, sDB.EXCLUSION, m.Membership)
+
+	env.get(t, "/membership/"+common.BytesToHash(mock.GenRandomHash(20)).Hex(), http.StatusOK, &m)
+	require.Equal(t, sDB.NONE, m.Membership)
+	require.False(t, m.Known)
+
+	var e ErrorResponse
+	for _, acc := range []string{"", "0x", "0x1234", "abcd", env.included.Hex() + "/x"} {
+		env.get(t, "/membership/"+acc, http.StatusBadRequest, &e)
+		require.NotEmpty(t, e.Error)
+	}
+
+	resp, err := http.Post(env.srv.URL+"/membership/"+env.included.Hex(), "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func Test_Server_Root(t *testing.T) {
+	env := setup(t)
+
+	var e ErrorResponse
+	env.get(t, "/root", http.StatusNotFound, &e)
+
+	v1, err := env.asp.Publish()
+	require.NoError(t, err)
+
+	var v asp.RootVersion
+	env.get(t, "/root", http.StatusOK, &v)
+	require.Equal(t, v1.Version, v.Version)
+	for _, m := range asp.Sets {
+		require.True(t, v1.Roots[m].Root.Equal(v.Roots[m].Root))
+		require.Equal(t, v1.Roots[m].Size, v.Roots[m].Size)
+		require.Equal(t, v1.Roots[m].HashID, v.Roots[m].HashID)
+	}
+
+	require.NoError(t, env.sdb.CompareAndSetMembership(env.unknown.Hex(), sDB.NONE, sDB.INCLUSION))
+	v2, err := env.asp.Publish()
+	require.NoError(t, err)
+
+	env.get(t, "/root", http.StatusOK, &v)
+	require.Equal(t, v2.Version, v.Version)
+	env.get(t, "/root?version=1", http.StatusOK, &v)
+	require.Equal(t, v1.Version, v.Version)
+	require.True(t, v1.Roots[sDB.INCLUSION].Root.Equal(v.Roots[sDB.INCLUSION].Root))
+
+	env.get(t, "/root?version=3", http.StatusNotFound, &e)
+	env.get(t, "/root?version=x", http.StatusBadRequest, &e)
+}
+
+func Test_Server_Proof(t *testing.T) {
+	env := setup(t)
+
+	var e ErrorResponse
+	env.get(t, "/proof/"+env.included.Hex(), http.StatusNotFound, &e)
+
+	_, err := env.asp.Publish()
+	require.NoError(t, err)
+
+	// proof against the current membership
+	var p ProofResponse
+	env.get(t, "/proof/"+env.included.Hex(), http.StatusOK, &p)
+	require.Equal(t, env.included, p.Account)
+	require.Equal(t, sDB.INCLUSION, p.Membership)
+	require.False(t, p.Proof.IsAbsenceProof())
+	require.NoError(t, p.Verify(nmt.ToElement(p.ZeroValue)))
+
+	// tampered roots fail verification
+	p.Root[len(p.Root)-1] ^= 0xff
+	require.Error(t, p.Verify(nmt.ToElement(p.ZeroValue)))
+
+	// the included account is absent from the exclusion set
+	p = ProofResponse{}
+	env.get(t, "/proof/"+env.included.Hex()+"?set=exclusion", http.StatusOK, &p)
+	require.Equal(t, sDB.EXCLUSION, p.Membership)
+	require.NoError(t, p.Verify(nmt.ToElement(p.ZeroValue)))
+
+	env.get(t, "/proof/"+env.unknown.Hex(), http.StatusNotFound, &e)
+	env.get(t, "/proof/"+env.included.Hex()+"?set=partial_inclusion", http.StatusNotFound, &e)
+	env.get(t, "/proof/"+env.included.Hex()+"?set=none", http.StatusBadRequest, &e)
+	env.get(t, "/proof/"+env.included.Hex()+"?set=bogus", http.StatusBadRequest, &e)
+	env.get(t, "/proof/0x12", http.StatusBadRequest, &e)
+}
+
+func Test_Server_Reports(t *testing.T) {
+	env := setup(t)
+
+	var e ErrorResponse
+	env.get(t, "/reports/"+env.included.Hex(), http.StatusNotFound, &e)
+
+	report := genReport(t, env.included)
+	env.rdb.Set(env.included.Hex(), report.GetTimeStamp(), report)
+
+	var r ReportResponse
+	env.get(t, "/reports/"+env.included.Hex(), http.StatusOK, &r)
+	require.Equal(t, env.included, r.Account)
+	require.Equal(t, report, r.Report)
+	require.Equal(t, report.GetTimeStamp(), r.Ts)
+	require.Empty(t, r.ParseErr)
+	require.Len(t, r.Events, 1)
+	require.Equal(t, env.included, r.Events[0].Account)
+	require.Equal(t, eas.EAS_ATTEST, r.Events[0].Type)
+
+	// unparsable reports are still served
+	report.Body = "{"
+	env.rdb.Set(env.excluded.Hex(), report.GetTimeStamp(), report)
+	env.get(t, "/reports/"+env.excluded.Hex(), http.StatusOK, &r)
+	require.NotEmpty(t, r.ParseErr)
+	require.Empty(t, r.Events)
+}
+
+func Test_Server_OpenAPI(t *testing.T) {
+	env := setup(t)
+
+	resp, err := http.Get(env.srv.URL + "/openapi.yaml")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/yaml", resp.Header.Get("Content-Type"))
+
+	// every endpoint is documented
+	for _, path := range []string{"/membership/{account}:", "/proof/{account}:", "/root:", "/reports/{account}:"} {
+		require.Contains(t, string(OpenAPI), path)
+	}
+}
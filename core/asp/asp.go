@@ -0,0 +1,220 @@
+package asp
+
+import (
+	"encoding/hex"
+	"sync"
+	"time"
+
+	nmt "github.com/0xBow-io/base-eas-asp/pkg/nmt"
+	sDB "github.com/0xBow-io/base-eas-asp/pkg/statedb"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrNoVersion     = errors.New("no published version")
+	ErrUnknownSet    = errors.New("unknown association set")
+	ErrEmptySet      = errors.New("association set is empty")
+	ErrNotPublished  = errors.New("version not found")
+	ErrInvalidMember = errors.New("invalid account")
+)
+
+// Association sets published by the ASP
+var Sets = []sDB.MEMBERSHIP_TYPE{sDB.INCLUSION, sDB.EXCLUSION, sDB.PARTIAL_INCLUSION}
+
+type StateDB interface {
+	GetMembership(ns string) sDB.MEMBERSHIP_TYPE
+	MembershipSet(m sDB.MEMBERSHIP_TYPE) (*nmt.NsGroups, error)
+}
+
+// SetRoot is the commitment to an association set,
+// Root is nil for an empty set & Size is its number of accounts
+type SetRoot struct {
+	Membership sDB.MEMBERSHIP_TYPE `json:"membership"`
+	Root       nmt.Node            `json:"root"`
+	HashID     nmt.HashID          `json:"hashId"`
+	Size       int                 `json:"size"`
+}
+
+// RootVersion holds the roots of every association set at a version
+type RootVersion struct {
+	Version uint64                          `json:"version"`
+	Ts      int64                           `json:"ts"`
+	Roots   map[sDB.MEMBERSHIP_TYPE]SetRoot `json:"roots"`
+}
+
+// MembershipProof proves that an account is (or is not)
+// a member of an association set at a version.
+type MembershipProof struct {
+	Version    uint64              `json:"version"`
+	Account    common.Hash         `json:"account"`
+	Membership sDB.MEMBERSHIP_TYPE `json:"membership"`
+	Root       nmt.Node            `json:"root"`
+	Proof      nmt.Proof           `json:"proof"`
+}
+
+// Verify checks the proof against its root
+func (p MembershipProof) Verify(zeroValue nmt.Element) error {
+	return p.Proof.VerifyNamespace(zeroValue, p.Account.Bytes(), p.Root)
+}
+
+/*
+ASP builds the association sets as namespaced merkle trees
+where every account is a namespace holding its events.
+
+Publish snapshots the memberships of StateDB into a new version
+when any of the set roots changed. Proofs are served against
+the latest published version.
+*/
+type ASP struct {
+	stateDB StateDB
+	hasher  nmt.Hasher
+	zero    nmt.Element
+
+	mut      sync.RWMutex
+	versions []RootVersion
+	trees    map[sDB.MEMBERSHIP_TYPE]*nmt.Tree
+}
+
+// DefaultZeroValue is the zero value used to pad the set trees
+func DefaultZeroValue() nmt.Element {
+	zero, _ := hex.DecodeString(nmt.MerkleZeroHex)
+	return nmt.Element(zero)
+}
+
+func NewASP(stateDB StateDB, hasher nmt.Hasher, zeroValue nmt.Element) *ASP {
+	if hasher == nil {
+		hasher = nmt.NsPoseidonHasher
+	}
+	return &ASP{
+		stateDB: stateDB,
+		hasher:  hasher,
+		zero:    zeroValue,
+		trees:   make(map[sDB.MEMBERSHIP_TYPE]*nmt.Tree),
+	}
+}
+
+func (a *ASP) ZeroValue() nmt.Element {
+	return a.zero
+}
+
+func (a *ASP) build() (map[sDB.MEMBERSHIP_TYPE]*nmt.Tree, map[sDB.MEMBERSHIP_TYPE]SetRoot, error) {
+	var (
+		trees = make(map[sDB.MEMBERSHIP_TYPE]*nmt.Tree)
+		roots = make(map[sDB.MEMBERSHIP_TYPE]SetRoot)
+	)
+	for _, m := range Sets {
+		set, err := a.stateDB.MembershipSet(m)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to get set "+string(m))
+		}
+
+		root := SetRoot{Membership: m, HashID: a.hasher.ID(), Size: set.Len()}
+		if set.Size() > 0 {
+			tree, err := nmt.NewTree(set, a.hasher, a.zero)
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "failed to build set "+string(m))
+			}
+			trees[m] = tree
+			root.Root = tree.Root()
+		}
+		roots[m] = root
+	}
+	return trees, roots, nil
+}
+
+func rootsEqual(a, b map[sDB.MEMBERSHIP_TYPE]SetRoot) bool {
+	for _, m := range Sets {
+		if !a[m].Root.Equal(b[m].Root) || a[m].Size != b[m].Size {
+			return false
+		}
+	}
+	return true
+}
+
+// Publish builds the association sets and publishes a new version
+// if any root changed, otherwise the latest version is returned.
+func (a *ASP) Publish() (RootVersion, error) {
+	trees, roots, err := a.build()
+	if err != nil {
+		return RootVersion{}, err
+	}
+
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if n := len(a.versions); n > 0 && rootsEqual(a.versions[n-1].Roots, roots) {
+		return a.versions[n-1], nil
+	}
+
+	v := RootVersion{
+		Version: uint64(len(a.versions)) + 1,
+		Ts:      time.Now().Unix(),
+		Roots:   roots,
+	}
+	a.versions = append(a.versions, v)
+	a.trees = trees
+	return v, nil
+}
+
+// Latest returns the latest published version
+func (a *ASP) Latest() (RootVersion, error) {
+	a.mut.RLock()
+	defer a.mut.RUnlock()
+	if len(a.versions) == 0 {
+		return RootVersion{}, ErrNoVersion
+	}
+	return a.versions[len(a.versions)-1], nil
+}
+
+// Version returns the published version v, versions start at 1
+func (a *ASP) Version(v uint64) (RootVersion, error) {
+	a.mut.RLock()
+	defer a.mut.RUnlock()
+	if v == 0 || v > uint64(len(a.versions)) {
+		return RootVersion{}, errors.Wrapf(ErrNotPublished, "%d", v)
+	}
+	return a.versions[v-1], nil
+}
+
+// Prove returns a proof of membership (or absence) of the account
+// in the set m of the latest published version.
+func (a *ASP) Prove(account string, m sDB.MEMBERSHIP_TYPE) (MembershipProof, error) {
+	if !common.IsHexAddress(account) && len(common.FromHex(account)) != common.HashLength {
+		return MembershipProof{}, errors.Wrap(ErrInvalidMember, account)
+	}
+
+	a.mut.RLock()
+	defer a.mut.RUnlock()
+
+	if len(a.versions) == 0 {
+		return MembershipProof{}, ErrNoVersion
+	}
+	latest := a.versions[len(a.versions)-1]
+	if _, ok := latest.Roots[m]; !ok {
+		return MembershipProof{}, errors.Wrap(ErrUnknownSet, string(m))
+	}
+	tree, ok := a.trees[m]
+	if !ok {
+		return MembershipProof{}, errors.Wrap(ErrEmptySet, string(m))
+	}
+
+	ns := common.HexToHash(account)
+	proof, err := tree.Prove(ns.Bytes())
+	if err != nil {
+		return MembershipProof{}, errors.Wrap(err, "failed to prove membership")
+	}
+	return MembershipProof{
+		Version:    latest.Version,
+		Account:    ns,
+		Membership: m,
+		Root:       tree.Root(),
+		Proof:      proof,
+	}, nil
+}
+
+// ProveMembership proves the account against the set
+// of its current membership in StateDB.
+func (a *ASP) ProveMembership(account string) (MembershipProof, error) {
+	return a.Prove(account, a.stateDB.GetMembership(common.HexToHash(account).Hex()))
+}
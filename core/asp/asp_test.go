@@ -0,0 +1,126 @@
+package asp
+
+import (
+	"testing"
+
+	mock "github.com/0xBow-io/base-eas-asp/pkg/mock"
+	nmt "github.com/0xBow-io/base-eas-asp/pkg/nmt"
+	pp "github.com/0xBow-io/base-eas-asp/pkg/privacy_pool"
+	sDB "github.com/0xBow-io/base-eas-asp/pkg/statedb"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func testStateDB(t *testing.T, memberships map[common.Hash]sDB.MEMBERSHIP_TYPE) *sDB.StateDB {
+	db, err := sDB.NewStateDB()
+	require.NoError(t, err)
+	for account, m := range memberships {
+		for i := 0; i < 2; i++ {
+			e := pp.Event{
+				TxHash: common.BytesToHash(mock.GenRandomHash(31)),
+				From:   account,
+				To:     common.BytesToHash(mock.GenRandomHash(20)),
+			}
+			se, err := e.Serialize()
+			require.NoError(t, err)
+			require.NoError(t, db.AddEvent(se))
+		}
+		if m != sDB.NONE {
+			require.NoError(t, db.CompareAndSetMembership(account.Hex(), sDB.NONE, m))
+		}
+	}
+	return db
+}
+
+func Test_ASP_Publish(t *testing.T) {
+	var (
+		included = common.BytesToHash(mock.GenRandomHash(20))
+		excluded = common.BytesToHash(mock.GenRandomHash(20))
+		unknown  = common.BytesToHash(mock.GenRandomHash(20))
+	)
+	db := testStateDB(t, map[common.Hash]sDB.MEMBERSHIP_TYPE{
+		included: sDB.INCLUSION,
+		excluded: sDB.EXCLUSION,
+		unknown:  sDB.NONE,
+	})
+	a := NewASP(db, nmt.NsPoseidonHasher, DefaultZeroValue())
+
+	_, err := a.Latest()
+	require.ErrorIs(t, err, ErrNoVersion)
+
+	v1, err := a.Publish()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), v1.Version)
+	require.Equal(t, 1, v1.Roots[sDB.INCLUSION].Size)
+	require.Equal(t, 1, v1.Roots[sDB.EXCLUSION].Size)
+	require.Equal(t, 0, v1.Roots[sDB.PARTIAL_INCLUSION].Size)
+	require.Nil(t, v1.Roots[sDB.PARTIAL_INCLUSION].Root)
+	require.Equal(t, nmt.HashNsPoseidon, v1.Roots[sDB.INCLUSION].HashID)
+
+	// nothing changed
+	same, err := a.Publish()
+	require.NoError(t, err)
+	require.Equal(t, v1.Version, same.Version)
+
+	// a membership change publishes a new version
+	require.NoError(t, db.CompareAndSetMembership(unknown.Hex(), sDB.NONE, sDB.INCLUSION))
+	v2, err := a.Publish()
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), v2.Version)
+	require.Equal(t, 2, v2.Roots[sDB.INCLUSION].Size)
+	require.False(t, v1.Roots[sDB.INCLUSION].Root.Equal(v2.Roots[sDB.INCLUSION].Root))
+	require.True(t, v1.Roots[sDB.EXCLUSION].Root.Equal(v2.Roots[sDB.EXCLUSION].Root))
+
+	old, err := a.Version(1)
+	require.NoError(t, err)
+	require.Equal(t, v1, old)
+	_, err = a.Version(3)
+	require.ErrorIs(t, err, ErrNotPublished)
+}
+
+func Test_ASP_Prove(t *testing.T) {
+	var (
+		included = common.BytesToHash(mock.GenRandomHash(20))
+		other    = common.BytesToHash(mock.GenRandomHash(20))
+		excluded = common.BytesToHash(mock.GenRandomHash(20))
+	)
+	db := testStateDB(t, map[common.Hash]sDB.MEMBERSHIP_TYPE{
+		included: sDB.INCLUSION,
+		other:    sDB.INCLUSION,
+		excluded: sDB.EXCLUSION,
+	})
+	a := NewASP(db, nil, DefaultZeroValue())
+
+	_, err := a.Prove(included.Hex(), sDB.INCLUSION)
+	require.ErrorIs(t, err, ErrNoVersion)
+
+	v, err := a.Publish()
+	require.NoError(t, err)
+
+	p, err := a.ProveMembership(included.Hex())
+	require.NoError(t, err)
+	require.Equal(t, v.Version, p.Version)
+	require.Equal(t, sDB.INCLUSION, p.Membership)
+	require.True(t, p.Root.Equal(v.Roots[sDB.INCLUSION].Root))
+	require.False(t, p.Proof.IsEmptyProof())
+	require.False(t, p.Proof.IsAbsenceProof())
+	require.NoError(t, p.Verify(a.ZeroValue()))
+
+	// the excluded account is not part of the inclusion set
+	p, err = a.Prove(excluded.Hex(), sDB.INCLUSION)
+	require.NoError(t, err)
+	require.True(t, p.Proof.IsAbsenceProof() || p.Proof.IsEmptyProof())
+	require.NoError(t, p.Verify(a.ZeroValue()))
+
+	// addresses are accepted as accounts
+	p, err = a.Prove(common.BytesToAddress(excluded.Bytes()).Hex(), sDB.EXCLUSION)
+	require.NoError(t, err)
+	require.NoError(t, p.Verify(a.ZeroValue()))
+
+	_, err = a.Prove(included.Hex(), sDB.PARTIAL_INCLUSION)
+	require.ErrorIs(t, err, ErrEmptySet)
+	_, err = a.Prove(included.Hex(), sDB.NONE)
+	require.ErrorIs(t, err, ErrUnknownSet)
+	_, err = a.Prove("0x1234", sDB.INCLUSION)
+	require.ErrorIs(t, err, ErrInvalidMember)
+}
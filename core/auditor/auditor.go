@@ -1,22 +1,45 @@
 package auditor
 
 import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
 	cr "github.com/0xBow-io/base-eas-asp/pkg/change_request"
+	"github.com/pkg/errors"
 
 	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
 	proofOfAudit "github.com/0xBow-io/base-eas-asp/pkg/proof-of-audit"
 	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
 	sDB "github.com/0xBow-io/base-eas-asp/pkg/statedb"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var ErrNoExpiryVerifier = errors.New("verifier can't downgrade expired attestations")
+
+const (
+	// DefaultRetryDelay before the first retry of a transition
+	DefaultRetryDelay = 10 * time.Second
+	// maxRetryDelay between the retries of a transition
+	maxRetryDelay = time.Hour
 )
 
 type Verifier interface {
 	SubmitChangeRequest(cr cr.ChangeRequest) error
 }
 
+// ExpiryVerifier downgrades the accounts which attestation expired without
+// proof of audit, the expiration is checked on chain by the auditor instead
+type ExpiryVerifier interface {
+	SubmitExpiry(ns []byte, membership sDB.MEMBERSHIP_TYPE, uid common.Hash) error
+}
+
 type ReportDB interface {
-	Get(publicID string) reportDB.Report
+	GetEvents(publicID string) (reportDB.Report, []reportDB.Event, error)
+	GetMembership(publicID string) (sDB.MEMBERSHIP_TYPE, reportDB.ReportRef)
 	Set(publicID string, ts int64, report reportDB.Report)
-	SubscribeToNotif(notifChan chan string)
+	SubscribeToTransitionNotif(notifChan chan reportDB.Transition)
 }
 
 type StateDB interface {
@@ -24,53 +47,394 @@ type StateDB interface {
 	GetMembership(ns string) sDB.MEMBERSHIP_TYPE
 }
 
+// Prover generates the proof of audit of an EAS event in a report
+type Prover interface {
+	Prove(r reportDB.Report, e eas.EAS) (*proofOfAudit.SP1Proof, error)
+}
+
 type Auditor struct {
 	v        Verifier
 	rDb      ReportDB
-	sDB      sDB.StateDB
-	rDbNotif chan string
+	sDB      StateDB
+	prover   Prover
+	rDbNotif chan reportDB.Transition
+	// checks the events against the chain before submitting, nil to skip
+	checker ChainChecker
+	// expiries of the attestations of the included accounts, nil to not downgrade
+	expiry    *ExpiryScheduler
+	downgrade sDB.MEMBERSHIP_TYPE
+	// derives the memberships, the membership of the last event if nil
+	policy   *eas.Policy
+	evidence eas.EvidenceSource
+
+	// transitions retried by HandleRetries & their errors
+	clock      Clock
+	retryDelay time.Duration
+	errs       chan<- error
+
+	mut sync.Mutex
+	// membership changes derived from unsigned reports, by public ID
+	unproven map[string]sDB.MEMBERSHIP_TYPE
+	// transitions to retry, by public ID
+	retries map[string]*retry
+}
+
+// retry of the transition of a public ID
+type retry struct {
+	attempts int
+	next     time.Time
 }
 
-func NewAuditor(v Verifier, rDb ReportDB) *Auditor {
-	rDbNotif := make(chan string)
-	rDb.SubscribeToNotif(rDbNotif)
+func NewAuditor(v Verifier, rDb ReportDB, sDB StateDB, prover Prover) *Auditor {
+	rDbNotif := make(chan reportDB.Transition)
+	rDb.SubscribeToTransitionNotif(rDbNotif)
+
+	return &Auditor{
+		v: v, rDb: rDb, sDB: sDB, prover: prover, rDbNotif: rDbNotif,
+		clock: SystemClock, retryDelay: DefaultRetryDelay,
+		retries: make(map[string]*retry),
+	}
+}
+
+// AuditorConfig of an Auditor
+type AuditorConfig struct {
+	// checks the EAS events of the signed reports before their
+	// membership changes are submitted, nil to skip
+	Checker ChainChecker
+	// schedules the downgrade of the accounts included by an attestation
+	// with an expiration time, nil to not downgrade. The expirations are
+	// read & checked by Checker, nothing is scheduled without it.
+	// The downgrades require a Verifier implementing ExpiryVerifier.
+	Expiry *ExpiryScheduler
+	// membership of the accounts which attestation expired, EXCLUSION if empty
+	Downgrade sDB.MEMBERSHIP_TYPE
+	// derives the membership of an account from its EAS events & its other
+	// attestations, the membership of the last event if nil. It must be the
+	// policy of the ReportDB & of the Verifier.
+	Policy *eas.Policy
+	// attestations of the EvidenceSchemas of Policy, nil if Policy has none
+	Evidence eas.EvidenceSource
+	// delay before the first retry of a transition which audit failed or
+	// which namespace isn't in StateDB yet, doubled at every retry up to
+	// an hour, DefaultRetryDelay if 0
+	RetryDelay time.Duration
+	// clock of the retries, SystemClock if nil
+	Clock Clock
+	// receives the errors of the transitions & of the downgrades,
+	// nil to discard them
+	Errors chan<- error
+}
+
+// NewAuditorWithChecker checks the EAS events of the signed reports
+// with checker before their membership changes are submitted
+func NewAuditorWithChecker(v Verifier, rDb ReportDB, sDB StateDB, prover Prover, checker ChainChecker) *Auditor {
+	return NewAuditorWithConfig(v, rDb, sDB, prover, AuditorConfig{Checker: checker})
+}
+
+func NewAuditorWithConfig(v Verifier, rDb ReportDB, stateDB StateDB, prover Prover, cfg AuditorConfig) *Auditor {
+	a := NewAuditor(v, rDb, stateDB, prover)
+	a.checker, a.expiry, a.downgrade = cfg.Checker, cfg.Expiry, cfg.Downgrade
+	a.policy, a.evidence = cfg.Policy, cfg.Evidence
+	a.errs = cfg.Errors
+	if a.downgrade == "" {
+		a.downgrade = sDB.EXCLUSION
+	}
+	if cfg.RetryDelay > 0 {
+		a.retryDelay = cfg.RetryDelay
+	}
+	if cfg.Clock != nil {
+		a.clock = cfg.Clock
+	}
+	return a
+}
 
-	return &Auditor{v: v, rDb: rDb, rDbNotif: rDbNotif}
+// Unproven returns the public IDs which membership change is only derived
+// from unsigned reports, such as backfilled ones. The proof of audit
+// requires the webhook signature, they are audited again with Audit
+// once a signed report is available.
+func (a *Auditor) Unproven() []string {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	ids := make([]string, 0, len(a.unproven))
+	for id := range a.unproven {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
 }
 
-// Async handle new reports
+// Async handle the membership transitions of new reports,
+// reports with the same result as before are not audited again.
+// The failed transitions are sent to Errors & retried by HandleRetries.
 func (a *Auditor) HandleIncomingReports() {
-	for i := 0; i < 10; i++ {
-		publicID := <-a.rDbNotif
-		r := a.rDb.Get(publicID)
-		easRes, _, err := r.Parse()
+	for t := range a.rDbNotif {
+		a.handle(t)
+	}
+}
+
+// HandleRetries audits again the transitions which audit failed or which
+// namespace wasn't in StateDB until ctx is done. The membership derived by
+// ReportDB is audited, with a delay doubling at every retry.
+func (a *Auditor) HandleRetries(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.clock.After(a.retryDelay):
+		}
+		a.retry()
+	}
+}
+
+// Retrying returns the public IDs which transition is retried
+func (a *Auditor) Retrying() []string {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	ids := make([]string, 0, len(a.retries))
+	for id := range a.retries {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// retry the transitions which are due
+func (a *Auditor) retry() {
+	now := a.clock.Now()
+	a.mut.Lock()
+	var due []string
+	for id, r := range a.retries {
+		if !now.Before(r.next) {
+			due = append(due, id)
+		}
+	}
+	a.mut.Unlock()
+	sort.Strings(due)
+
+	for _, publicID := range due {
+		// from the membership in StateDB to the derived one
+		m, ref := a.rDb.GetMembership(publicID)
+		a.handle(reportDB.Transition{PublicID: publicID, From: a.sDB.GetMembership(publicID), To: m, New: ref})
+	}
+}
+
+// handle audits the transition, it is retried if the audit fails
+// or if the namespace isn't in StateDB yet
+func (a *Auditor) handle(t reportDB.Transition) {
+	if a.sDB.NsExists(t.PublicID) {
+		err := a.AuditTransition(t)
+		if err == nil {
+			a.mut.Lock()
+			delete(a.retries, t.PublicID)
+			a.mut.Unlock()
+			return
+		}
+		a.report(errors.Wrap(err, "failed to audit transition "+t.String()))
+	}
+
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	r, ok := a.retries[t.PublicID]
+	if !ok {
+		r = &retry{}
+		a.retries[t.PublicID] = r
+	}
+	delay := maxRetryDelay
+	if r.attempts < 16 && a.retryDelay<<r.attempts < maxRetryDelay {
+		delay = a.retryDelay << r.attempts
+	}
+	r.attempts++
+	r.next = a.clock.Now().Add(delay)
+}
+
+// report an error of a transition or of a downgrade
+func (a *Auditor) report(err error) {
+	if a.errs != nil {
+		a.errs <- err
+	}
+}
+
+// AuditTransition submits a change request if the membership of the
+// transition disagrees with stateDB. The last EAS event of the public ID
+// in its latest report is proven, nothing is done if the latest report
+// no longer derives the membership of the transition.
+func (a *Auditor) AuditTransition(t reportDB.Transition) error {
+	r, events, err := a.rDb.GetEvents(t.PublicID)
+	if err != nil {
+		return err
+	}
+	m, ok, err := a.DeriveMembership(t.PublicID, events)
+	if err != nil || !ok {
+		return err
+	}
+
+	last := events[len(events)-1]
+	if m != t.To {
+		return nil
+	}
+	if !a.sDB.NsExists(t.PublicID) || a.sDB.GetMembership(t.PublicID) == t.To {
+		return nil
+	}
+	return a.submit(r, last.EAS, t.To)
+}
+
+// DeriveMembership returns the membership of publicID derived from its
+// EAS events by the policy of the auditor, ok is false without event
+func (a *Auditor) DeriveMembership(publicID string, events []reportDB.Event) (m sDB.MEMBERSHIP_TYPE, ok bool, err error) {
+	evs := make([]eas.EAS, len(events))
+	for i, e := range events {
+		evs[i] = e.EAS
+	}
+	return eas.DeriveMembership(a.policy, common.HexToHash(publicID), evs, a.evidence, time.Now().Unix())
+}
+
+// Audit submits a change request for every EAS event of publicID
+// in its latest report that disagrees with its membership in stateDB.
+// With a policy the membership derived from all the events is submitted
+// with the proof of the last one.
+// The events are indexed by ReportDB, the report is not parsed again.
+func (a *Auditor) Audit(publicID string) error {
+	r, events, err := a.rDb.GetEvents(publicID)
+	if err != nil {
+		return err
+	}
+
+	// check that we have any associated events for those EAS
+	// by checking the existence of a namespace (EAS account)
+	if !a.sDB.NsExists(publicID) {
+		return nil
+	}
+	if a.policy != nil {
+		m, ok, err := a.DeriveMembership(publicID, events)
+		if err != nil || !ok || m == a.sDB.GetMembership(publicID) {
+			return err
+		}
+		return a.submit(r, events[len(events)-1].EAS, m)
+	}
+	for _, e := range events {
+		// check if their membership in stateDB is valid
+		expectedMembership := eas.EasTypeToMembership(e.Type)
+		if expectedMembership == a.sDB.GetMembership(publicID) {
+			continue
+		}
+
+		if err := a.submit(r, e.EAS, expectedMembership); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// submit a change request (cr) of the membership of the EAS account,
+// cr will provide proof based on the report that a change in membership for the namesapce is needed.
+// Unsigned reports can't be proven, the change is recorded as unproven instead.
+func (a *Auditor) submit(r reportDB.Report, e eas.EAS, membership sDB.MEMBERSHIP_TYPE) error {
+	ns := e.Account.Hex()
+	if !r.Header.Signed() {
+		a.mut.Lock()
+		if a.unproven == nil {
+			a.unproven = make(map[string]sDB.MEMBERSHIP_TYPE)
+		}
+		a.unproven[ns] = membership
+		a.mut.Unlock()
+		return nil
+	}
+	if a.checker != nil {
+		checked, err := a.checker.Check(r, e)
 		if err != nil {
-			panic(err)
+			return errors.Wrap(err, "chain check failed for "+ns)
+		}
+		e = checked
+	}
+
+	proof, err := a.prover.Prove(r, e)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate proof of audit for "+ns)
+	}
+	if err := a.v.SubmitChangeRequest(cr.ChangeRequest{
+		Ns:         e.Account.Bytes(),
+		Membership: membership,
+		Proof:      *proof,
+	}); err != nil {
+		return errors.Wrap(err, "change request rejected for "+ns)
+	}
+
+	a.mut.Lock()
+	delete(a.unproven, ns)
+	a.mut.Unlock()
+	if a.expiry != nil {
+		if membership == sDB.INCLUSION {
+			a.expiry.Schedule(Expiry{PublicID: ns, Event: e})
+		} else {
+			a.expiry.Cancel(ns)
+		}
+	}
+	return nil
+}
+
+// HandleExpirations downgrades the membership of the accounts which
+// attestation expired until ctx is done, nothing is done without an
+// ExpiryScheduler
+func (a *Auditor) HandleExpirations(ctx context.Context) {
+	if a.expiry == nil {
+		return
+	}
+	a.expiry.Run(ctx, func(x Expiry) {
+		if err := a.expire(x); err != nil {
+			a.report(errors.Wrap(err, "failed to downgrade "+x.PublicID))
 		}
+	})
+}
 
-		for _, e := range easRes {
-			// check that we have any associated events for those EAS
-			// by checking the existence of a namespace (EAS account)
-			if a.sDB.NsExists(e.Account.Hex()) {
-				// check if their membership in stateDB is valid
-				expectedMembership := eas.EasTypeToMembership(e.Type)
-				if expectedMembership != a.sDB.GetMembership(e.Account.Hex()) {
-					// create a change request (cr)
-					// cr will provide proof based on the report that a change in membership for the namesapce is needed
-					proof, err := proofOfAudit.VerifyCommitment()
-					if err != nil {
-						panic(err)
-					}
-					a.v.SubmitChangeRequest(cr.ChangeRequest{
-						Ns:         e.Account.Bytes(),
-						Membership: expectedMembership,
-						Proof:      *proof,
-					})
-				}
-			}
-
-			// generate change request
+// expire submits the downgrade of an account which attestation expired
+// once the expiration is checked on chain. The expiry of the latest
+// attestation is scheduled instead when the account was renewed by one
+// that didn't expire, nothing is done when it is no longer included.
+func (a *Auditor) expire(x Expiry) error {
+	if !a.sDB.NsExists(x.PublicID) || a.sDB.GetMembership(x.PublicID) != sDB.INCLUSION {
+		return nil
+	}
+	r, events, err := a.rDb.GetEvents(x.PublicID)
+	if err != nil {
+		return err
+	}
+	if a.checker == nil {
+		return errors.Wrap(ErrNoExpiryVerifier, "no chain checker")
+	}
+	v, ok := a.v.(ExpiryVerifier)
+	if !ok {
+		return ErrNoExpiryVerifier
+	}
 
+	// the latest event derives the membership
+	e := x.Event
+	if len(events) > 0 {
+		e = events[len(events)-1].EAS
+	}
+	renewed := e.UUID != x.Event.UUID
+	if renewed && e.Type != eas.EAS_ATTEST {
+		return nil
+	}
+	if err := a.checker.CheckExpired(e); err != nil {
+		if renewed && errors.Is(err, ErrNotExpired) {
+			return a.renew(x.PublicID, r, e)
 		}
+		return errors.Wrap(err, "expiration check failed for "+x.PublicID)
+	}
+	if err := v.SubmitExpiry(e.Account.Bytes(), a.downgrade, e.UUID); err != nil {
+		return errors.Wrap(err, "downgrade rejected for "+x.PublicID)
+	}
+	return nil
+}
+
+// renew schedules the expiry of the attestation e which renewed the
+// inclusion of an account, its membership is unchanged so no change
+// request schedules it
+func (a *Auditor) renew(publicID string, r reportDB.Report, e eas.EAS) error {
+	checked, err := a.checker.Check(r, e)
+	if err != nil {
+		return errors.Wrap(err, "chain check failed for "+publicID)
 	}
+	a.expiry.Schedule(Expiry{PublicID: publicID, Event: checked})
+	return nil
 }
@@ -1 +1,381 @@
 package auditor
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0xBow-io/base-eas-asp/core/verifier"
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	cr "github.com/0xBow-io/base-eas-asp/pkg/change_request"
+	mock "github.com/0xBow-io/base-eas-asp/pkg/mock"
+	pp "github.com/0xBow-io/base-eas-asp/pkg/privacy_pool"
+	poa "github.com/0xBow-io/base-eas-asp/pkg/proof-of-audit"
+	quiknode "github.com/0xBow-io/base-eas-asp/pkg/quiknode"
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+	sDB "github.com/0xBow-io/base-eas-asp/pkg/statedb"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+type testProver struct {
+	calls int
+	err   error
+}
+
+func (p *testProver) Prove(r reportDB.Report, e eas.EAS) (*poa.SP1Proof, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &poa.SP1Proof{
+		Proof:  r.Header.NotificationID + e.UUID.Hex(),
+		Stdout: poa.StdIO{Buffer: poa.StdBuffer{Data: poa.EventPublicValues(e).Encode()}},
+	}, nil
+}
+
+// randHash returns a random non-zero hash
+func randHash() common.Hash {
+	var h common.Hash
+	for h == (common.Hash{}) {
+		h = common.BytesToHash(mock.GenRandomHash(32))
+	}
+	return h
+}
+
+// genReport returns a report with a single EAS event for account
+func genReport(t *testing.T, account common.Hash, topic string) reportDB.Report {
+	log := map[string]interface{}{
+		"address":         eas.BASE_EAS_ADDR,
+		"topics":          []string{topic, account.Hex(), eas.COINBASE_EAS_HASH, eas.COINBASE_EAS_SCHEMA_ID},
+		"data":            randHash().Hex(),
+		"blockNumber":     "0xb2bbad",
+		"blockHash":       randHash().Hex(),
+		"transactionHash": randHash().Hex(),
+		"logIndex":        "0x1",
+		"removed":         false,
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"matchedReceipts":     []interface{}{map[string]interface{}{"logs": []interface{}{log}}},
+		"matchedTransactions": []interface{}{map[string]interface{}{"chainId": eas.BASE_CHAIN_ID}},
+	})
+	require.NoError(t, err)
+
+	return reportDB.Report{
+		Header: reportDB.ReportHeader{
+			NotificationID: common.BytesToHash(mock.GenRandomHash(16)).Hex(),
+			ContentHash:    "hash",
+			Nonce:          "nonce",
+			Signature:      "signature",
+			Timestamp:      time.Now().Format(reportDB.Header_Time_Layout),
+		},
+		Body: string(body),
+	}
+}
+
+func setup(t *testing.T, accounts ...common.Hash) (*Auditor, *reportDB.ReportDB, *sDB.StateDB, *verifier.Verifier, *testProver) {
+	sdb, err := sDB.NewStateDB()
+	require.NoError(t, err)
+	for _, account := range accounts {
+		e := pp.Event{TxHash: common.BytesToHash(mock.GenRandomHash(31)), From: account}
+		se, err := e.Serialize()
+		require.NoError(t, err)
+		require.NoError(t, sdb.AddEvent(se))
+	}
+
+	var (
+		rdb    = reportDB.NewReportDB()
+		prover = &testProver{}
+	)
+	// the test proofs aren't SP1 proofs, only their public values are checked
+	v, err := verifier.NewVerifier(sdb, verifier.ProofVerifierFunc(poa.CheckProof))
+	require.NoError(t, err)
+	return NewAuditor(v, rdb, sdb, prover), rdb, sdb, v, prover
+}
+
+func Test_Auditor_Audit(t *testing.T) {
+	account := common.BytesToHash(mock.GenRandomHash(20))
+	a, rdb, sdb, v, prover := setup(t, account)
+
+	// attestation includes the account
+	rdb.Set(account.Hex(), time.Now().Unix(), genReport(t, account, eas.COINBASE_EAS_ATTEST_TOPIC))
+	require.NoError(t, a.Audit(account.Hex()))
+	require.Equal(t, sDB.INCLUSION, sdb.GetMembership(account.Hex()))
+	require.Equal(t, 1, prover.calls)
+
+	// nothing to change
+	require.NoError(t, a.Audit(account.Hex()))
+	require.Equal(t, 1, prover.calls)
+
+	// revocation excludes the account
+	rdb.Set(account.Hex(), time.Now().Unix()+1, genReport(t, account, eas.COINBASE_EAS_REVOKE_TOPIC))
+	require.NoError(t, a.Audit(account.Hex()))
+	require.Equal(t, sDB.EXCLUSION, sdb.GetMembership(account.Hex()))
+	require.Equal(t, 2, prover.calls)
+
+	outcomes := v.Outcomes()
+	require.Len(t, outcomes, 2)
+	require.Equal(t, sDB.NONE, outcomes[0].From)
+	require.Equal(t, sDB.INCLUSION, outcomes[1].From)
+	require.Equal(t, sDB.EXCLUSION, outcomes[1].To)
+}
+
+func Test_Auditor_Audit_UnknownNamespace(t *testing.T) {
+	account := common.BytesToHash(mock.GenRandomHash(20))
+	a, rdb, sdb, v, prover := setup(t)
+
+	rdb.Set(account.Hex(), time.Now().Unix(), genReport(t, account, eas.COINBASE_EAS_ATTEST_TOPIC))
+	require.NoError(t, a.Audit(account.Hex()))
+	require.Equal(t, sDB.NONE, sdb.GetMembership(account.Hex()))
+	require.Zero(t, prover.calls)
+	require.Empty(t, v.Outcomes())
+}
+
+func Test_Auditor_Audit_Errors(t *testing.T) {
+	account := common.BytesToHash(mock.GenRandomHash(20))
+	a, rdb, sdb, _, prover := setup(t, account)
+
+	// no report
+	require.Error(t, a.Audit(account.Hex()))
+
+	// failing prover
+	prover.err = errors.New("proving failed")
+	rdb.Set(account.Hex(), time.Now().Unix(), genReport(t, account, eas.COINBASE_EAS_ATTEST_TOPIC))
+	require.ErrorContains(t, a.Audit(account.Hex()), "proving failed")
+	require.Equal(t, sDB.NONE, sdb.GetMembership(account.Hex()))
+}
+
+func Test_Auditor_HandleIncomingReports(t *testing.T) {
+	account := common.BytesToHash(mock.GenRandomHash(20))
+	a, rdb, sdb, _, _ := setup(t, account)
+	go a.HandleIncomingReports()
+
+	rdb.Set(account.Hex(), time.Now().Unix(), genReport(t, account, eas.COINBASE_EAS_ATTEST_TOPIC))
+	require.Eventually(t, func() bool {
+		return sdb.GetMembership(account.Hex()) == sDB.INCLUSION
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+type recordingVerifier struct {
+	mut sync.Mutex
+	crs []cr.ChangeRequest
+}
+
+func (v *recordingVerifier) SubmitChangeRequest(c cr.ChangeRequest) error {
+	v.mut.Lock()
+	defer v.mut.Unlock()
+	v.crs = append(v.crs, c)
+	return nil
+}
+
+func (v *recordingVerifier) submitted() []cr.ChangeRequest {
+	v.mut.Lock()
+	defer v.mut.Unlock()
+	return append([]cr.ChangeRequest(nil), v.crs...)
+}
+
+func Test_Auditor_Transitions(t *testing.T) {
+	account := common.BytesToHash(mock.GenRandomHash(20))
+	_, rdb, sdb, _, prover := setup(t, account)
+
+	// change requests are never applied, every report would be proven again
+	v := &recordingVerifier{}
+	a := NewAuditor(v, rdb, sdb, prover)
+	go a.HandleIncomingReports()
+
+	now := time.Now().Unix()
+	for i := int64(0); i < 3; i++ {
+		rdb.Set(account.Hex(), now+i, genReport(t, account, eas.COINBASE_EAS_ATTEST_TOPIC))
+	}
+	require.Eventually(t, func() bool { return len(v.submitted()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	require.Len(t, v.submitted(), 1)
+
+	rdb.Set(account.Hex(), now+3, genReport(t, account, eas.COINBASE_EAS_REVOKE_TOPIC))
+	require.Eventually(t, func() bool { return len(v.submitted()) == 2 }, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	crs := v.submitted()
+	require.Len(t, crs, 2)
+	require.Equal(t, sDB.INCLUSION, crs[0].Membership)
+	require.Equal(t, sDB.EXCLUSION, crs[1].Membership)
+
+	// the latest report no longer derives the membership of the transition
+	require.NoError(t, a.AuditTransition(reportDB.Transition{PublicID: account.Hex(), From: sDB.NONE, To: sDB.INCLUSION}))
+	require.Len(t, v.submitted(), 2)
+
+	// the membership is already set
+	require.NoError(t, sdb.CompareAndSetMembership(account.Hex(), sDB.NONE, sDB.EXCLUSION))
+	require.NoError(t, a.AuditTransition(reportDB.Transition{PublicID: account.Hex(), From: sDB.INCLUSION, To: sDB.EXCLUSION}))
+	require.Len(t, v.submitted(), 2)
+}
+
+func Test_Auditor_Retries(t *testing.T) {
+	var (
+		account = common.BytesToHash(mock.GenRandomHash(20))
+		late    = common.BytesToHash(mock.GenRandomHash(20))
+		clock   = NewMockClock(time.Unix(1700000000, 0))
+		errs    = make(chan error, 1)
+	)
+	_, rdb, sdb, v, prover := setup(t, account)
+	a := NewAuditorWithConfig(v, rdb, sdb, prover, AuditorConfig{Clock: clock, Errors: errs})
+	go a.HandleIncomingReports()
+	retrying := func() bool { return len(a.Retrying()) == 1 }
+
+	// a failed transition is reported & retried after the delay
+	prover.err = errors.New("proving failed")
+	rdb.Set(account.Hex(), clock.Now().Unix(), genReport(t, account, eas.COINBASE_EAS_ATTEST_TOPIC))
+	require.ErrorContains(t, <-errs, "proving failed")
+	require.Eventually(t, retrying, time.Second, time.Millisecond)
+	require.Equal(t, []string{account.Hex()}, a.Retrying())
+	a.retry()
+	require.Equal(t, 1, prover.calls)
+
+	clock.Advance(DefaultRetryDelay)
+	a.retry()
+	require.ErrorContains(t, <-errs, "failed to audit transition "+account.Hex()+": none→inclusion")
+	require.Equal(t, 2, prover.calls)
+
+	// the delay doubles
+	clock.Advance(DefaultRetryDelay)
+	a.retry()
+	require.Equal(t, 2, prover.calls)
+	prover.err = nil
+	clock.Advance(DefaultRetryDelay)
+	a.retry()
+	require.Equal(t, 3, prover.calls)
+	require.Equal(t, sDB.INCLUSION, sdb.GetMembership(account.Hex()))
+	require.Empty(t, a.Retrying())
+
+	// the transition of a namespace not in StateDB yet is retried
+	rdb.Set(late.Hex(), clock.Now().Unix(), genReport(t, late, eas.COINBASE_EAS_ATTEST_TOPIC))
+	require.Eventually(t, retrying, time.Second, time.Millisecond)
+	require.Equal(t, []string{late.Hex()}, a.Retrying())
+	e := pp.Event{TxHash: common.BytesToHash(mock.GenRandomHash(31)), From: late}
+	se, err := e.Serialize()
+	require.NoError(t, err)
+	require.NoError(t, sdb.AddEvent(se))
+	clock.Advance(DefaultRetryDelay)
+	a.retry()
+	require.Equal(t, sDB.INCLUSION, sdb.GetMembership(late.Hex()))
+	require.Empty(t, a.Retrying())
+}
+
+func Test_Auditor_Unsigned(t *testing.T) {
+	account := common.BytesToHash(mock.GenRandomHash(20))
+	a, rdb, sdb, v, prover := setup(t, account)
+
+	// backfilled reports are not proven
+	r := genReport(t, account, eas.COINBASE_EAS_ATTEST_TOPIC)
+	r.Header.Nonce, r.Header.Signature, r.Header.Source = "", "", reportDB.SourceBackfill
+	rdb.Set(account.Hex(), time.Now().Unix(), r)
+	require.NoError(t, a.Audit(account.Hex()))
+	require.Zero(t, prover.calls)
+	require.Empty(t, v.Outcomes())
+	require.Equal(t, sDB.NONE, sdb.GetMembership(account.Hex()))
+	require.Equal(t, []string{account.Hex()}, a.Unproven())
+
+	// until a signed report derives the same membership
+	rdb.Set(account.Hex(), time.Now().Unix()+1, genReport(t, account, eas.COINBASE_EAS_ATTEST_TOPIC))
+	require.NoError(t, a.Audit(account.Hex()))
+	require.Equal(t, 1, prover.calls)
+	require.Equal(t, sDB.INCLUSION, sdb.GetMembership(account.Hex()))
+	require.Empty(t, a.Unproven())
+}
+
+func Test_SP1Prover_Unprovable(t *testing.T) {
+	account := randHash()
+	r := genReport(t, account, eas.COINBASE_EAS_ATTEST_TOPIC)
+	require.NoError(t, provable(r))
+	r.Header.Format = quiknode.FormatQuickAlerts
+	require.NoError(t, provable(r))
+
+	events, _, err := r.Parse()
+	require.NoError(t, err)
+	prover := NewSP1Prover("secret", "/webhook")
+	for _, tc := range []func(r *reportDB.Report){
+		func(r *reportDB.Report) { r.Header.ContentEncoding = "gzip" },
+		func(r *reportDB.Report) { r.Header.Format = quiknode.FormatStreams },
+		func(r *reportDB.Report) { r.Header.Format = quiknode.FormatLogs },
+		func(r *reportDB.Report) { r.Body += "\x00" },
+	} {
+		unprovable := r
+		tc(&unprovable)
+		_, err := prover.Prove(unprovable, events[0])
+		require.ErrorIs(t, err, ErrUnprovableReport)
+	}
+}
+
+// countrySource serves the country verification of every account
+type countrySource struct {
+	mut  sync.Mutex
+	code string
+}
+
+func (s *countrySource) set(code string) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.code = code
+}
+
+func (s *countrySource) Evidence(account common.Hash, schemas []common.Hash) ([]eas.Evidence, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return []eas.Evidence{{
+		UID:    account,
+		Schema: common.HexToHash(eas.COINBASE_EAS_COUNTRY_SCHEMA_ID),
+		Fields: map[string]string{"country": s.code},
+	}}, nil
+}
+
+func Test_Auditor_Policy(t *testing.T) {
+	account := common.BytesToHash(mock.GenRandomHash(20))
+	_, _, sdb, _, prover := setup(t, account)
+	policy := &eas.Policy{
+		Rules: []eas.Rule{
+			{Name: "revoked", Membership: sDB.EXCLUSION, When: eas.Condition{Attestation: &eas.Match{State: eas.StateRevoked}}},
+			{Name: "verified", Membership: sDB.INCLUSION, When: eas.Condition{All: []eas.Condition{
+				{Attestation: &eas.Match{Schema: "account", State: eas.StateValid}},
+				{Attestation: &eas.Match{Schema: "country", Field: "country", NotIn: []string{"KP"}}},
+			}}},
+		},
+		Default: sDB.PARTIAL_INCLUSION,
+	}
+	src := &countrySource{code: "KP"}
+
+	// the ReportDB, the verifier & the auditor share the policy
+	rdb := reportDB.NewReportDBWithConfig(reportDB.ReportDBConfig{Policy: policy, Evidence: src})
+	v, err := verifier.NewVerifierWithConfig(sdb, verifier.ProofVerifierFunc(poa.CheckProof), verifier.VerifierConfig{Policy: policy, Evidence: src})
+	require.NoError(t, err)
+	a := NewAuditorWithConfig(v, rdb, sdb, prover, AuditorConfig{Policy: policy, Evidence: src})
+	go a.HandleIncomingReports()
+
+	// the attestation of an account of a denied country partially includes it
+	rdb.Set(account.Hex(), time.Now().Unix(), genReport(t, account, eas.COINBASE_EAS_ATTEST_TOPIC))
+	require.Eventually(t, func() bool {
+		return sdb.GetMembership(account.Hex()) == sDB.PARTIAL_INCLUSION
+	}, 5*time.Second, 10*time.Millisecond)
+	m, ok, err := a.DeriveMembership(account.Hex(), nil)
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Equal(t, sDB.NONE, m)
+
+	// a transition no longer derived isn't audited
+	calls := prover.calls
+	require.NoError(t, a.AuditTransition(reportDB.Transition{PublicID: account.Hex(), From: sDB.NONE, To: sDB.INCLUSION}))
+	require.Equal(t, calls, prover.calls)
+
+	// the country changed, the account is included once audited again
+	src.set("US")
+	require.NoError(t, a.Audit(account.Hex()))
+	require.Equal(t, sDB.INCLUSION, sdb.GetMembership(account.Hex()))
+	require.Equal(t, calls+1, prover.calls)
+	require.NoError(t, a.Audit(account.Hex()))
+	require.Equal(t, calls+1, prover.calls)
+
+	outcomes := v.Outcomes()
+	require.Len(t, outcomes, 2)
+	require.Equal(t, sDB.PARTIAL_INCLUSION, outcomes[1].From)
+	require.Equal(t, sDB.INCLUSION, outcomes[1].To)
+}
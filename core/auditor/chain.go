@@ -0,0 +1,178 @@
+package auditor
+
+import (
+	"context"
+	"time"
+
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	quiknode "github.com/0xBow-io/base-eas-asp/pkg/quiknode"
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrChainMismatch = errors.New("EAS event disagrees with chain state")
+	ErrNotIncluded   = errors.New("EAS event not included on chain")
+	ErrNotExpired    = errors.New("attestation not expired on chain")
+)
+
+// ChainReader is the subset of ethclient.Client read by ChainVerifier
+type ChainReader interface {
+	ethereum.ContractCaller
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+// ChainChecker checks an EAS event of a report before its membership change
+// is submitted & returns the event with the expiration time of its attestation.
+// CheckExpired checks that the attestation of an event expired before the
+// account it included is downgraded.
+type ChainChecker interface {
+	Check(r reportDB.Report, e eas.EAS) (eas.EAS, error)
+	CheckExpired(e eas.EAS) error
+}
+
+/*
+ChainVerifier cross-checks the EAS events of the webhook reports against
+the chain, a compromised webhook secret would otherwise allow fake events.
+
+The receipt of the transaction of the event must be in the block of the
+report & hold the event log, the attestation of the EAS contract must match
+the recipient & schema of the event and be revoked for a revocation,
+neither revoked nor expired for an attestation.
+*/
+type ChainVerifier struct {
+	reader  ChainReader
+	timeout time.Duration
+	// now returns the time the expiration is checked at
+	now func() time.Time
+}
+
+func NewChainVerifier(reader ChainReader) *ChainVerifier {
+	return &ChainVerifier{reader: reader, timeout: 10 * time.Second, now: time.Now}
+}
+
+// Check the EAS event e of the report r against the chain,
+// the expiration time of its attestation is set on the returned event
+func (c *ChainVerifier) Check(r reportDB.Report, e eas.EAS) (eas.EAS, error) {
+	logs, err := r.EventLogs()
+	if err != nil {
+		return e, errors.Wrap(err, "failed to decode the event logs")
+	}
+	var log *quiknode.EventLog
+	for i := range logs {
+		if l := logs[i].EAS; l.UUID == e.UUID && l.Account == e.Account && l.Type == e.Type {
+			log = &logs[i]
+			break
+		}
+	}
+	if log == nil {
+		return e, errors.Wrap(ErrNotIncluded, "event not in report")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	if err := c.checkReceipt(ctx, *log); err != nil {
+		return e, err
+	}
+	a, err := c.checkAttestation(ctx, e)
+	if err != nil {
+		return e, err
+	}
+	e.ExpirationTime = a.ExpirationTime
+	return e, nil
+}
+
+// checkReceipt checks that the receipt of the log is in its block & holds it
+func (c *ChainVerifier) checkReceipt(ctx context.Context, l quiknode.EventLog) error {
+	if l.TxHash == (common.Hash{}) || l.BlockHash == (common.Hash{}) {
+		return errors.Wrap(ErrNotIncluded, "event log has no transaction or block hash")
+	}
+	receipt, err := c.reader.TransactionReceipt(ctx, l.TxHash)
+	if err != nil {
+		return errors.Wrap(err, "failed to read the receipt of "+l.TxHash.Hex())
+	}
+	if receipt.BlockHash != l.BlockHash {
+		return errors.Wrapf(ErrNotIncluded, "transaction %s is in block %s", l.TxHash.Hex(), receipt.BlockHash.Hex())
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return errors.Wrapf(ErrNotIncluded, "transaction %s failed", l.TxHash.Hex())
+	}
+
+	easAddr := common.HexToAddress(eas.BASE_EAS_ADDR)
+	for _, rl := range receipt.Logs {
+		if rl.Index != l.LogIndex || rl.Address != easAddr || rl.Removed {
+			continue
+		}
+		if len(rl.Topics) == 4 && rl.Topics[1] == l.Account && len(rl.Data) >= common.HashLength &&
+			common.BytesToHash(rl.Data[:common.HashLength]) == l.UUID {
+			return nil
+		}
+	}
+	return errors.Wrapf(ErrNotIncluded, "log %d not in the receipt of %s", l.LogIndex, l.TxHash.Hex())
+}
+
+// CheckExpired checks that the attestation of the event e
+// expired & wasn't revoked in the EAS contract
+func (c *ChainVerifier) CheckExpired(e eas.EAS) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	a, err := c.readAttestation(ctx, e)
+	if err != nil {
+		return err
+	}
+	uid := e.UUID.Hex()
+	if a.Revoked() {
+		return errors.Wrapf(ErrChainMismatch, "attestation %s is revoked", uid)
+	}
+	if !a.Expired(c.now().Unix()) {
+		return errors.Wrapf(ErrNotExpired, "attestation %s expires at %d", uid, a.ExpirationTime)
+	}
+	return nil
+}
+
+// checkAttestation checks the attestation of the event in the EAS contract
+func (c *ChainVerifier) checkAttestation(ctx context.Context, e eas.EAS) (eas.Attestation, error) {
+	a, err := c.readAttestation(ctx, e)
+	if err != nil {
+		return a, err
+	}
+
+	uid := e.UUID.Hex()
+	switch e.Type {
+	case eas.EAS_ATTEST:
+		if a.Revoked() {
+			return a, errors.Wrapf(ErrChainMismatch, "attestation %s is revoked", uid)
+		}
+		if a.Expired(c.now().Unix()) {
+			return a, errors.Wrapf(ErrChainMismatch, "attestation %s is expired", uid)
+		}
+	case eas.EAS_REVOKE:
+		if !a.Revoked() {
+			return a, errors.Wrapf(ErrChainMismatch, "attestation %s is not revoked", uid)
+		}
+	}
+	return a, nil
+}
+
+// readAttestation reads the attestation of the event from the EAS contract
+// & checks its recipient, schema & attester
+func (c *ChainVerifier) readAttestation(ctx context.Context, e eas.EAS) (eas.Attestation, error) {
+	a, err := eas.GetAttestation(ctx, c.reader, e.UUID)
+	if err != nil {
+		return a, err
+	}
+
+	uid := e.UUID.Hex()
+	switch {
+	case a.Recipient != common.BytesToAddress(e.Account.Bytes()):
+		return a, errors.Wrapf(ErrChainMismatch, "recipient of %s is %s", uid, a.Recipient.Hex())
+	case a.Schema != common.HexToHash(eas.COINBASE_EAS_SCHEMA_ID):
+		return a, errors.Wrapf(ErrChainMismatch, "schema of %s is %s", uid, a.Schema.Hex())
+	case a.Attester != common.HexToAddress(eas.COINBASE_EAS_HASH):
+		return a, errors.Wrapf(ErrChainMismatch, "attester of %s is %s", uid, a.Attester.Hex())
+	}
+	return a, nil
+}
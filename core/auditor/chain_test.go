@@ -0,0 +1,250 @@
+package auditor
+
+import (
+	"testing"
+	"time"
+
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	mock "github.com/0xBow-io/base-eas-asp/pkg/mock"
+	quiknode "github.com/0xBow-io/base-eas-asp/pkg/quiknode"
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+	sDB "github.com/0xBow-io/base-eas-asp/pkg/statedb"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+// included returns a report of the event of the account & a reader including it
+func included(t *testing.T, account common.Hash, topic string) (reportDB.Report, quiknode.EventLog, *MockChainReader) {
+	r := genReport(t, account, topic)
+	logs, err := r.EventLogs()
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	reader := NewMockChainReader()
+	reader.Include(logs[0])
+	return r, logs[0], reader
+}
+
+func Test_ChainVerifier(t *testing.T) {
+	account := common.BytesToHash(mock.GenRandomHash(20))
+	now := time.Unix(1700000000, 0)
+
+	for _, tc := range []struct {
+		name  string
+		topic string
+		// alter the chain state of the event
+		alter func(reader *MockChainReader, l quiknode.EventLog)
+		err   error
+	}{
+		{name: "attestation", topic: eas.COINBASE_EAS_ATTEST_TOPIC},
+		{name: "revocation", topic: eas.COINBASE_EAS_REVOKE_TOPIC},
+		{
+			name:  "attestation not expired",
+			topic: eas.COINBASE_EAS_ATTEST_TOPIC,
+			alter: func(reader *MockChainReader, l quiknode.EventLog) {
+				reader.attestations[l.UUID] = withAttestation(reader.attestations[l.UUID], func(a *eas.Attestation) {
+					a.ExpirationTime = uint64(now.Unix()) + 1
+				})
+			},
+		},
+		{
+			name:  "missing receipt",
+			topic: eas.COINBASE_EAS_ATTEST_TOPIC,
+			alter: func(reader *MockChainReader, l quiknode.EventLog) { delete(reader.receipts, l.TxHash) },
+			err:   ethereum.NotFound,
+		},
+		{
+			name:  "receipt in another block",
+			topic: eas.COINBASE_EAS_ATTEST_TOPIC,
+			alter: func(reader *MockChainReader, l quiknode.EventLog) {
+				reader.receipts[l.TxHash].BlockHash = common.HexToHash("0x01")
+			},
+			err: ErrNotIncluded,
+		},
+		{
+			name:  "failed transaction",
+			topic: eas.COINBASE_EAS_ATTEST_TOPIC,
+			alter: func(reader *MockChainReader, l quiknode.EventLog) {
+				reader.receipts[l.TxHash].Status = types.ReceiptStatusFailed
+			},
+			err: ErrNotIncluded,
+		},
+		{
+			name:  "log at another index",
+			topic: eas.COINBASE_EAS_ATTEST_TOPIC,
+			alter: func(reader *MockChainReader, l quiknode.EventLog) {
+				reader.receipts[l.TxHash].Logs[0].Index++
+			},
+			err: ErrNotIncluded,
+		},
+		{
+			name:  "log of another uid",
+			topic: eas.COINBASE_EAS_ATTEST_TOPIC,
+			alter: func(reader *MockChainReader, l quiknode.EventLog) {
+				reader.receipts[l.TxHash].Logs[0].Data = common.HexToHash("0x01").Bytes()
+			},
+			err: ErrNotIncluded,
+		},
+		{
+			name:  "unknown attestation",
+			topic: eas.COINBASE_EAS_ATTEST_TOPIC,
+			alter: func(reader *MockChainReader, l quiknode.EventLog) { delete(reader.attestations, l.UUID) },
+			err:   eas.ErrAttestationNotFound,
+		},
+		{
+			name:  "other recipient",
+			topic: eas.COINBASE_EAS_ATTEST_TOPIC,
+			alter: func(reader *MockChainReader, l quiknode.EventLog) {
+				reader.attestations[l.UUID] = withAttestation(reader.attestations[l.UUID], func(a *eas.Attestation) {
+					a.Recipient = common.HexToAddress("0x01")
+				})
+			},
+			err: ErrChainMismatch,
+		},
+		{
+			name:  "other schema",
+			topic: eas.COINBASE_EAS_ATTEST_TOPIC,
+			alter: func(reader *MockChainReader, l quiknode.EventLog) {
+				reader.attestations[l.UUID] = withAttestation(reader.attestations[l.UUID], func(a *eas.Attestation) {
+					a.Schema = common.HexToHash("0x01")
+				})
+			},
+			err: ErrChainMismatch,
+		},
+		{
+			name:  "other attester",
+			topic: eas.COINBASE_EAS_ATTEST_TOPIC,
+			alter: func(reader *MockChainReader, l quiknode.EventLog) {
+				reader.attestations[l.UUID] = withAttestation(reader.attestations[l.UUID], func(a *eas.Attestation) {
+					a.Attester = common.HexToAddress("0x01")
+				})
+			},
+			err: ErrChainMismatch,
+		},
+		{
+			name:  "revoked attestation",
+			topic: eas.COINBASE_EAS_ATTEST_TOPIC,
+			alter: func(reader *MockChainReader, l quiknode.EventLog) {
+				reader.attestations[l.UUID] = withAttestation(reader.attestations[l.UUID], func(a *eas.Attestation) {
+					a.RevocationTime = 2
+				})
+			},
+			err: ErrChainMismatch,
+		},
+		{
+			name:  "expired attestation",
+			topic: eas.COINBASE_EAS_ATTEST_TOPIC,
+			alter: func(reader *MockChainReader, l quiknode.EventLog) {
+				reader.attestations[l.UUID] = withAttestation(reader.attestations[l.UUID], func(a *eas.Attestation) {
+					a.ExpirationTime = uint64(now.Unix())
+				})
+			},
+			err: ErrChainMismatch,
+		},
+		{
+			name:  "revocation not revoked",
+			topic: eas.COINBASE_EAS_REVOKE_TOPIC,
+			alter: func(reader *MockChainReader, l quiknode.EventLog) {
+				reader.attestations[l.UUID] = withAttestation(reader.attestations[l.UUID], func(a *eas.Attestation) {
+					a.RevocationTime = 0
+				})
+			},
+			err: ErrChainMismatch,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, l, reader := included(t, account, tc.topic)
+			if tc.alter != nil {
+				tc.alter(reader, l)
+			}
+			c := NewChainVerifier(reader)
+			c.now = func() time.Time { return now }
+
+			checked, err := c.Check(r, l.EAS)
+			if tc.err == nil {
+				require.NoError(t, err)
+				// the expiration is captured from the attestation
+				require.Equal(t, reader.attestations[l.UUID].ExpirationTime, checked.ExpirationTime)
+				checked.ExpirationTime = 0
+				require.Equal(t, l.EAS, checked)
+				return
+			}
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func withAttestation(a eas.Attestation, alter func(a *eas.Attestation)) eas.Attestation {
+	alter(&a)
+	return a
+}
+
+func Test_ChainVerifier_Report(t *testing.T) {
+	account := common.BytesToHash(mock.GenRandomHash(20))
+	r, l, reader := included(t, account, eas.COINBASE_EAS_ATTEST_TOPIC)
+	c := NewChainVerifier(reader)
+
+	// the event must be in the report
+	other := l.EAS
+	other.UUID = common.HexToHash("0x01")
+	_, err := c.Check(r, other)
+	require.ErrorIs(t, err, ErrNotIncluded)
+
+	// & located by its log
+	body := r.Body
+	r.Body = `{"matchedReceipts":[{"logs":[{"topics":["` + eas.COINBASE_EAS_ATTEST_TOPIC + `","` + account.Hex() + `","` +
+		eas.COINBASE_EAS_HASH + `","` + eas.COINBASE_EAS_SCHEMA_ID + `"],"data":"` + l.UUID.Hex() + `"}]}]}`
+	_, err = c.Check(r, l.EAS)
+	require.ErrorIs(t, err, ErrNotIncluded)
+
+	r.Body = body
+	_, err = c.Check(r, l.EAS)
+	require.NoError(t, err)
+}
+
+func Test_ChainVerifier_CheckExpired(t *testing.T) {
+	account := common.BytesToHash(mock.GenRandomHash(20))
+	now := time.Unix(1700000000, 0)
+	_, l, reader := included(t, account, eas.COINBASE_EAS_ATTEST_TOPIC)
+	c := NewChainVerifier(reader)
+	c.now = func() time.Time { return now }
+	expire := func(alter func(a *eas.Attestation)) {
+		reader.SetAttestation(withAttestation(reader.attestations[l.UUID], alter))
+	}
+
+	// no expiration
+	require.ErrorIs(t, c.CheckExpired(l.EAS), ErrNotExpired)
+	expire(func(a *eas.Attestation) { a.ExpirationTime = uint64(now.Unix()) + 1 })
+	require.ErrorIs(t, c.CheckExpired(l.EAS), ErrNotExpired)
+
+	expire(func(a *eas.Attestation) { a.ExpirationTime = uint64(now.Unix()) })
+	require.NoError(t, c.CheckExpired(l.EAS))
+
+	// the attestation must still match the event
+	other := l.EAS
+	other.Account = common.BytesToHash(mock.GenRandomHash(20))
+	require.ErrorIs(t, c.CheckExpired(other), ErrChainMismatch)
+
+	expire(func(a *eas.Attestation) { a.RevocationTime = 2 })
+	require.ErrorIs(t, c.CheckExpired(l.EAS), ErrChainMismatch)
+}
+
+func Test_Auditor_ChainCheck(t *testing.T) {
+	account := common.BytesToHash(mock.GenRandomHash(20))
+	_, rdb, sdb, v, prover := setup(t, account)
+	r, l, reader := included(t, account, eas.COINBASE_EAS_ATTEST_TOPIC)
+	a := NewAuditorWithChecker(v, rdb, sdb, prover, NewChainVerifier(reader))
+
+	// a fake attestation is not proven
+	delete(reader.attestations, l.UUID)
+	rdb.Set(account.Hex(), time.Now().Unix(), r)
+	require.ErrorIs(t, a.Audit(account.Hex()), eas.ErrAttestationNotFound)
+	require.Zero(t, prover.calls)
+	require.Equal(t, sDB.NONE, sdb.GetMembership(account.Hex()))
+
+	reader.Include(l)
+	require.NoError(t, a.Audit(account.Hex()))
+	require.Equal(t, 1, prover.calls)
+	require.Equal(t, sDB.INCLUSION, sdb.GetMembership(account.Hex()))
+}
@@ -0,0 +1,137 @@
+package auditor
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+)
+
+// Clock of the ExpiryScheduler, MockClock in tests
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// SystemClock is the Clock of the system time
+var SystemClock Clock = systemClock{}
+
+// Expiry of the attestation which included an account
+type Expiry struct {
+	PublicID string  `json:"publicID"`
+	Event    eas.EAS `json:"event"`
+}
+
+// At returns the time the attestation expires at
+func (x Expiry) At() time.Time {
+	return time.Unix(int64(x.Event.ExpirationTime), 0)
+}
+
+// ExpiryScheduler keeps the expiration of the attestation of each
+// included account & hands the expired ones to Run
+type ExpiryScheduler struct {
+	clock Clock
+
+	mut       sync.Mutex
+	scheduled map[string]Expiry
+	// wakes Run up when an earlier expiry is scheduled
+	wake chan struct{}
+}
+
+func NewExpiryScheduler(clock Clock) *ExpiryScheduler {
+	if clock == nil {
+		clock = SystemClock
+	}
+	return &ExpiryScheduler{clock: clock, scheduled: make(map[string]Expiry), wake: make(chan struct{}, 1)}
+}
+
+// Schedule the expiry of x, it replaces the expiry scheduled for its
+// public ID. An event without expiration time cancels it instead.
+func (s *ExpiryScheduler) Schedule(x Expiry) {
+	if x.Event.ExpirationTime == 0 {
+		s.Cancel(x.PublicID)
+		return
+	}
+	s.mut.Lock()
+	s.scheduled[x.PublicID] = x
+	s.mut.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Cancel the expiry scheduled for the public ID
+func (s *ExpiryScheduler) Cancel(publicID string) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	delete(s.scheduled, publicID)
+}
+
+// Scheduled returns the scheduled expiries, the earliest first
+func (s *ExpiryScheduler) Scheduled() []Expiry {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.sorted()
+}
+
+func (s *ExpiryScheduler) sorted() []Expiry {
+	out := make([]Expiry, 0, len(s.scheduled))
+	for _, x := range s.scheduled {
+		out = append(out, x)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Event.ExpirationTime != out[j].Event.ExpirationTime {
+			return out[i].Event.ExpirationTime < out[j].Event.ExpirationTime
+		}
+		return out[i].PublicID < out[j].PublicID
+	})
+	return out
+}
+
+// Due removes & returns the expiries due at the current time,
+// & the time until the next one, 0 if none is left
+func (s *ExpiryScheduler) Due() ([]Expiry, time.Duration) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	now := s.clock.Now().Unix()
+	var due []Expiry
+	for _, x := range s.sorted() {
+		if !x.Event.Expired(now) {
+			return due, time.Duration(int64(x.Event.ExpirationTime)-now) * time.Second
+		}
+		due = append(due, x)
+		delete(s.scheduled, x.PublicID)
+	}
+	return due, 0
+}
+
+// Run calls expire for every expiry when it is due until ctx is done
+func (s *ExpiryScheduler) Run(ctx context.Context, expire func(x Expiry)) {
+	for {
+		due, next := s.Due()
+		for _, x := range due {
+			expire(x)
+		}
+
+		var timer <-chan time.Time
+		if next > 0 {
+			timer = s.clock.After(next)
+		}
+		select {
+		case <-timer:
+		case <-s.wake:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
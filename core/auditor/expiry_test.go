@@ -0,0 +1,210 @@
+package auditor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/0xBow-io/base-eas-asp/core/verifier"
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	mock "github.com/0xBow-io/base-eas-asp/pkg/mock"
+	sDB "github.com/0xBow-io/base-eas-asp/pkg/statedb"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func expiryAt(publicID string, at time.Time) Expiry {
+	return Expiry{PublicID: publicID, Event: eas.EAS{Type: eas.EAS_ATTEST, ExpirationTime: uint64(at.Unix())}}
+}
+
+func expired(t *testing.T, expiries chan Expiry) []string {
+	var ids []string
+	for {
+		select {
+		case x := <-expiries:
+			ids = append(ids, x.PublicID)
+		case <-time.After(20 * time.Millisecond):
+			return ids
+		}
+	}
+}
+
+func Test_ExpiryScheduler(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	clock := NewMockClock(t0)
+	s := NewExpiryScheduler(clock)
+
+	s.Schedule(expiryAt("a", t0.Add(10*time.Second)))
+	s.Schedule(expiryAt("b", t0.Add(5*time.Second)))
+	s.Schedule(expiryAt("c", t0.Add(5*time.Second)))
+	// an event without expiration cancels the expiry
+	s.Schedule(Expiry{PublicID: "c", Event: eas.EAS{Type: eas.EAS_ATTEST}})
+	scheduled := s.Scheduled()
+	require.Len(t, scheduled, 2)
+	require.Equal(t, "b", scheduled[0].PublicID)
+	require.Equal(t, "a", scheduled[1].PublicID)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	expiries := make(chan Expiry, 8)
+	go s.Run(ctx, func(x Expiry) { expiries <- x })
+	require.Empty(t, expired(t, expiries))
+
+	clock.Advance(5 * time.Second)
+	require.Equal(t, []string{"b"}, expired(t, expiries))
+
+	// an earlier expiry wakes the scheduler up
+	s.Schedule(expiryAt("d", t0.Add(7*time.Second)))
+	clock.Advance(2 * time.Second)
+	require.Equal(t, []string{"d"}, expired(t, expiries))
+
+	// a rescheduled expiry replaces the previous one
+	s.Schedule(expiryAt("a", t0.Add(20*time.Second)))
+	clock.Advance(5 * time.Second)
+	require.Empty(t, expired(t, expiries))
+	clock.Advance(8 * time.Second)
+	require.Equal(t, []string{"a"}, expired(t, expiries))
+
+	// canceled
+	s.Schedule(expiryAt("e", t0.Add(30*time.Second)))
+	s.Cancel("e")
+	clock.Advance(time.Minute)
+	require.Empty(t, expired(t, expiries))
+	require.Empty(t, s.Scheduled())
+
+	// already expired
+	s.Schedule(expiryAt("f", t0))
+	require.Equal(t, []string{"f"}, expired(t, expiries))
+}
+
+func Test_Auditor_Expiry(t *testing.T) {
+	var (
+		account  = common.BytesToHash(mock.GenRandomHash(20))
+		revoked  = common.BytesToHash(mock.GenRandomHash(20))
+		renewed  = common.BytesToHash(mock.GenRandomHash(20))
+		t0       = time.Unix(1700000000, 0)
+		clock    = NewMockClock(t0)
+		expiry   = NewExpiryScheduler(clock)
+		reader   = NewMockChainReader()
+		checker  = NewChainVerifier(reader)
+		expireAt = uint64(t0.Add(time.Hour).Unix())
+	)
+	checker.now = clock.Now
+	_, rdb, sdb, v, prover := setup(t, account, revoked, renewed)
+	a := NewAuditorWithConfig(v, rdb, sdb, prover, AuditorConfig{
+		Checker:   checker,
+		Expiry:    expiry,
+		Downgrade: sDB.PARTIAL_INCLUSION,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go a.HandleExpirations(ctx)
+
+	// the expiration is read from the attestations
+	attest := func(account common.Hash, ts int64, expireAt uint64) {
+		r, l, _ := included(t, account, eas.COINBASE_EAS_ATTEST_TOPIC)
+		reader.Include(l)
+		reader.SetAttestation(withAttestation(eas.Attestation{}, func(at *eas.Attestation) {
+			*at = reader.attestations[l.UUID]
+			at.ExpirationTime = expireAt
+		}))
+		rdb.Set(account.Hex(), ts, r)
+		require.NoError(t, a.Audit(account.Hex()))
+		require.Equal(t, sDB.INCLUSION, sdb.GetMembership(account.Hex()))
+	}
+	attest(account, t0.Unix(), expireAt)
+	attest(revoked, t0.Unix(), expireAt)
+	attest(renewed, t0.Unix(), expireAt)
+	scheduled := expiry.Scheduled()
+	require.Len(t, scheduled, 3)
+	require.Equal(t, expireAt, scheduled[0].Event.ExpirationTime)
+
+	// a renewal keeps the membership, the expiry is rescheduled when due
+	attest(renewed, t0.Unix()+1, expireAt+3600)
+	require.Equal(t, 3, prover.calls)
+
+	// a revocation cancels the expiry
+	r, l, _ := included(t, revoked, eas.COINBASE_EAS_REVOKE_TOPIC)
+	reader.Include(l)
+	rdb.Set(revoked.Hex(), t0.Unix()+1, r)
+	require.NoError(t, a.Audit(revoked.Hex()))
+	require.Equal(t, sDB.EXCLUSION, sdb.GetMembership(revoked.Hex()))
+	require.Len(t, expiry.Scheduled(), 2)
+
+	clock.Advance(time.Hour - time.Second)
+	time.Sleep(20 * time.Millisecond)
+	require.Equal(t, sDB.INCLUSION, sdb.GetMembership(account.Hex()))
+
+	// the expiration is checked on chain & the downgrade applied without proof
+	clock.Advance(time.Second)
+	require.Eventually(t, func() bool {
+		return sdb.GetMembership(account.Hex()) == sDB.PARTIAL_INCLUSION
+	}, time.Second, time.Millisecond)
+	outcomes := v.Outcomes()
+	require.Len(t, outcomes, 5)
+	require.Equal(t, sDB.INCLUSION, outcomes[4].From)
+	require.Equal(t, sDB.PARTIAL_INCLUSION, outcomes[4].To)
+	require.Equal(t, verifier.APPLIED, outcomes[4].Status)
+	require.Contains(t, outcomes[4].Cause, "expired")
+	require.Equal(t, 4, prover.calls)
+
+	// the renewed attestation is scheduled
+	require.Eventually(t, func() bool {
+		scheduled := expiry.Scheduled()
+		return len(scheduled) == 1 && scheduled[0].Event.ExpirationTime == expireAt+3600
+	}, time.Second, time.Millisecond)
+	require.Equal(t, renewed.Hex(), expiry.Scheduled()[0].PublicID)
+	require.Equal(t, sDB.INCLUSION, sdb.GetMembership(renewed.Hex()))
+
+	clock.Advance(time.Hour)
+	require.Eventually(t, func() bool {
+		return sdb.GetMembership(renewed.Hex()) == sDB.PARTIAL_INCLUSION
+	}, time.Second, time.Millisecond)
+	require.Empty(t, expiry.Scheduled())
+
+	// the expired attestation is no longer accepted
+	require.NoError(t, sdb.CompareAndSetMembership(account.Hex(), sDB.PARTIAL_INCLUSION, sDB.EXCLUSION))
+	require.ErrorIs(t, a.Audit(account.Hex()), ErrChainMismatch)
+}
+
+// proofVerifier hides the expiry path of the verifier
+type proofVerifier struct{ Verifier }
+
+func Test_Auditor_Expiry_Stale(t *testing.T) {
+	var (
+		account = common.BytesToHash(mock.GenRandomHash(20))
+		r, l, _ = included(t, account, eas.COINBASE_EAS_ATTEST_TOPIC)
+		reader  = NewMockChainReader()
+		checker = NewChainVerifier(reader)
+	)
+	reader.Include(l)
+	_, rdb, sdb, v, prover := setup(t, account)
+	a := NewAuditorWithConfig(v, rdb, sdb, prover, AuditorConfig{Checker: checker, Expiry: NewExpiryScheduler(nil)})
+
+	rdb.Set(account.Hex(), time.Now().Unix(), r)
+	require.NoError(t, a.Audit(account.Hex()))
+	// the attestation doesn't expire
+	require.Empty(t, a.expiry.Scheduled())
+
+	// a later attestation derives the membership
+	x := Expiry{PublicID: account.Hex(), Event: eas.EAS{UUID: common.HexToHash("0x01"), Account: account, ExpirationTime: 1}}
+	require.NoError(t, a.expire(x))
+	require.Equal(t, sDB.INCLUSION, sdb.GetMembership(account.Hex()))
+	require.Empty(t, a.expiry.Scheduled())
+
+	// the expiration is checked on chain
+	x.Event = l.EAS
+	require.ErrorIs(t, a.expire(x), ErrNotExpired)
+	require.Equal(t, sDB.INCLUSION, sdb.GetMembership(account.Hex()))
+
+	reader.SetAttestation(withAttestation(reader.attestations[l.UUID], func(at *eas.Attestation) {
+		at.ExpirationTime = 1
+	}))
+	// the downgrade requires the chain checker & the expiry path of the verifier
+	require.ErrorIs(t, NewAuditorWithConfig(v, rdb, sdb, prover, AuditorConfig{}).expire(x), ErrNoExpiryVerifier)
+	require.ErrorIs(t, NewAuditorWithConfig(proofVerifier{v}, rdb, sdb, prover, AuditorConfig{Checker: checker}).expire(x), ErrNoExpiryVerifier)
+	require.Equal(t, sDB.INCLUSION, sdb.GetMembership(account.Hex()))
+
+	require.NoError(t, a.expire(x))
+	require.Equal(t, sDB.EXCLUSION, sdb.GetMembership(account.Hex()))
+}
@@ -0,0 +1,170 @@
+package auditor
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	proofOfAudit "github.com/0xBow-io/base-eas-asp/pkg/proof-of-audit"
+	quiknode "github.com/0xBow-io/base-eas-asp/pkg/quiknode"
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// MockProver returns committed proofs without running the SP1 prover,
+// for local demos only.
+type MockProver struct{}
+
+func NewMockProver() *MockProver {
+	return &MockProver{}
+}
+
+func (p *MockProver) Prove(r reportDB.Report, e eas.EAS) (*proofOfAudit.SP1Proof, error) {
+	id := crypto.Keccak256Hash([]byte(r.Header.Signature), e.UUID.Bytes(), e.Account.Bytes())
+	return &proofOfAudit.SP1Proof{
+		Proof:  "mock:" + id.Hex(),
+		Stdout: proofOfAudit.StdIO{Buffer: proofOfAudit.StdBuffer{Data: proofOfAudit.EventPublicValues(e).Encode()}},
+	}, nil
+}
+
+// MockChainReader serves the attestations & receipts set on it
+// like the EAS contract & a node, for tests & local demos.
+type MockChainReader struct {
+	mut          sync.Mutex
+	attestations map[common.Hash]eas.Attestation
+	receipts     map[common.Hash]*types.Receipt
+}
+
+func NewMockChainReader() *MockChainReader {
+	return &MockChainReader{
+		attestations: make(map[common.Hash]eas.Attestation),
+		receipts:     make(map[common.Hash]*types.Receipt),
+	}
+}
+
+// SetAttestation sets the attestation returned for its uid
+func (m *MockChainReader) SetAttestation(a eas.Attestation) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.attestations[a.UID] = a
+}
+
+// SetReceipt sets the receipt returned for its transaction
+func (m *MockChainReader) SetReceipt(r *types.Receipt) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.receipts[r.TxHash] = r
+}
+
+// Include sets a successful receipt holding the log of the event
+// & the Coinbase attestation of the event, revoked for a revocation
+func (m *MockChainReader) Include(l quiknode.EventLog) {
+	topic := common.HexToHash(eas.COINBASE_EAS_ATTEST_TOPIC)
+	a := eas.Attestation{
+		UID:       l.UUID,
+		Schema:    common.HexToHash(eas.COINBASE_EAS_SCHEMA_ID),
+		Time:      1,
+		Recipient: common.BytesToAddress(l.Account.Bytes()),
+		Attester:  common.HexToAddress(eas.COINBASE_EAS_HASH),
+		Revocable: true,
+	}
+	if l.Type == eas.EAS_REVOKE {
+		topic = common.HexToHash(eas.COINBASE_EAS_REVOKE_TOPIC)
+		a.RevocationTime = 2
+	}
+	m.SetAttestation(a)
+	m.SetReceipt(&types.Receipt{
+		Status:      types.ReceiptStatusSuccessful,
+		TxHash:      l.TxHash,
+		BlockHash:   l.BlockHash,
+		BlockNumber: new(big.Int).SetUint64(l.BlockNumber),
+		Logs: []*types.Log{{
+			Address:     common.HexToAddress(eas.BASE_EAS_ADDR),
+			Topics:      []common.Hash{topic, l.Account, common.HexToHash(eas.COINBASE_EAS_HASH), common.HexToHash(eas.COINBASE_EAS_SCHEMA_ID)},
+			Data:        l.UUID.Bytes(),
+			BlockNumber: l.BlockNumber,
+			TxHash:      l.TxHash,
+			BlockHash:   l.BlockHash,
+			Index:       l.LogIndex,
+		}},
+	})
+}
+
+func (m *MockChainReader) CallContract(_ context.Context, call ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	if call.To == nil || *call.To != common.HexToAddress(eas.BASE_EAS_ADDR) {
+		return nil, nil
+	}
+	uid, err := eas.UnpackGetAttestation(call.Data)
+	if err != nil {
+		return nil, err
+	}
+	m.mut.Lock()
+	a := m.attestations[uid]
+	m.mut.Unlock()
+	return eas.PackAttestation(a)
+}
+
+func (m *MockChainReader) TransactionReceipt(_ context.Context, txHash common.Hash) (*types.Receipt, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	r, ok := m.receipts[txHash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return r, nil
+}
+
+// MockClock is a Clock which time only moves with Advance, for tests
+type MockClock struct {
+	mut    sync.Mutex
+	now    time.Time
+	timers []mockTimer
+}
+
+type mockTimer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func NewMockClock(now time.Time) *MockClock {
+	return &MockClock{now: now}
+}
+
+func (c *MockClock) Now() time.Time {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.now
+}
+
+func (c *MockClock) After(d time.Duration) <-chan time.Time {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.timers = append(c.timers, mockTimer{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance the time by d & fire the timers due
+func (c *MockClock) Advance(d time.Duration) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.now = c.now.Add(d)
+	timers := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			timers = append(timers, t)
+			continue
+		}
+		t.ch <- c.now
+	}
+	c.timers = timers
+}
@@ -0,0 +1,57 @@
+package auditor
+
+import (
+	"strings"
+
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	proofOfAudit "github.com/0xBow-io/base-eas-asp/pkg/proof-of-audit"
+	quiknode "github.com/0xBow-io/base-eas-asp/pkg/quiknode"
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+	"github.com/pkg/errors"
+)
+
+var ErrUnprovableReport = errors.New("report can't be proven by the SP1 program")
+
+// SP1Prover generates proofs of audit with the SP1 prover,
+// secret & urlPath are the private inputs of the webhook.
+// The program checks the signature of the body as delivered & parses
+// it as a QuickAlerts payload, the encoded reports & the other formats
+// are rejected.
+type SP1Prover struct {
+	secret  string
+	urlPath string
+}
+
+func NewSP1Prover(secret, urlPath string) *SP1Prover {
+	return &SP1Prover{secret: secret, urlPath: urlPath}
+}
+
+func (p *SP1Prover) Prove(r reportDB.Report, e eas.EAS) (*proofOfAudit.SP1Proof, error) {
+	if err := provable(r); err != nil {
+		return nil, err
+	}
+	return proofOfAudit.VerifyCommitment(
+		p.secret,
+		p.urlPath,
+		r.Header.Nonce,
+		r.Header.Timestamp,
+		r.Body,
+		r.Header.Signature,
+		e.UUID.Hex(),
+		e.Account.Hex(),
+	)
+}
+
+// provable returns an error if the report can't be passed to the program
+func provable(r reportDB.Report) error {
+	switch {
+	case r.Header.ContentEncoding != "":
+		return errors.Wrapf(ErrUnprovableReport, "content encoding %q", r.Header.ContentEncoding)
+	case r.Header.Format != "" && r.Header.Format != quiknode.FormatQuickAlerts:
+		return errors.Wrapf(ErrUnprovableReport, "format %q", r.Header.Format)
+	case strings.ContainsRune(r.Body, 0):
+		// the body is passed as a C string
+		return errors.Wrap(ErrUnprovableReport, "body contains a NUL byte")
+	}
+	return nil
+}
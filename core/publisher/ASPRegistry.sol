@@ -0,0 +1,46 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.20;
+
+/*
+    Registry of the association set roots published by the ASP.
+
+    Sets are identified by their index in asp.Sets:
+        0 = inclusion, 1 = exclusion, 2 = partial_inclusion
+
+    root is the digest of the set's namespaced merkle tree root
+    (0 for an empty set) and versions of a set must increase.
+*/
+contract ASPRegistry {
+    struct Root {
+        uint64 version;
+        uint8 set;
+        uint256 root;
+    }
+
+    address public immutable publisher;
+
+    mapping(uint8 => uint64) public latestVersion;
+    mapping(uint8 => uint256) public latestRoot;
+
+    event RootPublished(uint64 indexed version, uint8 indexed set, uint256 root);
+
+    error Unauthorized();
+    error StaleVersion(uint8 set, uint64 version);
+
+    constructor(address _publisher) {
+        publisher = _publisher;
+    }
+
+    function publishRoots(Root[] calldata roots) external {
+        if (msg.sender != publisher) revert Unauthorized();
+
+        for (uint256 i = 0; i < roots.length; i++) {
+            Root calldata r = roots[i];
+            if (r.version <= latestVersion[r.set]) revert StaleVersion(r.set, r.version);
+
+            latestVersion[r.set] = r.version;
+            latestRoot[r.set] = r.root;
+            emit RootPublished(r.version, r.set, r.root);
+        }
+    }
+}
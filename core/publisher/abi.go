@@ -0,0 +1,68 @@
+package publisher
+
+// ASPRegistryABI is the ABI of ASPRegistry.sol
+const ASPRegistryABI = `[
+	{
+		"type": "constructor",
+		"inputs": [{"name": "_publisher", "type": "address", "internalType": "address"}],
+		"stateMutability": "nonpayable"
+	},
+	{
+		"type": "function",
+		"name": "publisher",
+		"inputs": [],
+		"outputs": [{"name": "", "type": "address", "internalType": "address"}],
+		"stateMutability": "view"
+	},
+	{
+		"type": "function",
+		"name": "latestVersion",
+		"inputs": [{"name": "", "type": "uint8", "internalType": "uint8"}],
+		"outputs": [{"name": "", "type": "uint64", "internalType": "uint64"}],
+		"stateMutability": "view"
+	},
+	{
+		"type": "function",
+		"name": "latestRoot",
+		"inputs": [{"name": "", "type": "uint8", "internalType": "uint8"}],
+		"outputs": [{"name": "", "type": "uint256", "internalType": "uint256"}],
+		"stateMutability": "view"
+	},
+	{
+		"type": "function",
+		"name": "publishRoots",
+		"inputs": [
+			{
+				"name": "roots",
+				"type": "tuple[]",
+				"internalType": "struct ASPRegistry.Root[]",
+				"components": [
+					{"name": "version", "type": "uint64", "internalType": "uint64"},
+					{"name": "set", "type": "uint8", "internalType": "uint8"},
+					{"name": "root", "type": "uint256", "internalType": "uint256"}
+				]
+			}
+		],
+		"outputs": [],
+		"stateMutability": "nonpayable"
+	},
+	{
+		"type": "event",
+		"name": "RootPublished",
+		"inputs": [
+			{"name": "version", "type": "uint64", "indexed": true, "internalType": "uint64"},
+			{"name": "set", "type": "uint8", "indexed": true, "internalType": "uint8"},
+			{"name": "root", "type": "uint256", "indexed": false, "internalType": "uint256"}
+		],
+		"anonymous": false
+	},
+	{"type": "error", "name": "Unauthorized", "inputs": []},
+	{
+		"type": "error",
+		"name": "StaleVersion",
+		"inputs": [
+			{"name": "set", "type": "uint8", "internalType": "uint8"},
+			{"name": "version", "type": "uint64", "internalType": "uint64"}
+		]
+	}
+]`
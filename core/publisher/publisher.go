@@ -0,0 +1,353 @@
+package publisher
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/0xBow-io/base-eas-asp/core/asp"
+	nmt "github.com/0xBow-io/base-eas-asp/pkg/nmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrTxFailed       = errors.New("transaction failed")
+	ErrConfirmTimeout = errors.New("timed out waiting for confirmations")
+	ErrNothingToFlush = errors.New("no pending roots")
+)
+
+type Backend interface {
+	bind.ContractBackend
+	TransactionByHash(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	BlockNumber(ctx context.Context) (uint64, error)
+}
+
+type Config struct {
+	// address of the ASPRegistry contract
+	Registry common.Address
+	// max number of roots per transaction
+	BatchSize int
+	// how often pending roots are flushed by Run
+	BatchInterval time.Duration
+	// number of blocks including the one of the transaction
+	// before a submission is considered final
+	Confirmations uint64
+	// max time to wait for the confirmations of a transaction
+	ConfirmTimeout time.Duration
+	// number of retries after a failed attempt
+	MaxRetries   int
+	RetryDelay   time.Duration
+	PollInterval time.Duration
+}
+
+func DefaultConfig(registry common.Address) Config {
+	return Config{
+		Registry:       registry,
+		BatchSize:      len(asp.Sets),
+		BatchInterval:  time.Minute,
+		Confirmations:  3,
+		ConfirmTimeout: 5 * time.Minute,
+		MaxRetries:     3,
+		RetryDelay:     5 * time.Second,
+		PollInterval:   2 * time.Second,
+	}
+}
+
+// Submission records a batch of roots sent to the registry
+type Submission struct {
+	TxHash    common.Hash `json:"txHash"`
+	Roots     []Root      `json:"roots"`
+	Attempts  int         `json:"attempts"`
+	Block     uint64      `json:"block"`
+	Confirmed bool        `json:"confirmed"`
+	Err       string      `json:"error,omitempty"`
+}
+
+/*
+Publisher batches the roots of published ASP versions
+and submits them to the ASPRegistry contract.
+
+Roots stay queued until the transaction publishing them
+has the configured number of confirmations, failed attempts
+are retried up to MaxRetries times per Flush.
+The transaction of a previous attempt that is pending or mined
+is awaited instead of publishing its roots again, the next Flush
+resumes an unconfirmed submission.
+*/
+type Publisher struct {
+	cfg      Config
+	backend  Backend
+	auth     *bind.TransactOpts
+	registry *Registry
+
+	// only one batch is flushed at a time
+	flushMut sync.Mutex
+
+	mut   sync.Mutex
+	queue []Root
+	// highest version queued per set
+	latest      map[uint8]uint64
+	submissions []Submission
+}
+
+func NewPublisher(cfg Config, backend Backend, auth *bind.TransactOpts) *Publisher {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = len(asp.Sets)
+	}
+	return &Publisher{
+		cfg:      cfg,
+		backend:  backend,
+		auth:     auth,
+		registry: NewRegistry(cfg.Registry, backend),
+		latest:   make(map[uint8]uint64),
+	}
+}
+
+// SetIndex returns the registry id of an association set
+func SetIndex(v asp.SetRoot) (uint8, bool) {
+	for i, m := range asp.Sets {
+		if m == v.Membership {
+			return uint8(i), true
+		}
+	}
+	return 0, false
+}
+
+// RootDigest returns the digest of a set root, 0 for an empty set
+func RootDigest(root nmt.Node) *big.Int {
+	if len(root) <= nmt.ElementSize {
+		return new(big.Int)
+	}
+	return root.Hash(nmt.IDSize((len(root) - nmt.ElementSize) / 2)).BigInt()
+}
+
+// Enqueue queues the set roots of a version,
+// returns the number of roots that were newer than the queued ones.
+func (p *Publisher) Enqueue(v asp.RootVersion) int {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	n := 0
+	for _, m := range asp.Sets {
+		setRoot, ok := v.Roots[m]
+		if !ok {
+			continue
+		}
+		set, ok := SetIndex(setRoot)
+		if !ok {
+			continue
+		}
+		if v.Version <= p.latest[set] {
+			continue
+		}
+		p.latest[set] = v.Version
+		p.queue = append(p.queue, Root{Version: v.Version, Set: set, Root: RootDigest(setRoot.Root)})
+		n++
+	}
+	return n
+}
+
+func (p *Publisher) Pending() int {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	return len(p.queue)
+}
+
+// Submissions returns every submission in order
+func (p *Publisher) Submissions() []Submission {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	return append([]Submission(nil), p.submissions...)
+}
+
+// nextBatch returns the roots of the next submission, the ones of the
+// last submission & its transaction if it wasn't confirmed
+func (p *Publisher) nextBatch() ([]Root, common.Hash) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if n := len(p.submissions); n > 0 && !p.submissions[n-1].Confirmed && len(p.queue) > 0 {
+		last := p.submissions[n-1]
+		return append([]Root(nil), last.Roots...), last.TxHash
+	}
+	n := len(p.queue)
+	if n > p.cfg.BatchSize {
+		n = p.cfg.BatchSize
+	}
+	return append([]Root(nil), p.queue[:n]...), common.Hash{}
+}
+
+func (p *Publisher) record(s Submission) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if s.Confirmed {
+		p.queue = p.queue[len(s.Roots):]
+	}
+	p.submissions = append(p.submissions, s)
+}
+
+// Flush publishes the next batch of queued roots
+// and waits for its confirmations.
+func (p *Publisher) Flush(ctx context.Context) (Submission, error) {
+	p.flushMut.Lock()
+	defer p.flushMut.Unlock()
+
+	batch, txHash := p.nextBatch()
+	if len(batch) == 0 {
+		return Submission{}, ErrNothingToFlush
+	}
+
+	s := Submission{TxHash: txHash, Roots: batch}
+	var err error
+	for s.Attempts <= p.cfg.MaxRetries {
+		if s.Attempts > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(p.cfg.RetryDelay):
+			}
+			if ctx.Err() != nil {
+				err = ctx.Err()
+				break
+			}
+		}
+		s.Attempts++
+
+		if err = p.submit(ctx, &s); err == nil {
+			s.Confirmed = true
+			s.Err = ""
+			break
+		}
+		s.Err = err.Error()
+	}
+
+	p.record(s)
+	return s, err
+}
+
+func (p *Publisher) submit(ctx context.Context, s *Submission) error {
+	sent, err := p.sent(ctx, s.TxHash)
+	if err != nil {
+		return err
+	}
+	if !sent {
+		opts := *p.auth
+		opts.Context = ctx
+
+		tx, err := p.registry.PublishRoots(&opts, s.Roots)
+		if err != nil {
+			return errors.Wrap(err, "failed to send transaction")
+		}
+		s.TxHash = tx.Hash()
+	}
+
+	receipt, err := p.waitConfirmed(ctx, s.TxHash)
+	if err != nil {
+		return err
+	}
+	s.Block = receipt.BlockNumber.Uint64()
+	return nil
+}
+
+// sent returns true if the transaction of a previous attempt is pending or
+// was mined successfully, it could be mined after its confirmations timed out
+func (p *Publisher) sent(ctx context.Context, txHash common.Hash) (bool, error) {
+	if txHash == (common.Hash{}) {
+		return false, nil
+	}
+	receipt, err := p.backend.TransactionReceipt(ctx, txHash)
+	switch {
+	case err == nil:
+		return receipt.Status == types.ReceiptStatusSuccessful, nil
+	case !errors.Is(err, ethereum.NotFound):
+		return false, errors.Wrap(err, "failed to get the receipt of "+txHash.Hex())
+	}
+
+	_, pending, err := p.backend.TransactionByHash(ctx, txHash)
+	switch {
+	case err == nil:
+		return pending, nil
+	case errors.Is(err, ethereum.NotFound):
+		// dropped
+		return false, nil
+	default:
+		return false, errors.Wrap(err, "failed to get the transaction "+txHash.Hex())
+	}
+}
+
+func (p *Publisher) waitConfirmed(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	var (
+		timeout = time.After(p.cfg.ConfirmTimeout)
+		ticker  = time.NewTicker(p.cfg.PollInterval)
+		lastErr error
+	)
+	defer ticker.Stop()
+
+	for {
+		receipt, err := p.backend.TransactionReceipt(ctx, txHash)
+		switch {
+		case err == nil:
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				return nil, errors.Wrap(ErrTxFailed, txHash.Hex())
+			}
+			head, err := p.backend.BlockNumber(ctx)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to get block number")
+			}
+			if head+1 >= receipt.BlockNumber.Uint64()+p.cfg.Confirmations {
+				return receipt, nil
+			}
+		case !errors.Is(err, ethereum.NotFound):
+			// nodes report transient errors while indexing transactions
+			lastErr = err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timeout:
+			if lastErr != nil {
+				return nil, errors.Wrapf(ErrConfirmTimeout, "%s: %v", txHash.Hex(), lastErr)
+			}
+			return nil, errors.Wrap(ErrConfirmTimeout, txHash.Hex())
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run flushes the queued roots every BatchInterval until ctx is done
+func (p *Publisher) Run(ctx context.Context, errChan chan<- error) {
+	ticker := time.NewTicker(p.cfg.BatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := p.flushAll(ctx); err != nil {
+			select {
+			case errChan <- errors.Wrap(err, "failed to publish roots"):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (p *Publisher) flushAll(ctx context.Context) error {
+	for p.Pending() > 0 {
+		if _, err := p.Flush(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
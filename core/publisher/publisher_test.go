@@ -0,0 +1,379 @@
+package publisher
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0xBow-io/base-eas-asp/core/asp"
+	nmt "github.com/0xBow-io/base-eas-asp/pkg/nmt"
+	sDB "github.com/0xBow-io/base-eas-asp/pkg/statedb"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// runtime code: STOP, accepts any call
+	acceptAllInit = "0x6001600c60003960016000f300"
+	// runtime code: revert(0, 0)
+	revertInit = "0x6005600c60003960056000f360006000fd"
+)
+
+type testChain struct {
+	backend *simulated.Backend
+	client  simulated.Client
+	key     *ecdsa.PrivateKey
+	auth    *bind.TransactOpts
+}
+
+func newTestChain(t *testing.T) *testChain {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	deployer := crypto.PubkeyToAddress(key.PublicKey)
+
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		deployer: {Balance: new(big.Int).Lsh(big.NewInt(1), 100)},
+	})
+	t.Cleanup(func() { backend.Close() })
+
+	chainID, err := backend.Client().ChainID(context.Background())
+	require.NoError(t, err)
+	auth, err := bind.NewKeyedTransactorWithChainID(key, chainID)
+	require.NoError(t, err)
+
+	return &testChain{backend: backend, client: backend.Client(), key: key, auth: auth}
+}
+
+// deploy sends a contract creation with the init code & mines it
+func (c *testChain) deploy(t *testing.T, initCode string) common.Address {
+	ctx := context.Background()
+	nonce, err := c.client.PendingNonceAt(ctx, c.auth.From)
+	require.NoError(t, err)
+
+	tx := types.NewContractCreation(nonce, big.NewInt(0), 1_000_000, big.NewInt(10_000_000_000), common.FromHex(initCode))
+	tx, err = c.auth.Signer(c.auth.From, tx)
+	require.NoError(t, err)
+	require.NoError(t, c.client.SendTransaction(ctx, tx))
+	c.backend.Commit()
+	return crypto.CreateAddress(c.auth.From, nonce)
+}
+
+// mine commits a block every interval until the test ends
+func (c *testChain) mine(t *testing.T, interval time.Duration) {
+	var (
+		done = make(chan struct{})
+		wg   sync.WaitGroup
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				c.backend.Commit()
+			}
+		}
+	}()
+	t.Cleanup(func() {
+		close(done)
+		wg.Wait()
+	})
+}
+
+// flakyBackend fails the first n transactions sent
+type flakyBackend struct {
+	Backend
+	mut   sync.Mutex
+	fails int
+}
+
+func (b *flakyBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	if b.fails > 0 {
+		b.fails--
+		return errors.New("connection reset")
+	}
+	return b.Backend.SendTransaction(ctx, tx)
+}
+
+// countingBackend counts the transactions sent
+type countingBackend struct {
+	Backend
+	mut  sync.Mutex
+	sent int
+}
+
+func (b *countingBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	b.mut.Lock()
+	b.sent++
+	b.mut.Unlock()
+	return b.Backend.SendTransaction(ctx, tx)
+}
+
+func (b *countingBackend) Sent() int {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return b.sent
+}
+
+func testConfig(registry common.Address) Config {
+	cfg := DefaultConfig(registry)
+	cfg.Confirmations = 2
+	cfg.ConfirmTimeout = 5 * time.Second
+	cfg.MaxRetries = 2
+	cfg.RetryDelay = 10 * time.Millisecond
+	cfg.PollInterval = 10 * time.Millisecond
+	cfg.BatchInterval = 20 * time.Millisecond
+	return cfg
+}
+
+func genVersion(version uint64) asp.RootVersion {
+	v := asp.RootVersion{Version: version, Roots: make(map[sDB.MEMBERSHIP_TYPE]asp.SetRoot)}
+	for i, m := range asp.Sets {
+		root := asp.SetRoot{Membership: m, HashID: nmt.HashNsPoseidon, Size: i}
+		if i > 0 {
+			ns := common.BigToHash(big.NewInt(int64(i)))
+			root.Root = append(append(append(nmt.Node{}, ns[:]...), ns[:]...),
+				common.BigToHash(new(big.Int).SetUint64(version*10+uint64(i))).Bytes()...)
+		}
+		v.Roots[m] = root
+	}
+	return v
+}
+
+func txRoots(t *testing.T, c *testChain, txHash common.Hash) []Root {
+	tx, _, err := c.client.TransactionByHash(context.Background(), txHash)
+	require.NoError(t, err)
+	roots, err := UnpackPublishRoots(tx.Data())
+	require.NoError(t, err)
+	return roots
+}
+
+func Test_Publisher_Enqueue(t *testing.T) {
+	p := NewPublisher(testConfig(common.Address{}), nil, nil)
+
+	require.Equal(t, len(asp.Sets), p.Enqueue(genVersion(2)))
+	// stale & duplicate versions are skipped
+	require.Equal(t, 0, p.Enqueue(genVersion(2)))
+	require.Equal(t, 0, p.Enqueue(genVersion(1)))
+	require.Equal(t, len(asp.Sets), p.Pending())
+
+	v := genVersion(3)
+	delete(v.Roots, sDB.EXCLUSION)
+	require.Equal(t, len(asp.Sets)-1, p.Enqueue(v))
+	require.Equal(t, 2*len(asp.Sets)-1, p.Pending())
+
+	// roots of unknown sets are skipped
+	v = genVersion(4)
+	root := v.Roots[sDB.EXCLUSION]
+	root.Membership = sDB.NONE
+	v.Roots[sDB.EXCLUSION] = root
+	require.Equal(t, len(asp.Sets)-1, p.Enqueue(v))
+
+	// empty set is published as a zero root
+	require.Equal(t, int64(0), RootDigest(genVersion(1).Roots[sDB.INCLUSION].Root).Int64())
+	require.Equal(t, int64(21), RootDigest(genVersion(2).Roots[sDB.EXCLUSION].Root).Int64())
+}
+
+func Test_Publisher_Flush(t *testing.T) {
+	c := newTestChain(t)
+	registry := c.deploy(t, acceptAllInit)
+	c.mine(t, 5*time.Millisecond)
+
+	cfg := testConfig(registry)
+	cfg.BatchSize = 2
+	p := NewPublisher(cfg, c.client, c.auth)
+
+	_, err := p.Flush(context.Background())
+	require.ErrorIs(t, err, ErrNothingToFlush)
+
+	p.Enqueue(genVersion(1))
+	p.Enqueue(genVersion(2))
+	require.Equal(t, 2*len(asp.Sets), p.Pending())
+
+	s, err := p.Flush(context.Background())
+	require.NoError(t, err)
+	require.True(t, s.Confirmed)
+	require.Equal(t, 1, s.Attempts)
+	require.Len(t, s.Roots, 2)
+	require.Equal(t, 2*len(asp.Sets)-2, p.Pending())
+
+	receipt, err := c.client.TransactionReceipt(context.Background(), s.TxHash)
+	require.NoError(t, err)
+	require.Equal(t, receipt.BlockNumber.Uint64(), s.Block)
+	head, err := c.client.BlockNumber(context.Background())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, head+1, s.Block+cfg.Confirmations)
+
+	roots := txRoots(t, c, s.TxHash)
+	require.Equal(t, len(s.Roots), len(roots))
+	for i := range roots {
+		require.Equal(t, s.Roots[i].Version, roots[i].Version)
+		require.Equal(t, s.Roots[i].Set, roots[i].Set)
+		require.Equal(t, 0, s.Roots[i].Root.Cmp(roots[i].Root))
+	}
+
+	require.NoError(t, p.flushAll(context.Background()))
+	require.Equal(t, 0, p.Pending())
+	require.Len(t, p.Submissions(), 3)
+}
+
+func Test_Publisher_Retry(t *testing.T) {
+	c := newTestChain(t)
+	registry := c.deploy(t, acceptAllInit)
+	c.mine(t, 5*time.Millisecond)
+
+	p := NewPublisher(testConfig(registry), &flakyBackend{Backend: c.client, fails: 2}, c.auth)
+	p.Enqueue(genVersion(1))
+
+	s, err := p.Flush(context.Background())
+	require.NoError(t, err)
+	require.True(t, s.Confirmed)
+	require.Equal(t, 3, s.Attempts)
+	require.Empty(t, s.Err)
+	require.Equal(t, 0, p.Pending())
+}
+
+func Test_Publisher_Failure(t *testing.T) {
+	c := newTestChain(t)
+	registry := c.deploy(t, revertInit)
+	c.mine(t, 5*time.Millisecond)
+
+	p := NewPublisher(testConfig(registry), c.client, c.auth)
+	p.Enqueue(genVersion(1))
+
+	s, err := p.Flush(context.Background())
+	require.Error(t, err)
+	require.False(t, s.Confirmed)
+	require.Equal(t, testConfig(registry).MaxRetries+1, s.Attempts)
+	require.NotEmpty(t, s.Err)
+
+	// roots stay queued for the next flush
+	require.Equal(t, len(asp.Sets), p.Pending())
+	require.Len(t, p.Submissions(), 1)
+
+	// transactions that are never mined time out
+	c2 := newTestChain(t)
+	cfg := testConfig(c2.deploy(t, acceptAllInit))
+	cfg.MaxRetries = 0
+	cfg.ConfirmTimeout = 50 * time.Millisecond
+	p = NewPublisher(cfg, c2.client, c2.auth)
+	p.Enqueue(genVersion(1))
+	_, err = p.Flush(context.Background())
+	require.ErrorIs(t, err, ErrConfirmTimeout)
+}
+
+func Test_Publisher_Resend(t *testing.T) {
+	c := newTestChain(t)
+	registry := c.deploy(t, acceptAllInit)
+	cfg := testConfig(registry)
+	cfg.MaxRetries = 0
+	cfg.ConfirmTimeout = 50 * time.Millisecond
+	backend := &countingBackend{Backend: c.client}
+	p := NewPublisher(cfg, backend, c.auth)
+	p.Enqueue(genVersion(1))
+
+	// the transaction is mined after its confirmations timed out
+	first, err := p.Flush(context.Background())
+	require.ErrorIs(t, err, ErrConfirmTimeout)
+	require.Equal(t, 1, backend.Sent())
+	p.Enqueue(genVersion(2))
+	for i := 0; i < 2; i++ {
+		c.backend.Commit()
+	}
+
+	// the next flush resumes the submission without sending it again
+	s, err := p.Flush(context.Background())
+	require.NoError(t, err)
+	require.True(t, s.Confirmed)
+	require.Equal(t, first.TxHash, s.TxHash)
+	require.Equal(t, first.Roots, s.Roots)
+	require.Equal(t, 1, backend.Sent())
+	require.Equal(t, len(asp.Sets), p.Pending())
+
+	// a retry awaits the transaction of the previous attempt
+	cfg.MaxRetries = 1
+	cfg.RetryDelay = 300 * time.Millisecond
+	p = NewPublisher(cfg, backend, c.auth)
+	p.Enqueue(genVersion(1))
+	go func() {
+		time.Sleep(150 * time.Millisecond)
+		for i := 0; i < 2; i++ {
+			c.backend.Commit()
+		}
+	}()
+	s, err = p.Flush(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, s.Attempts)
+	require.Equal(t, 2, backend.Sent())
+}
+
+func Test_Publisher_Run(t *testing.T) {
+	c := newTestChain(t)
+	registry := c.deploy(t, acceptAllInit)
+	c.mine(t, 5*time.Millisecond)
+
+	p := NewPublisher(testConfig(registry), c.client, c.auth)
+	p.Enqueue(genVersion(1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errChan := make(chan error, 1)
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx, errChan)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return p.Pending() == 0 }, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	require.Len(t, p.Submissions(), 1)
+	require.True(t, p.Submissions()[0].Confirmed)
+	require.Empty(t, errChan)
+}
+
+// Publishes against the compiled ASPRegistry,
+// uses the artifacts built by the makefile
+func Test_Publisher_ASPRegistry(t *testing.T) {
+	b, err := os.ReadFile("build/ASPRegistry.bin")
+	require.NoError(t, err, "missing contract artifact build/ASPRegistry.bin, run `make build-registry` to build it")
+	bytecode := common.FromHex(strings.TrimSpace(string(b)))
+
+	c := newTestChain(t)
+	registry, _, err := DeployRegistry(c.auth, c.client, bytecode, c.auth.From)
+	require.NoError(t, err)
+	c.backend.Commit()
+	c.mine(t, 5*time.Millisecond)
+
+	p := NewPublisher(testConfig(registry.Address), c.client, c.auth)
+	v := genVersion(1)
+	p.Enqueue(v)
+	require.NoError(t, p.flushAll(context.Background()))
+
+	for _, m := range asp.Sets {
+		set, _ := SetIndex(v.Roots[m])
+		version, err := registry.LatestVersion(nil, set)
+		require.NoError(t, err)
+		require.Equal(t, v.Version, version)
+
+		root, err := registry.LatestRoot(nil, set)
+		require.NoError(t, err)
+		require.Equal(t, 0, RootDigest(v.Roots[m].Root).Cmp(root))
+	}
+}
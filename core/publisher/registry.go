@@ -0,0 +1,83 @@
+package publisher
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var registryABI, _ = abi.JSON(strings.NewReader(ASPRegistryABI))
+
+// Root mirrors ASPRegistry.Root
+type Root struct {
+	Version uint64
+	Set     uint8
+	Root    *big.Int
+}
+
+// Registry is a binding to a deployed ASPRegistry contract
+type Registry struct {
+	Address  common.Address
+	contract *bind.BoundContract
+}
+
+func NewRegistry(address common.Address, backend bind.ContractBackend) *Registry {
+	return &Registry{
+		Address:  address,
+		contract: bind.NewBoundContract(address, registryABI, backend, backend, backend),
+	}
+}
+
+// DeployRegistry deploys the compiled ASPRegistry bytecode
+func DeployRegistry(auth *bind.TransactOpts, backend bind.ContractBackend, bytecode []byte, publisher common.Address) (*Registry, *types.Transaction, error) {
+	address, tx, contract, err := bind.DeployContract(auth, registryABI, bytecode, backend, publisher)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Registry{Address: address, contract: contract}, tx, nil
+}
+
+// PackPublishRoots returns the calldata of ASPRegistry.publishRoots
+func PackPublishRoots(roots []Root) ([]byte, error) {
+	return registryABI.Pack("publishRoots", roots)
+}
+
+// UnpackPublishRoots decodes calldata of ASPRegistry.publishRoots
+func UnpackPublishRoots(data []byte) ([]Root, error) {
+	method, err := registryABI.MethodById(data)
+	if err != nil {
+		return nil, err
+	}
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return nil, err
+	}
+	return *abi.ConvertType(args[0], new([]Root)).(*[]Root), nil
+}
+
+// PublishRoots sends a ASPRegistry.publishRoots transaction
+func (r *Registry) PublishRoots(opts *bind.TransactOpts, roots []Root) (*types.Transaction, error) {
+	return r.contract.Transact(opts, "publishRoots", roots)
+}
+
+// LatestRoot calls ASPRegistry.latestRoot
+func (r *Registry) LatestRoot(opts *bind.CallOpts, set uint8) (*big.Int, error) {
+	var out []interface{}
+	if err := r.contract.Call(opts, &out, "latestRoot", set); err != nil {
+		return nil, err
+	}
+	return *abi.ConvertType(out[0], new(*big.Int)).(**big.Int), nil
+}
+
+// LatestVersion calls ASPRegistry.latestVersion
+func (r *Registry) LatestVersion(opts *bind.CallOpts, set uint8) (uint64, error) {
+	var out []interface{}
+	if err := r.contract.Call(opts, &out, "latestVersion", set); err != nil {
+		return 0, err
+	}
+	return *abi.ConvertType(out[0], new(uint64)).(*uint64), nil
+}
@@ -0,0 +1,73 @@
+package rpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"github.com/0xBow-io/base-eas-asp/core/rpc/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// WriteServices are the services changing the state of the node,
+// the reports & change requests of remote aggregators & auditors
+var WriteServices = []string{
+	pb.ReportService_ServiceDesc.ServiceName,
+	pb.ChangeRequestService_ServiceDesc.ServiceName,
+}
+
+// RequireToken returns the interceptor rejecting the calls to the services
+// without the bearer token in their authorization metadata, the calls to
+// the other services are passed through
+func RequireToken(token string, services ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if protected(info.FullMethod, services) && !hasToken(ctx, token) {
+			return nil, status.Error(codes.Unauthenticated, "missing or invalid token")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// protected returns true if the method, /service/method, is of the services
+func protected(method string, services []string) bool {
+	service, _, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	for _, s := range services {
+		if s == service {
+			return true
+		}
+	}
+	return false
+}
+
+func hasToken(ctx context.Context, token string) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || token == "" {
+		return false
+	}
+	for _, v := range md.Get("authorization") {
+		if got, ok := strings.CutPrefix(v, "Bearer "); ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenCredentials sends the bearer token of RequireToken with every call,
+// use grpc.WithPerRPCCredentials to dial with it
+type TokenCredentials string
+
+func (t TokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity is false to reach the node over localhost,
+// use TLS transport credentials with a remote node
+func (t TokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+var _ credentials.PerRPCCredentials = TokenCredentials("")
@@ -0,0 +1,110 @@
+package rpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/0xBow-io/base-eas-asp/core/asp"
+	"github.com/0xBow-io/base-eas-asp/core/rpc/pb"
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	cr "github.com/0xBow-io/base-eas-asp/pkg/change_request"
+	nmt "github.com/0xBow-io/base-eas-asp/pkg/nmt"
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+	sDB "github.com/0xBow-io/base-eas-asp/pkg/statedb"
+	"github.com/ethereum/go-ethereum/common"
+	"google.golang.org/grpc"
+)
+
+const DefaultTimeout = 30 * time.Second
+
+/*
+Client calls the gRPC services with the repo types,
+it is an auditor.Verifier submitting change requests
+to a remote ChangeRequestServer.
+*/
+type Client struct {
+	reports     pb.ReportServiceClient
+	changes     pb.ChangeRequestServiceClient
+	memberships pb.MembershipServiceClient
+
+	// timeout of calls without a context
+	Timeout time.Duration
+}
+
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{
+		reports:     pb.NewReportServiceClient(conn),
+		changes:     pb.NewChangeRequestServiceClient(conn),
+		memberships: pb.NewMembershipServiceClient(conn),
+		Timeout:     DefaultTimeout,
+	}
+}
+
+// SubmitReport returns the EAS events parsed from the report by the server
+func (c *Client) SubmitReport(ctx context.Context, r reportDB.Report) ([]eas.EAS, error) {
+	resp, err := c.reports.SubmitReport(ctx, &pb.SubmitReportRequest{Report: ReportToPb(r)})
+	if err != nil {
+		return nil, err
+	}
+	events := make([]eas.EAS, len(resp.Events))
+	for i, e := range resp.Events {
+		if events[i], err = EASFromPb(e); err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+func (c *Client) SubmitChangeRequest(req cr.ChangeRequest) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+	_, err := c.SubmitChangeRequestCtx(ctx, req)
+	return err
+}
+
+// SubmitChangeRequestCtx returns the ID of the applied change request
+func (c *Client) SubmitChangeRequestCtx(ctx context.Context, req cr.ChangeRequest) (common.Hash, error) {
+	in, err := ChangeRequestToPb(req)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	resp, err := c.changes.SubmitChangeRequest(ctx, &pb.SubmitChangeRequestRequest{ChangeRequest: in})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(resp.Id), nil
+}
+
+// GetMembership returns the membership of the account
+// and whether it has any events
+func (c *Client) GetMembership(ctx context.Context, account common.Hash) (sDB.MEMBERSHIP_TYPE, bool, error) {
+	resp, err := c.memberships.GetMembership(ctx, &pb.GetMembershipRequest{Account: account.Bytes()})
+	if err != nil {
+		return "", false, err
+	}
+	m, err := MembershipFromPb(resp.Membership)
+	return m, resp.Known, err
+}
+
+// GetProof proves the account against set,
+// sDB.NONE proves it against its current membership
+func (c *Client) GetProof(ctx context.Context, account common.Hash, set sDB.MEMBERSHIP_TYPE) (asp.MembershipProof, nmt.Element, error) {
+	m, err := MembershipToPb(set)
+	if err != nil {
+		return asp.MembershipProof{}, nmt.Element{}, err
+	}
+	resp, err := c.memberships.GetProof(ctx, &pb.GetProofRequest{Account: account.Bytes(), Set: m})
+	if err != nil {
+		return asp.MembershipProof{}, nmt.Element{}, err
+	}
+	return MembershipProofFromPb(resp)
+}
+
+// GetRoot returns the roots of version, 0 for the latest version
+func (c *Client) GetRoot(ctx context.Context, version uint64) (asp.RootVersion, error) {
+	resp, err := c.memberships.GetRoot(ctx, &pb.GetRootRequest{Version: version})
+	if err != nil {
+		return asp.RootVersion{}, err
+	}
+	return RootVersionFromPb(resp)
+}
@@ -0,0 +1,261 @@
+package rpc
+
+import (
+	"unicode/utf8"
+
+	"github.com/0xBow-io/base-eas-asp/core/asp"
+	"github.com/0xBow-io/base-eas-asp/core/rpc/pb"
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	cr "github.com/0xBow-io/base-eas-asp/pkg/change_request"
+	nmt "github.com/0xBow-io/base-eas-asp/pkg/nmt"
+	poa "github.com/0xBow-io/base-eas-asp/pkg/proof-of-audit"
+	quiknode "github.com/0xBow-io/base-eas-asp/pkg/quiknode"
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+	sDB "github.com/0xBow-io/base-eas-asp/pkg/statedb"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrInvalidAccount    = errors.New("invalid account")
+	ErrInvalidMembership = errors.New("invalid membership")
+	ErrMissingField      = errors.New("missing field")
+)
+
+var (
+	membershipToPb = map[sDB.MEMBERSHIP_TYPE]pb.Membership{
+		sDB.NONE:              pb.Membership_MEMBERSHIP_NONE,
+		sDB.INCLUSION:         pb.Membership_MEMBERSHIP_INCLUSION,
+		sDB.EXCLUSION:         pb.Membership_MEMBERSHIP_EXCLUSION,
+		sDB.PARTIAL_INCLUSION: pb.Membership_MEMBERSHIP_PARTIAL_INCLUSION,
+	}
+	membershipFromPb = map[pb.Membership]sDB.MEMBERSHIP_TYPE{
+		pb.Membership_MEMBERSHIP_NONE:              sDB.NONE,
+		pb.Membership_MEMBERSHIP_INCLUSION:         sDB.INCLUSION,
+		pb.Membership_MEMBERSHIP_EXCLUSION:         sDB.EXCLUSION,
+		pb.Membership_MEMBERSHIP_PARTIAL_INCLUSION: sDB.PARTIAL_INCLUSION,
+	}
+)
+
+func MembershipToPb(m sDB.MEMBERSHIP_TYPE) (pb.Membership, error) {
+	if out, ok := membershipToPb[m]; ok {
+		return out, nil
+	}
+	return 0, errors.Wrap(ErrInvalidMembership, string(m))
+}
+
+func MembershipFromPb(m pb.Membership) (sDB.MEMBERSHIP_TYPE, error) {
+	if out, ok := membershipFromPb[m]; ok {
+		return out, nil
+	}
+	return "", errors.Wrap(ErrInvalidMembership, m.String())
+}
+
+// accountFromPb accepts 20 byte addresses & 32 byte namespaces
+func accountFromPb(b []byte) (common.Hash, error) {
+	if len(b) != common.AddressLength && len(b) != common.HashLength {
+		return common.Hash{}, errors.Wrapf(ErrInvalidAccount, "%d bytes", len(b))
+	}
+	return common.BytesToHash(b), nil
+}
+
+// ReportToPb converts the report, a body which is not valid UTF-8
+// is sent as raw bytes
+func ReportToPb(r reportDB.Report) *pb.Report {
+	out := &pb.Report{
+		Header: &pb.ReportHeader{
+			NotificationId:  r.Header.NotificationID,
+			ContentHash:     r.Header.ContentHash,
+			Nonce:           r.Header.Nonce,
+			Signature:       r.Header.Signature,
+			Timestamp:       r.Header.Timestamp,
+			ContentEncoding: r.Header.ContentEncoding,
+			Format:          string(r.Header.Format),
+			Source:          r.Header.Source,
+		},
+		Body: r.Body,
+	}
+	if !utf8.ValidString(r.Body) {
+		out.Body, out.RawBody = "", []byte(r.Body)
+	}
+	return out
+}
+
+func ReportFromPb(r *pb.Report) (reportDB.Report, error) {
+	if r == nil || r.Header == nil {
+		return reportDB.Report{}, errors.Wrap(ErrMissingField, "report header")
+	}
+	out := reportDB.Report{
+		Header: reportDB.ReportHeader{
+			NotificationID:  r.Header.NotificationId,
+			ContentHash:     r.Header.ContentHash,
+			Nonce:           r.Header.Nonce,
+			Signature:       r.Header.Signature,
+			Timestamp:       r.Header.Timestamp,
+			ContentEncoding: r.Header.ContentEncoding,
+			Format:          quiknode.Format(r.Header.Format),
+			Source:          r.Header.Source,
+		},
+		Body: r.Body,
+	}
+	if len(r.RawBody) > 0 {
+		out.Body = string(r.RawBody)
+	}
+	return out, nil
+}
+
+func EASToPb(e eas.EAS) *pb.EAS {
+	out := &pb.EAS{Uuid: e.UUID.Bytes(), Account: e.Account.Bytes(), ExpirationTime: e.ExpirationTime}
+	switch e.Type {
+	case eas.EAS_ATTEST:
+		out.Type = pb.EASType_EAS_TYPE_ATTEST
+	case eas.EAS_REVOKE:
+		out.Type = pb.EASType_EAS_TYPE_REVOKE
+	default:
+		out.Type = pb.EASType_EAS_TYPE_UNKNOWN
+	}
+	return out
+}
+
+func EASFromPb(e *pb.EAS) (eas.EAS, error) {
+	if e == nil {
+		return eas.EAS{}, errors.Wrap(ErrMissingField, "eas")
+	}
+	account, err := accountFromPb(e.Account)
+	if err != nil {
+		return eas.EAS{}, err
+	}
+	out := eas.EAS{UUID: common.BytesToHash(e.Uuid), Account: account, Type: eas.EAS_UNKNOWN, ExpirationTime: e.ExpirationTime}
+	switch e.Type {
+	case pb.EASType_EAS_TYPE_ATTEST:
+		out.Type = eas.EAS_ATTEST
+	case pb.EASType_EAS_TYPE_REVOKE:
+		out.Type = eas.EAS_REVOKE
+	}
+	return out, nil
+}
+
+func SP1ProofToPb(p poa.SP1Proof) *pb.SP1Proof {
+	return &pb.SP1Proof{
+		Proof:  p.Proof,
+		Stdin:  p.Stdin.Buffer.Data,
+		Stdout: p.Stdout.Buffer.Data,
+	}
+}
+
+func SP1ProofFromPb(p *pb.SP1Proof) poa.SP1Proof {
+	if p == nil {
+		return poa.SP1Proof{}
+	}
+	return poa.SP1Proof{
+		Proof:  p.Proof,
+		Stdin:  poa.StdIO{Buffer: poa.StdBuffer{Data: p.Stdin}},
+		Stdout: poa.StdIO{Buffer: poa.StdBuffer{Data: p.Stdout}},
+	}
+}
+
+func ChangeRequestToPb(c cr.ChangeRequest) (*pb.ChangeRequest, error) {
+	m, err := MembershipToPb(c.Membership)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ChangeRequest{
+		Namespace:  c.Ns,
+		Membership: m,
+		Proof:      SP1ProofToPb(c.Proof),
+	}, nil
+}
+
+func ChangeRequestFromPb(c *pb.ChangeRequest) (cr.ChangeRequest, error) {
+	if c == nil {
+		return cr.ChangeRequest{}, errors.Wrap(ErrMissingField, "change request")
+	}
+	m, err := MembershipFromPb(c.Membership)
+	if err != nil {
+		return cr.ChangeRequest{}, err
+	}
+	return cr.ChangeRequest{
+		Ns:         c.Namespace,
+		Membership: m,
+		Proof:      SP1ProofFromPb(c.Proof),
+	}, nil
+}
+
+func RootVersionToPb(v asp.RootVersion) (*pb.GetRootResponse, error) {
+	out := &pb.GetRootResponse{Version: v.Version, Ts: v.Ts}
+	for _, m := range asp.Sets {
+		root, ok := v.Roots[m]
+		if !ok {
+			continue
+		}
+		set, err := MembershipToPb(m)
+		if err != nil {
+			return nil, err
+		}
+		out.Roots = append(out.Roots, &pb.SetRoot{
+			Membership: set,
+			Root:       root.Root,
+			HashId:     uint32(root.HashID),
+			Size:       uint64(root.Size),
+		})
+	}
+	return out, nil
+}
+
+func RootVersionFromPb(v *pb.GetRootResponse) (asp.RootVersion, error) {
+	out := asp.RootVersion{Version: v.Version, Ts: v.Ts, Roots: make(map[sDB.MEMBERSHIP_TYPE]asp.SetRoot)}
+	for _, root := range v.Roots {
+		m, err := MembershipFromPb(root.Membership)
+		if err != nil {
+			return asp.RootVersion{}, err
+		}
+		setRoot := asp.SetRoot{Membership: m, HashID: nmt.HashID(root.HashId), Size: int(root.Size)}
+		if len(root.Root) > 0 {
+			setRoot.Root = nmt.Node(root.Root)
+		}
+		out.Roots[m] = setRoot
+	}
+	return out, nil
+}
+
+func MembershipProofToPb(p asp.MembershipProof, zeroValue nmt.Element) (*pb.GetProofResponse, error) {
+	m, err := MembershipToPb(p.Membership)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := p.Proof.MarshalBinary()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode proof")
+	}
+	return &pb.GetProofResponse{
+		Version:    p.Version,
+		Account:    p.Account.Bytes(),
+		Membership: m,
+		Root:       p.Root,
+		Proof:      proof,
+		ZeroValue:  zeroValue[:],
+	}, nil
+}
+
+// MembershipProofFromPb returns the proof & the zero value to verify it
+func MembershipProofFromPb(p *pb.GetProofResponse) (asp.MembershipProof, nmt.Element, error) {
+	account, err := accountFromPb(p.Account)
+	if err != nil {
+		return asp.MembershipProof{}, nmt.Element{}, err
+	}
+	m, err := MembershipFromPb(p.Membership)
+	if err != nil {
+		return asp.MembershipProof{}, nmt.Element{}, err
+	}
+	var proof nmt.Proof
+	if err := proof.UnmarshalBinary(p.Proof); err != nil {
+		return asp.MembershipProof{}, nmt.Element{}, errors.Wrap(err, "failed to decode proof")
+	}
+	return asp.MembershipProof{
+		Version:    p.Version,
+		Account:    account,
+		Membership: m,
+		Root:       nmt.Node(p.Root),
+		Proof:      proof,
+	}, nmt.ToElement(p.ZeroValue), nil
+}
@@ -0,0 +1,94 @@
+package rpc
+
+import (
+	"testing"
+
+	"github.com/0xBow-io/base-eas-asp/core/rpc/pb"
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	cr "github.com/0xBow-io/base-eas-asp/pkg/change_request"
+	mock "github.com/0xBow-io/base-eas-asp/pkg/mock"
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+	sDB "github.com/0xBow-io/base-eas-asp/pkg/statedb"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func Test_Convert_RoundTrip(t *testing.T) {
+	account := common.BytesToHash(mock.GenRandomHash(20))
+
+	report := genReport(t, account)
+	out, err := ReportFromPb(ReportToPb(report))
+	require.NoError(t, err)
+	require.Equal(t, report, out)
+
+	for _, typ := range []eas.EAS_TYPE{eas.EAS_ATTEST, eas.EAS_REVOKE, eas.EAS_UNKNOWN} {
+		e := eas.EAS{UUID: common.BytesToHash(mock.GenRandomHash(32)), Account: account, Type: typ}
+		out, err := EASFromPb(EASToPb(e))
+		require.NoError(t, err)
+		require.Equal(t, e, out)
+
+		e.ExpirationTime = 1700000000
+		out, err = EASFromPb(EASToPb(e))
+		require.NoError(t, err)
+		require.Equal(t, e, out)
+	}
+
+	for _, m := range []sDB.MEMBERSHIP_TYPE{sDB.NONE, sDB.INCLUSION, sDB.EXCLUSION, sDB.PARTIAL_INCLUSION} {
+		c := cr.ChangeRequest{Ns: account.Bytes(), Membership: m, Proof: testProof(account, eas.EAS_ATTEST)}
+		in, err := ChangeRequestToPb(c)
+		require.NoError(t, err)
+
+		// survives the wire encoding
+		b, err := proto.Marshal(in)
+		require.NoError(t, err)
+		decoded := new(pb.ChangeRequest)
+		require.NoError(t, proto.Unmarshal(b, decoded))
+
+		out, err := ChangeRequestFromPb(decoded)
+		require.NoError(t, err)
+		require.Equal(t, c, out)
+		require.Equal(t, c.ID(), out.ID())
+	}
+}
+
+func Test_Convert_Invalid(t *testing.T) {
+	_, err := ReportFromPb(nil)
+	require.ErrorIs(t, err, ErrMissingField)
+	_, err = ReportFromPb(&pb.Report{Body: "body"})
+	require.ErrorIs(t, err, ErrMissingField)
+
+	_, err = EASFromPb(nil)
+	require.ErrorIs(t, err, ErrMissingField)
+	_, err = EASFromPb(&pb.EAS{Account: []byte{1, 2, 3}})
+	require.ErrorIs(t, err, ErrInvalidAccount)
+
+	_, err = ChangeRequestFromPb(nil)
+	require.ErrorIs(t, err, ErrMissingField)
+	_, err = ChangeRequestFromPb(&pb.ChangeRequest{Membership: pb.Membership(42)})
+	require.ErrorIs(t, err, ErrInvalidMembership)
+	_, err = ChangeRequestToPb(cr.ChangeRequest{Membership: "bogus"})
+	require.ErrorIs(t, err, ErrInvalidMembership)
+
+	_, _, err = MembershipProofFromPb(&pb.GetProofResponse{Account: make([]byte, 32), Proof: []byte{0xff}})
+	require.Error(t, err)
+}
+
+func Test_Convert_CompressedReport(t *testing.T) {
+	report := genReport(t, common.BytesToHash(mock.GenRandomHash(20)))
+	body, err := reportDB.Encode(reportDB.EncodingBrotli, []byte(report.Body))
+	require.NoError(t, err)
+	report.Header.ContentEncoding = reportDB.EncodingBrotli
+	report.Body = body
+
+	in := ReportToPb(report)
+	require.Empty(t, in.Body)
+	b, err := proto.Marshal(in)
+	require.NoError(t, err)
+	decoded := new(pb.Report)
+	require.NoError(t, proto.Unmarshal(b, decoded))
+
+	out, err := ReportFromPb(decoded)
+	require.NoError(t, err)
+	require.Equal(t, report, out)
+}
@@ -0,0 +1,224 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/0xBow-io/base-eas-asp/core/asp"
+	"github.com/0xBow-io/base-eas-asp/core/rpc/pb"
+	"github.com/0xBow-io/base-eas-asp/core/verifier"
+	cr "github.com/0xBow-io/base-eas-asp/pkg/change_request"
+	nmt "github.com/0xBow-io/base-eas-asp/pkg/nmt"
+	poa "github.com/0xBow-io/base-eas-asp/pkg/proof-of-audit"
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+	sDB "github.com/0xBow-io/base-eas-asp/pkg/statedb"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type ReportDB interface {
+	Set(publicID string, ts int64, report reportDB.Report)
+}
+
+type Verifier interface {
+	SubmitChangeRequest(c cr.ChangeRequest) error
+}
+
+type StateDB interface {
+	NsExists(ns string) bool
+	GetMembership(ns string) sDB.MEMBERSHIP_TYPE
+}
+
+type ASP interface {
+	ZeroValue() nmt.Element
+	Latest() (asp.RootVersion, error)
+	Version(v uint64) (asp.RootVersion, error)
+	Prove(account string, m sDB.MEMBERSHIP_TYPE) (asp.MembershipProof, error)
+}
+
+// errorCode maps the errors of the components to gRPC codes
+func errorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, ErrInvalidAccount), errors.Is(err, ErrInvalidMembership),
+		errors.Is(err, ErrMissingField), errors.Is(err, asp.ErrInvalidMember),
+		errors.Is(err, asp.ErrUnknownSet), errors.Is(err, sDB.ErrInvalidMembership),
+		errors.Is(err, verifier.ErrInvalidProof):
+		return codes.InvalidArgument
+	case errors.Is(err, asp.ErrNoVersion), errors.Is(err, asp.ErrNotPublished),
+		errors.Is(err, asp.ErrEmptySet), errors.Is(err, verifier.ErrNsNotFound):
+		return codes.NotFound
+	case errors.Is(err, verifier.ErrDuplicateRequest):
+		return codes.AlreadyExists
+	case errors.Is(err, verifier.ErrConflictingRequest):
+		return codes.Aborted
+	case errors.Is(err, verifier.ErrNoChange), errors.Is(err, verifier.ErrPolicyMismatch):
+		return codes.FailedPrecondition
+	case errors.Is(err, ErrInvalidSignature):
+		return codes.Unauthenticated
+	default:
+		return codes.Internal
+	}
+}
+
+func toStatus(err error) error {
+	return status.Error(errorCode(err), err.Error())
+}
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// ReportServer stores the reports submitted by remote aggregators,
+// only webhook reports signed with the webhook secret are accepted
+type ReportServer struct {
+	pb.UnimplementedReportServiceServer
+	rDb     ReportDB
+	secret  string
+	urlPath string
+}
+
+func NewReportServer(rDb ReportDB, secret, urlPath string) *ReportServer {
+	return &ReportServer{rDb: rDb, secret: secret, urlPath: urlPath}
+}
+
+// verify the webhook signature of the report
+func (s *ReportServer) verify(r reportDB.Report) error {
+	h := r.Header
+	if !h.Signed() || s.secret == "" ||
+		!poa.VerifySignature(s.secret, s.urlPath, h.Nonce, h.Timestamp, r.Body, h.Signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (s *ReportServer) Register(r grpc.ServiceRegistrar) {
+	pb.RegisterReportServiceServer(r, s)
+}
+
+// SubmitReport stores the signed report for every account of its EAS events
+func (s *ReportServer) SubmitReport(ctx context.Context, req *pb.SubmitReportRequest) (*pb.SubmitReportResponse, error) {
+	report, err := ReportFromPb(req.Report)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if err := s.verify(report); err != nil {
+		return nil, toStatus(err)
+	}
+	events, ts, err := report.Parse()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, errors.Wrap(err, "failed to parse report").Error())
+	}
+
+	resp := &pb.SubmitReportResponse{Ts: ts}
+	for _, publicID := range reportDB.PublicIDs(events) {
+		s.rDb.Set(publicID, ts, report)
+	}
+	for _, e := range events {
+		resp.Events = append(resp.Events, EASToPb(e))
+	}
+	return resp, nil
+}
+
+// ChangeRequestServer submits the change requests of remote auditors to a Verifier
+type ChangeRequestServer struct {
+	pb.UnimplementedChangeRequestServiceServer
+	v Verifier
+}
+
+func NewChangeRequestServer(v Verifier) *ChangeRequestServer {
+	return &ChangeRequestServer{v: v}
+}
+
+func (s *ChangeRequestServer) Register(r grpc.ServiceRegistrar) {
+	pb.RegisterChangeRequestServiceServer(r, s)
+}
+
+func (s *ChangeRequestServer) SubmitChangeRequest(ctx context.Context, req *pb.SubmitChangeRequestRequest) (*pb.SubmitChangeRequestResponse, error) {
+	c, err := ChangeRequestFromPb(req.ChangeRequest)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if err := s.v.SubmitChangeRequest(c); err != nil {
+		return nil, toStatus(err)
+	}
+	id := c.ID()
+	return &pb.SubmitChangeRequestResponse{Id: id.Bytes()}, nil
+}
+
+// MembershipServer serves memberships, set roots & membership proofs
+type MembershipServer struct {
+	pb.UnimplementedMembershipServiceServer
+	stateDB StateDB
+	asp     ASP
+}
+
+func NewMembershipServer(stateDB StateDB, a ASP) *MembershipServer {
+	return &MembershipServer{stateDB: stateDB, asp: a}
+}
+
+func (s *MembershipServer) Register(r grpc.ServiceRegistrar) {
+	pb.RegisterMembershipServiceServer(r, s)
+}
+
+func (s *MembershipServer) GetMembership(ctx context.Context, req *pb.GetMembershipRequest) (*pb.GetMembershipResponse, error) {
+	account, err := accountFromPb(req.Account)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	m, err := MembershipToPb(s.stateDB.GetMembership(account.Hex()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.GetMembershipResponse{
+		Account:    account.Bytes(),
+		Membership: m,
+		Known:      s.stateDB.NsExists(account.Hex()),
+	}, nil
+}
+
+// GetProof proves the account against the requested set,
+// defaults to the current membership of the account
+func (s *MembershipServer) GetProof(ctx context.Context, req *pb.GetProofRequest) (*pb.GetProofResponse, error) {
+	account, err := accountFromPb(req.Account)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	set, err := MembershipFromPb(req.Set)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if set == sDB.NONE {
+		if set = s.stateDB.GetMembership(account.Hex()); set == sDB.NONE {
+			return nil, status.Error(codes.NotFound, "account has no membership")
+		}
+	}
+
+	proof, err := s.asp.Prove(account.Hex(), set)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp, err := MembershipProofToPb(proof, s.asp.ZeroValue())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return resp, nil
+}
+
+func (s *MembershipServer) GetRoot(ctx context.Context, req *pb.GetRootRequest) (*pb.GetRootResponse, error) {
+	var (
+		v   asp.RootVersion
+		err error
+	)
+	if req.Version > 0 {
+		v, err = s.asp.Version(req.Version)
+	} else {
+		v, err = s.asp.Latest()
+	}
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp, err := RootVersionToPb(v)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,287 @@
+package rpc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/0xBow-io/base-eas-asp/core/asp"
+	"github.com/0xBow-io/base-eas-asp/core/verifier"
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	cr "github.com/0xBow-io/base-eas-asp/pkg/change_request"
+	mock "github.com/0xBow-io/base-eas-asp/pkg/mock"
+	nmt "github.com/0xBow-io/base-eas-asp/pkg/nmt"
+	pp "github.com/0xBow-io/base-eas-asp/pkg/privacy_pool"
+	poa "github.com/0xBow-io/base-eas-asp/pkg/proof-of-audit"
+	reportDB "github.com/0xBow-io/base-eas-asp/pkg/reportDB"
+	sDB "github.com/0xBow-io/base-eas-asp/pkg/statedb"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// genReport returns a report with a single EAS attestation for account
+func genReport(t *testing.T, account common.Hash) reportDB.Report {
+	log := map[string]interface{}{
+		"address":         eas.BASE_EAS_ADDR,
+		"topics":          []string{eas.COINBASE_EAS_ATTEST_TOPIC, account.Hex(), eas.COINBASE_EAS_HASH, eas.COINBASE_EAS_SCHEMA_ID},
+		"data":            common.BytesToHash(mock.GenRandomHash(32)).Hex(),
+		"blockNumber":     "0xb2bbad",
+		"transactionHash": common.BytesToHash(mock.GenRandomHash(32)).Hex(),
+		"logIndex":        "0x1",
+		"removed":         false,
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"matchedReceipts":     []interface{}{map[string]interface{}{"logs": []interface{}{log}}},
+		"matchedTransactions": []interface{}{map[string]interface{}{"chainId": eas.BASE_CHAIN_ID}},
+	})
+	require.NoError(t, err)
+
+	r := reportDB.Report{
+		Header: reportDB.ReportHeader{
+			NotificationID: "id",
+			ContentHash:    "hash",
+			Nonce:          "nonce",
+			Timestamp:      time.Now().Format(reportDB.Header_Time_Layout),
+		},
+		Body: string(body),
+	}
+	r.Header.Signature = sign(r)
+	return r
+}
+
+const (
+	testSecret  = "secret"
+	testURLPath = "/webhook"
+	testToken   = "token"
+)
+
+// sign returns the webhook signature of the report
+func sign(r reportDB.Report) string {
+	bodyHash := sha256.Sum256([]byte(testURLPath + r.Body))
+	h := hmac.New(sha256.New, []byte(testSecret))
+	h.Write([]byte(r.Header.Nonce + hex.EncodeToString(bodyHash[:]) + r.Header.Timestamp))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// testProof returns a proof of an event of the account with the type
+func testProof(account common.Hash, t eas.EAS_TYPE) poa.SP1Proof {
+	e := eas.EAS{UUID: common.BytesToHash(mock.GenRandomHash(32)), Account: account, Type: t}
+	return poa.SP1Proof{
+		Proof:  "proof",
+		Stdin:  poa.StdIO{Buffer: poa.StdBuffer{Data: []byte{1, 2}}},
+		Stdout: poa.StdIO{Buffer: poa.StdBuffer{Data: poa.EventPublicValues(e).Encode()}},
+	}
+}
+
+type testEnv struct {
+	client *Client
+	// dials a client of the server
+	dial     func(opts ...grpc.DialOption) *Client
+	sdb      *sDB.StateDB
+	rdb      *reportDB.ReportDB
+	asp      *asp.ASP
+	v        *verifier.Verifier
+	accounts []common.Hash
+}
+
+// setup serves every service over bufconn,
+// the accounts have no membership
+func setup(t *testing.T, n int) *testEnv {
+	sdb, err := sDB.NewStateDB()
+	require.NoError(t, err)
+
+	env := &testEnv{sdb: sdb, rdb: reportDB.NewReportDB()}
+	for i := 0; i < n; i++ {
+		account := common.BytesToHash(mock.GenRandomHash(20))
+		for j := 0; j < 2; j++ {
+			e := pp.Event{TxHash: common.BytesToHash(mock.GenRandomHash(31)), From: account}
+			se, err := e.Serialize()
+			require.NoError(t, err)
+			require.NoError(t, sdb.AddEvent(se))
+		}
+		env.accounts = append(env.accounts, account)
+	}
+	env.asp = asp.NewASP(sdb, nmt.NsPoseidonHasher, asp.DefaultZeroValue())
+	// the tests don't run the SP1 verifier
+	env.v, err = verifier.NewVerifier(sdb, verifier.ProofVerifierFunc(poa.CheckProof))
+	require.NoError(t, err)
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(grpc.UnaryInterceptor(RequireToken(testToken, WriteServices...)))
+	NewReportServer(env.rdb, testSecret, testURLPath).Register(srv)
+	NewChangeRequestServer(env.v).Register(srv)
+	NewMembershipServer(sdb, env.asp).Register(srv)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	env.dial = func(opts ...grpc.DialOption) *Client {
+		conn, err := grpc.DialContext(context.Background(), "bufnet", append([]grpc.DialOption{
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		}, opts...)...)
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		return NewClient(conn)
+	}
+	env.client = env.dial(grpc.WithPerRPCCredentials(TokenCredentials(testToken)))
+	return env
+}
+
+func requireCode(t *testing.T, code codes.Code, err error) {
+	require.Error(t, err)
+	require.Equal(t, code, status.Code(err), err.Error())
+}
+
+func Test_ReportServer_SubmitReport(t *testing.T) {
+	env := setup(t, 1)
+	ctx := context.Background()
+
+	report := genReport(t, env.accounts[0])
+	events, err := env.client.SubmitReport(ctx, report)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, env.accounts[0], events[0].Account)
+	require.Equal(t, eas.EAS_ATTEST, events[0].Type)
+	require.Equal(t, report, env.rdb.Get(env.accounts[0].Hex()))
+
+	// the signature must match the body
+	report.Body = ""
+	_, err = env.client.SubmitReport(ctx, report)
+	requireCode(t, codes.Unauthenticated, err)
+	report.Header.Signature = sign(report)
+	_, err = env.client.SubmitReport(ctx, report)
+	requireCode(t, codes.InvalidArgument, err)
+
+	// & be a webhook signature
+	report = genReport(t, env.accounts[0])
+	report.Header.Source = reportDB.SourceRPC
+	_, err = env.client.SubmitReport(ctx, report)
+	requireCode(t, codes.Unauthenticated, err)
+}
+
+func Test_Server_Token(t *testing.T) {
+	env := setup(t, 1)
+	ctx := context.Background()
+	report := genReport(t, env.accounts[0])
+	c := cr.ChangeRequest{Ns: env.accounts[0].Bytes(), Membership: sDB.INCLUSION, Proof: testProof(env.accounts[0], eas.EAS_ATTEST)}
+
+	for _, client := range []*Client{env.dial(), env.dial(grpc.WithPerRPCCredentials(TokenCredentials("other")))} {
+		_, err := client.SubmitReport(ctx, report)
+		requireCode(t, codes.Unauthenticated, err)
+		requireCode(t, codes.Unauthenticated, client.SubmitChangeRequest(c))
+
+		// the membership service is public
+		_, _, err = client.GetMembership(ctx, env.accounts[0])
+		require.NoError(t, err)
+	}
+	require.Equal(t, reportDB.Report{}, env.rdb.Get(env.accounts[0].Hex()))
+	require.Equal(t, sDB.NONE, env.sdb.GetMembership(env.accounts[0].Hex()))
+}
+
+func Test_ChangeRequestServer_SubmitChangeRequest(t *testing.T) {
+	env := setup(t, 2)
+	ctx := context.Background()
+
+	c := cr.ChangeRequest{Ns: env.accounts[0].Bytes(), Membership: sDB.INCLUSION, Proof: testProof(env.accounts[0], eas.EAS_ATTEST)}
+	id, err := env.client.SubmitChangeRequestCtx(ctx, c)
+	require.NoError(t, err)
+	require.Equal(t, c.ID(), id)
+	require.Equal(t, sDB.INCLUSION, env.sdb.GetMembership(env.accounts[0].Hex()))
+
+	o, ok := env.v.Outcome(id)
+	require.True(t, ok)
+	require.Equal(t, verifier.APPLIED, o.Status)
+
+	// the client is an auditor.Verifier
+	requireCode(t, codes.AlreadyExists, env.client.SubmitChangeRequest(c))
+
+	// the attestation doesn't prove the exclusion
+	c.Membership = sDB.EXCLUSION
+	requireCode(t, codes.InvalidArgument, env.client.SubmitChangeRequest(c))
+	c.Proof.Stdout.Buffer.Data = []byte{0}
+	requireCode(t, codes.InvalidArgument, env.client.SubmitChangeRequest(c))
+
+	c = cr.ChangeRequest{Ns: env.accounts[1].Bytes(), Membership: sDB.NONE, Proof: testProof(env.accounts[1], eas.EAS_ATTEST)}
+	requireCode(t, codes.InvalidArgument, env.client.SubmitChangeRequest(c))
+
+	account := common.BytesToHash(mock.GenRandomHash(20))
+	c = cr.ChangeRequest{Ns: account.Bytes(), Membership: sDB.INCLUSION, Proof: testProof(account, eas.EAS_ATTEST)}
+	requireCode(t, codes.NotFound, env.client.SubmitChangeRequest(c))
+
+	c = cr.ChangeRequest{Ns: env.accounts[0].Bytes(), Membership: sDB.INCLUSION, Proof: testProof(env.accounts[0], eas.EAS_ATTEST)}
+	requireCode(t, codes.FailedPrecondition, env.client.SubmitChangeRequest(c))
+}
+
+func Test_MembershipServer(t *testing.T) {
+	env := setup(t, 3)
+	ctx := context.Background()
+	included, excluded, unknown := env.accounts[0], env.accounts[1], env.accounts[2]
+
+	require.NoError(t, env.sdb.CompareAndSetMembership(included.Hex(), sDB.NONE, sDB.INCLUSION))
+	require.NoError(t, env.sdb.CompareAndSetMembership(excluded.Hex(), sDB.NONE, sDB.EXCLUSION))
+
+	m, known, err := env.client.GetMembership(ctx, included)
+	require.NoError(t, err)
+	require.Equal(t, sDB.INCLUSION, m)
+	require.True(t, known)
+
+	m, known, err = env.client.GetMembership(ctx, common.BytesToHash(mock.GenRandomHash(20)))
+	require.NoError(t, err)
+	require.Equal(t, sDB.NONE, m)
+	require.False(t, known)
+
+	_, err = env.client.memberships.GetMembership(ctx, nil)
+	requireCode(t, codes.InvalidArgument, err)
+
+	// nothing published yet
+	_, err = env.client.GetRoot(ctx, 0)
+	requireCode(t, codes.NotFound, err)
+	_, _, err = env.client.GetProof(ctx, included, sDB.NONE)
+	requireCode(t, codes.NotFound, err)
+
+	v1, err := env.asp.Publish()
+	require.NoError(t, err)
+
+	v, err := env.client.GetRoot(ctx, 0)
+	require.NoError(t, err)
+	require.Equal(t, v1.Version, v.Version)
+	require.Equal(t, v1.Ts, v.Ts)
+	for _, set := range asp.Sets {
+		require.True(t, v1.Roots[set].Root.Equal(v.Roots[set].Root))
+		require.Equal(t, v1.Roots[set].Size, v.Roots[set].Size)
+		require.Equal(t, v1.Roots[set].HashID, v.Roots[set].HashID)
+	}
+	require.Nil(t, v.Roots[sDB.PARTIAL_INCLUSION].Root)
+
+	_, err = env.client.GetRoot(ctx, 2)
+	requireCode(t, codes.NotFound, err)
+
+	p, zero, err := env.client.GetProof(ctx, included, sDB.NONE)
+	require.NoError(t, err)
+	require.Equal(t, sDB.INCLUSION, p.Membership)
+	require.Equal(t, included, p.Account)
+	require.Equal(t, env.asp.ZeroValue(), zero)
+	require.False(t, p.Proof.IsAbsenceProof())
+	require.NoError(t, p.Verify(zero))
+
+	p, zero, err = env.client.GetProof(ctx, included, sDB.EXCLUSION)
+	require.NoError(t, err)
+	require.Equal(t, sDB.EXCLUSION, p.Membership)
+	require.NoError(t, p.Verify(zero))
+
+	_, _, err = env.client.GetProof(ctx, unknown, sDB.NONE)
+	requireCode(t, codes.NotFound, err)
+	_, _, err = env.client.GetProof(ctx, included, sDB.PARTIAL_INCLUSION)
+	requireCode(t, codes.NotFound, err)
+}
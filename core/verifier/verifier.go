@@ -0,0 +1,297 @@
+package verifier
+
+import (
+	"sync"
+	"time"
+
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	cr "github.com/0xBow-io/base-eas-asp/pkg/change_request"
+	poa "github.com/0xBow-io/base-eas-asp/pkg/proof-of-audit"
+	sDB "github.com/0xBow-io/base-eas-asp/pkg/statedb"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrDuplicateRequest   = errors.New("duplicate change request")
+	ErrConflictingRequest = errors.New("conflicting change request")
+	ErrNsNotFound         = errors.New("namespace not found")
+	ErrNoChange           = errors.New("membership is already set")
+	ErrInvalidProof       = errors.New("invalid proof")
+	ErrNoProofVerifier    = errors.New("missing proof verifier")
+	ErrPolicyMismatch     = errors.New("membership not derived by the policy")
+)
+
+type StateDB interface {
+	NsExists(ns string) bool
+	GetMembership(ns string) sDB.MEMBERSHIP_TYPE
+	CompareAndSetMembership(ns string, expected, membership sDB.MEMBERSHIP_TYPE) error
+}
+
+// ProofVerifier checks the SP1 proof of a change request,
+// poa.VerifyProof runs the SP1 verifier
+type ProofVerifier interface {
+	VerifyProof(p poa.SP1Proof) error
+}
+
+type ProofVerifierFunc func(p poa.SP1Proof) error
+
+func (f ProofVerifierFunc) VerifyProof(p poa.SP1Proof) error {
+	return f(p)
+}
+
+type STATUS string
+
+const (
+	APPLIED  = STATUS("applied")
+	REJECTED = STATUS("rejected")
+)
+
+// Outcome records how a change request was handled
+type Outcome struct {
+	ID     common.Hash         `json:"id"`
+	Ns     string              `json:"nameSpace"`
+	From   sDB.MEMBERSHIP_TYPE `json:"from"`
+	To     sDB.MEMBERSHIP_TYPE `json:"to"`
+	Status STATUS              `json:"status"`
+	Reason string              `json:"reason,omitempty"`
+	// cause of a change applied without proof of audit, e.g. an expiry
+	Cause string `json:"cause,omitempty"`
+	Ts    int64  `json:"ts"`
+}
+
+/*
+Verifier is an in-process auditor.Verifier
+
+A change request is applied when:
+  - it has not been submitted before
+  - no other request for the same namespace is being verified
+  - its proof is valid & its public values commit to the namespace
+    and to an EAS event deriving the requested membership, with the other
+    attestations of the account when the verifier has a policy
+  - the namespace exists in the StateDB
+  - the membership of the namespace is unchanged while the request was verified
+
+Applied requests & requests rejected for a final reason (invalid membership
+or proof) have their outcome recorded, a request rejected for a temporary
+reason (unknown namespace, unchanged membership, conflict, policy mismatch)
+can be resubmitted.
+
+SubmitExpiry is the trusted path of the in-process auditor: the downgrade
+of an account which attestation expired is applied without proof of audit,
+the auditor checked the expiration on chain. It isn't exposed to the
+change request clients.
+*/
+type Verifier struct {
+	sDB StateDB
+	pv  ProofVerifier
+	// derives the membership of the proven events, EasTypeToMembership if nil
+	policy   *eas.Policy
+	evidence eas.EvidenceSource
+
+	mut      sync.RWMutex
+	outcomes map[common.Hash]Outcome
+	order    []common.Hash
+	// namespaces with a request being verified
+	pending map[string]common.Hash
+}
+
+// VerifierConfig of a Verifier
+type VerifierConfig struct {
+	// derives the membership of the account of a proven event with its
+	// attestations, the membership of the event type if nil
+	Policy *eas.Policy
+	// attestations of the EvidenceSchemas of Policy, nil if Policy has none
+	Evidence eas.EvidenceSource
+}
+
+func NewVerifier(sDB StateDB, pv ProofVerifier) (*Verifier, error) {
+	return NewVerifierWithConfig(sDB, pv, VerifierConfig{})
+}
+
+// NewVerifierWithConfig checks the requested memberships against the policy
+func NewVerifierWithConfig(sDB StateDB, pv ProofVerifier, cfg VerifierConfig) (*Verifier, error) {
+	if pv == nil {
+		return nil, ErrNoProofVerifier
+	}
+	return &Verifier{
+		sDB:      sDB,
+		pv:       pv,
+		policy:   cfg.Policy,
+		evidence: cfg.Evidence,
+		outcomes: make(map[common.Hash]Outcome),
+		pending:  make(map[string]common.Hash),
+	}, nil
+}
+
+// reserve marks the namespace of the request as pending
+func (v *Verifier) reserve(id common.Hash, ns string) error {
+	v.mut.Lock()
+	defer v.mut.Unlock()
+
+	if _, ok := v.outcomes[id]; ok {
+		return errors.Wrap(ErrDuplicateRequest, id.Hex())
+	}
+	if other, ok := v.pending[ns]; ok {
+		if other == id {
+			return errors.Wrap(ErrDuplicateRequest, id.Hex())
+		}
+		return errors.Wrapf(ErrConflictingRequest, "request %s is pending for %s", other.Hex(), ns)
+	}
+	v.pending[ns] = id
+	return nil
+}
+
+func (v *Verifier) record(o Outcome) {
+	v.mut.Lock()
+	defer v.mut.Unlock()
+
+	delete(v.pending, o.Ns)
+	o.Ts = time.Now().Unix()
+	v.outcomes[o.ID] = o
+	v.order = append(v.order, o.ID)
+}
+
+// release the namespace of a request which outcome isn't recorded
+func (v *Verifier) release(ns string) {
+	v.mut.Lock()
+	defer v.mut.Unlock()
+	delete(v.pending, ns)
+}
+
+// final returns true if a request rejected with err
+// would be rejected again if resubmitted
+func final(err error) bool {
+	return errors.Is(err, ErrInvalidProof) || errors.Is(err, sDB.ErrInvalidMembership)
+}
+
+func (v *Verifier) SubmitChangeRequest(c cr.ChangeRequest) error {
+	o := Outcome{ID: c.ID(), Ns: sDB.NsKey(c.Ns), To: c.Membership}
+	return v.submit(o, func(sDB.MEMBERSHIP_TYPE) error {
+		if err := v.pv.VerifyProof(c.Proof); err != nil {
+			return errors.Wrap(ErrInvalidProof, err.Error())
+		}
+		return v.bind(c)
+	})
+}
+
+// SubmitExpiry downgrades the namespace ns to membership without proof of
+// audit, the attestation uid which included it expired. The outcome
+// records the expiry as its cause.
+func (v *Verifier) SubmitExpiry(ns []byte, membership sDB.MEMBERSHIP_TYPE, uid common.Hash) error {
+	o := Outcome{
+		ID:    crypto.Keccak256Hash([]byte("expiry"), ns, []byte(membership), uid.Bytes()),
+		Ns:    sDB.NsKey(ns),
+		To:    membership,
+		Cause: "attestation " + uid.Hex() + " expired",
+	}
+	return v.submit(o, func(from sDB.MEMBERSHIP_TYPE) error {
+		if from != sDB.INCLUSION {
+			return errors.Wrapf(ErrNoChange, "%s is %s, only an inclusion expires", o.Ns, from)
+		}
+		return nil
+	})
+}
+
+// submit applies the change of the outcome o once check passed for
+// the current membership & records its outcome
+func (v *Verifier) submit(o Outcome, check func(from sDB.MEMBERSHIP_TYPE) error) error {
+	if err := v.reserve(o.ID, o.Ns); err != nil {
+		return err
+	}
+
+	err := v.apply(&o, check)
+	switch {
+	case err == nil:
+		o.Status = APPLIED
+	case final(err):
+		o.Status, o.Reason = REJECTED, err.Error()
+	default:
+		v.release(o.Ns)
+		return err
+	}
+	v.record(o)
+	return err
+}
+
+// apply the membership of the outcome once check passed
+func (v *Verifier) apply(o *Outcome, check func(from sDB.MEMBERSHIP_TYPE) error) error {
+	if !o.To.IsValid() {
+		return errors.Wrap(sDB.ErrInvalidMembership, string(o.To))
+	}
+	if !v.sDB.NsExists(o.Ns) {
+		return errors.Wrap(ErrNsNotFound, o.Ns)
+	}
+
+	o.From = v.sDB.GetMembership(o.Ns)
+	if o.From == o.To {
+		return errors.Wrap(ErrNoChange, string(o.To))
+	}
+
+	if err := check(o.From); err != nil {
+		return err
+	}
+
+	// membership could have been changed outside of the verifier
+	if err := v.sDB.CompareAndSetMembership(o.Ns, o.From, o.To); err != nil {
+		if errors.Is(err, sDB.ErrMembershipMismatch) {
+			return errors.Wrap(ErrConflictingRequest, err.Error())
+		}
+		return errors.Wrap(err, "failed to apply membership")
+	}
+	return nil
+}
+
+// bind checks that the public values of the proof commit to
+// the namespace & to an event deriving the membership of c.
+// With a policy the membership is derived from the event & the other
+// attestations of the account, which may change so a mismatch isn't final.
+func (v *Verifier) bind(c cr.ChangeRequest) error {
+	pv, err := c.Proof.PublicValues()
+	if err != nil {
+		return errors.Wrap(ErrInvalidProof, err.Error())
+	}
+	if pv.Account() != common.BytesToHash(c.Ns) {
+		return errors.Wrapf(ErrInvalidProof, "proof commits to account %s", pv.PublicID)
+	}
+	if v.policy == nil {
+		if m := eas.EasTypeToMembership(pv.Type()); m != c.Membership {
+			return errors.Wrapf(ErrInvalidProof, "proof commits to %s event %s deriving %s", pv.Type(), pv.CommitmentID, m)
+		}
+		return nil
+	}
+
+	if pv.Type() == eas.EAS_UNKNOWN {
+		return errors.Wrapf(ErrInvalidProof, "proof commits to no event")
+	}
+	e := eas.EAS{UUID: pv.UUID(), Account: pv.Account(), Type: pv.Type()}
+	d, err := v.policy.Derive(e.Account, []eas.EAS{e}, v.evidence, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+	if d.Membership != c.Membership {
+		return errors.Wrapf(ErrPolicyMismatch, "%s event %s derives %s by rule %q", pv.Type(), pv.CommitmentID, d.Membership, d.Rule)
+	}
+	return nil
+}
+
+// Outcome returns the outcome of the change request with the given ID
+func (v *Verifier) Outcome(id common.Hash) (Outcome, bool) {
+	v.mut.RLock()
+	defer v.mut.RUnlock()
+	o, ok := v.outcomes[id]
+	return o, ok
+}
+
+// Outcomes returns the outcomes of all change requests in submission order
+func (v *Verifier) Outcomes() []Outcome {
+	v.mut.RLock()
+	defer v.mut.RUnlock()
+
+	out := make([]Outcome, len(v.order))
+	for i, id := range v.order {
+		out[i] = v.outcomes[id]
+	}
+	return out
+}
@@ -0,0 +1,303 @@
+package verifier
+
+import (
+	"sync"
+	"testing"
+
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	cr "github.com/0xBow-io/base-eas-asp/pkg/change_request"
+	mock "github.com/0xBow-io/base-eas-asp/pkg/mock"
+	pp "github.com/0xBow-io/base-eas-asp/pkg/privacy_pool"
+	poa "github.com/0xBow-io/base-eas-asp/pkg/proof-of-audit"
+	sDB "github.com/0xBow-io/base-eas-asp/pkg/statedb"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// testProof returns a proof of an event of the account with the type
+func testProof(p string, account common.Hash, t eas.EAS_TYPE) poa.SP1Proof {
+	e := eas.EAS{UUID: common.BytesToHash(mock.GenRandomHash(32)), Account: account, Type: t}
+	return poa.SP1Proof{
+		Proof:  p,
+		Stdout: poa.StdIO{Buffer: poa.StdBuffer{Data: poa.EventPublicValues(e).Encode()}},
+	}
+}
+
+// checkProof only checks the public values, the tests don't run the SP1 verifier
+var checkProof = ProofVerifierFunc(poa.CheckProof)
+
+func testVerifier(t *testing.T, db StateDB, pv ProofVerifier) *Verifier {
+	v, err := NewVerifier(db, pv)
+	require.NoError(t, err)
+	return v
+}
+
+func testStateDB(t *testing.T, accounts ...common.Hash) *sDB.StateDB {
+	db, err := sDB.NewStateDB()
+	require.NoError(t, err)
+	for _, account := range accounts {
+		e := pp.Event{
+			TxHash: common.BytesToHash(mock.GenRandomHash(31)),
+			From:   account,
+			To:     common.BytesToHash(mock.GenRandomHash(20)),
+		}
+		se, err := e.Serialize()
+		require.NoError(t, err)
+		require.NoError(t, db.AddEvent(se))
+	}
+	return db
+}
+
+func Test_Verifier_Apply(t *testing.T) {
+	account := common.BytesToHash(mock.GenRandomHash(20))
+	db := testStateDB(t, account)
+	v := testVerifier(t, db, checkProof)
+
+	_, err := NewVerifier(db, nil)
+	require.ErrorIs(t, err, ErrNoProofVerifier)
+
+	req := cr.ChangeRequest{Ns: account.Bytes(), Membership: sDB.INCLUSION, Proof: testProof("proof-1", account, eas.EAS_ATTEST)}
+	require.NoError(t, v.SubmitChangeRequest(req))
+	require.Equal(t, sDB.INCLUSION, db.GetMembership(account.Hex()))
+
+	o, ok := v.Outcome(req.ID())
+	require.True(t, ok)
+	require.Equal(t, APPLIED, o.Status)
+	require.Equal(t, sDB.NONE, o.From)
+	require.Equal(t, sDB.INCLUSION, o.To)
+
+	// same request again
+	require.ErrorIs(t, v.SubmitChangeRequest(req), ErrDuplicateRequest)
+
+	// membership is already inclusion, the rejection isn't recorded
+	same := cr.ChangeRequest{Ns: account.Bytes(), Membership: sDB.INCLUSION, Proof: testProof("proof-2", account, eas.EAS_ATTEST)}
+	require.ErrorIs(t, v.SubmitChangeRequest(same), ErrNoChange)
+	_, ok = v.Outcome(same.ID())
+	require.False(t, ok)
+
+	// downgrade
+	revoke := cr.ChangeRequest{Ns: account.Bytes(), Membership: sDB.EXCLUSION, Proof: testProof("proof-3", account, eas.EAS_REVOKE)}
+	require.NoError(t, v.SubmitChangeRequest(revoke))
+	require.Equal(t, sDB.EXCLUSION, db.GetMembership(account.Hex()))
+
+	// the earlier request can now be applied
+	require.NoError(t, v.SubmitChangeRequest(same))
+	require.Equal(t, sDB.INCLUSION, db.GetMembership(account.Hex()))
+
+	outcomes := v.Outcomes()
+	require.Len(t, outcomes, 3)
+	require.Equal(t, []STATUS{APPLIED, APPLIED, APPLIED}, []STATUS{outcomes[0].Status, outcomes[1].Status, outcomes[2].Status})
+}
+
+func Test_Verifier_Reject(t *testing.T) {
+	account := common.BytesToHash(mock.GenRandomHash(20))
+	other := common.BytesToHash(mock.GenRandomHash(20))
+	db := testStateDB(t, account)
+	v := testVerifier(t, db, checkProof)
+
+	notCommitted := testProof("b", account, eas.EAS_ATTEST)
+	notCommitted.Stdout.Buffer.Data = poa.PublicValues{PublicID: account.Hex()}.Encode()
+
+	for name, tc := range map[string]struct {
+		req cr.ChangeRequest
+		err error
+	}{
+		"empty proof": {
+			req: cr.ChangeRequest{Ns: account.Bytes(), Membership: sDB.INCLUSION, Proof: testProof("", account, eas.EAS_ATTEST)},
+			err: ErrInvalidProof,
+		},
+		"not committed": {
+			req: cr.ChangeRequest{Ns: account.Bytes(), Membership: sDB.INCLUSION, Proof: notCommitted},
+			err: ErrInvalidProof,
+		},
+		"bool output": {
+			req: cr.ChangeRequest{Ns: account.Bytes(), Membership: sDB.INCLUSION, Proof: poa.SP1Proof{Proof: "c", Stdout: poa.StdIO{Buffer: poa.StdBuffer{Data: []byte{1}}}}},
+			err: ErrInvalidProof,
+		},
+		"proof of another account": {
+			req: cr.ChangeRequest{Ns: account.Bytes(), Membership: sDB.INCLUSION, Proof: testProof("d", other, eas.EAS_ATTEST)},
+			err: ErrInvalidProof,
+		},
+		"proof of another event": {
+			req: cr.ChangeRequest{Ns: account.Bytes(), Membership: sDB.INCLUSION, Proof: testProof("e", account, eas.EAS_REVOKE)},
+			err: ErrInvalidProof,
+		},
+		"invalid membership": {
+			req: cr.ChangeRequest{Ns: account.Bytes(), Membership: sDB.NONE, Proof: testProof("f", account, eas.EAS_ATTEST)},
+			err: sDB.ErrInvalidMembership,
+		},
+	} {
+		err := v.SubmitChangeRequest(tc.req)
+		require.ErrorIs(t, err, tc.err, name)
+
+		o, ok := v.Outcome(tc.req.ID())
+		require.True(t, ok, name)
+		require.Equal(t, REJECTED, o.Status, name)
+		require.NotEmpty(t, o.Reason, name)
+
+		// final rejections are duplicates when resubmitted
+		require.ErrorIs(t, v.SubmitChangeRequest(tc.req), ErrDuplicateRequest, name)
+	}
+	require.Equal(t, sDB.NONE, db.GetMembership(account.Hex()))
+}
+
+func Test_Verifier_SubmitExpiry(t *testing.T) {
+	account := common.BytesToHash(mock.GenRandomHash(20))
+	db := testStateDB(t, account)
+	v := testVerifier(t, db, checkProof)
+	uid := common.BytesToHash(mock.GenRandomHash(32))
+
+	// only an inclusion expires
+	require.ErrorIs(t, v.SubmitExpiry(account.Bytes(), sDB.EXCLUSION, uid), ErrNoChange)
+	include := cr.ChangeRequest{Ns: account.Bytes(), Membership: sDB.INCLUSION, Proof: testProof("proof", account, eas.EAS_ATTEST)}
+	require.NoError(t, v.SubmitChangeRequest(include))
+
+	require.ErrorIs(t, v.SubmitExpiry(account.Bytes(), sDB.INCLUSION, uid), ErrNoChange)
+
+	require.NoError(t, v.SubmitExpiry(account.Bytes(), sDB.PARTIAL_INCLUSION, uid))
+	require.Equal(t, sDB.PARTIAL_INCLUSION, db.GetMembership(account.Hex()))
+	require.ErrorIs(t, v.SubmitExpiry(account.Bytes(), sDB.PARTIAL_INCLUSION, uid), ErrDuplicateRequest)
+
+	// the temporary rejections aren't recorded
+	outcomes := v.Outcomes()
+	require.Len(t, outcomes, 2)
+	o := outcomes[1]
+	require.Equal(t, APPLIED, o.Status)
+	require.Equal(t, sDB.INCLUSION, o.From)
+	require.Equal(t, "attestation "+uid.Hex()+" expired", o.Cause)
+}
+
+func Test_Verifier_Reject_Temporary(t *testing.T) {
+	account := common.BytesToHash(mock.GenRandomHash(20))
+	db := testStateDB(t)
+	v := testVerifier(t, db, checkProof)
+
+	// the namespace isn't known yet
+	req := cr.ChangeRequest{Ns: account.Bytes(), Membership: sDB.INCLUSION, Proof: testProof("a", account, eas.EAS_ATTEST)}
+	require.ErrorIs(t, v.SubmitChangeRequest(req), ErrNsNotFound)
+	_, ok := v.Outcome(req.ID())
+	require.False(t, ok)
+	require.Empty(t, v.Outcomes())
+
+	// resubmitted once it is
+	e := pp.Event{TxHash: common.BytesToHash(mock.GenRandomHash(31)), From: account}
+	se, err := e.Serialize()
+	require.NoError(t, err)
+	require.NoError(t, db.AddEvent(se))
+
+	require.NoError(t, v.SubmitChangeRequest(req))
+	require.Equal(t, sDB.INCLUSION, db.GetMembership(account.Hex()))
+	o, ok := v.Outcome(req.ID())
+	require.True(t, ok)
+	require.Equal(t, APPLIED, o.Status)
+}
+
+func Test_Verifier_Conflicting(t *testing.T) {
+	account := common.BytesToHash(mock.GenRandomHash(20))
+	db := testStateDB(t, account)
+
+	// block the first request while it is verified
+	var (
+		started = make(chan struct{})
+		release = make(chan struct{})
+		once    sync.Once
+	)
+	v := testVerifier(t, db, ProofVerifierFunc(func(p poa.SP1Proof) error {
+		once.Do(func() {
+			close(started)
+			<-release
+		})
+		return poa.CheckProof(p)
+	}))
+
+	first := cr.ChangeRequest{Ns: account.Bytes(), Membership: sDB.INCLUSION, Proof: testProof("first", account, eas.EAS_ATTEST)}
+	errChan := make(chan error)
+	go func() { errChan <- v.SubmitChangeRequest(first) }()
+	<-started
+
+	second := cr.ChangeRequest{Ns: account.Bytes(), Membership: sDB.EXCLUSION, Proof: testProof("second", account, eas.EAS_REVOKE)}
+	require.ErrorIs(t, v.SubmitChangeRequest(second), ErrConflictingRequest)
+	require.ErrorIs(t, v.SubmitChangeRequest(first), ErrDuplicateRequest)
+
+	close(release)
+	require.NoError(t, <-errChan)
+	require.Equal(t, sDB.INCLUSION, db.GetMembership(account.Hex()))
+}
+
+func Test_Verifier_ConcurrentChange(t *testing.T) {
+	account := common.BytesToHash(mock.GenRandomHash(20))
+	db := testStateDB(t, account)
+
+	// membership changes while the request is verified
+	v := testVerifier(t, db, ProofVerifierFunc(func(p poa.SP1Proof) error {
+		return db.CompareAndSetMembership(account.Hex(), sDB.NONE, sDB.PARTIAL_INCLUSION)
+	}))
+
+	req := cr.ChangeRequest{Ns: account.Bytes(), Membership: sDB.INCLUSION, Proof: testProof("proof", account, eas.EAS_ATTEST)}
+	err := v.SubmitChangeRequest(req)
+	require.ErrorIs(t, err, ErrConflictingRequest)
+	require.Equal(t, sDB.PARTIAL_INCLUSION, db.GetMembership(account.Hex()))
+
+	// the conflict isn't final
+	_, ok := v.Outcome(req.ID())
+	require.False(t, ok)
+}
+
+// countrySource serves the country verification of every account
+type countrySource struct {
+	code string
+	err  error
+}
+
+func (s *countrySource) Evidence(account common.Hash, schemas []common.Hash) ([]eas.Evidence, error) {
+	return []eas.Evidence{{
+		UID:    account,
+		Schema: common.HexToHash(eas.COINBASE_EAS_COUNTRY_SCHEMA_ID),
+		Fields: map[string]string{"country": s.code},
+	}}, s.err
+}
+
+func Test_Verifier_Policy(t *testing.T) {
+	account := common.BytesToHash(mock.GenRandomHash(20))
+	db := testStateDB(t, account)
+	policy := &eas.Policy{
+		Rules: []eas.Rule{
+			{Name: "revoked", Membership: sDB.EXCLUSION, When: eas.Condition{Attestation: &eas.Match{State: eas.StateRevoked}}},
+			{Name: "verified", Membership: sDB.INCLUSION, When: eas.Condition{All: []eas.Condition{
+				{Attestation: &eas.Match{Schema: "account", State: eas.StateValid}},
+				{Attestation: &eas.Match{Schema: "country", Field: "country", NotIn: []string{"KP"}}},
+			}}},
+		},
+		Default: sDB.PARTIAL_INCLUSION,
+	}
+	src := &countrySource{code: "KP"}
+	v, err := NewVerifierWithConfig(db, checkProof, VerifierConfig{Policy: policy, Evidence: src})
+	require.NoError(t, err)
+
+	// an attestation doesn't include an account of a denied country,
+	// the mismatch depends on the country so it isn't recorded
+	include := cr.ChangeRequest{Ns: account.Bytes(), Membership: sDB.INCLUSION, Proof: testProof("proof-1", account, eas.EAS_ATTEST)}
+	require.ErrorIs(t, v.SubmitChangeRequest(include), ErrPolicyMismatch)
+	_, ok := v.Outcome(include.ID())
+	require.False(t, ok)
+	partial := cr.ChangeRequest{Ns: account.Bytes(), Membership: sDB.PARTIAL_INCLUSION, Proof: testProof("proof-2", account, eas.EAS_ATTEST)}
+	require.NoError(t, v.SubmitChangeRequest(partial))
+	require.Equal(t, sDB.PARTIAL_INCLUSION, db.GetMembership(account.Hex()))
+
+	// the evidence can't be read
+	src.code, src.err = "US", eas.ErrAttestationNotFound
+	require.ErrorIs(t, v.SubmitChangeRequest(include), eas.ErrAttestationNotFound)
+	_, ok = v.Outcome(include.ID())
+	require.False(t, ok)
+
+	src.err = nil
+	require.NoError(t, v.SubmitChangeRequest(include))
+	require.Equal(t, sDB.INCLUSION, db.GetMembership(account.Hex()))
+
+	// the proof still commits to the account & an event
+	other := cr.ChangeRequest{Ns: account.Bytes(), Membership: sDB.EXCLUSION, Proof: testProof("proof-3", common.HexToHash("0x01"), eas.EAS_REVOKE)}
+	require.ErrorIs(t, v.SubmitChangeRequest(other), ErrInvalidProof)
+	unknown := cr.ChangeRequest{Ns: account.Bytes(), Membership: sDB.PARTIAL_INCLUSION, Proof: testProof("proof-4", account, eas.EAS_UNKNOWN)}
+	require.ErrorIs(t, v.SubmitChangeRequest(unknown), ErrInvalidProof)
+	require.Equal(t, sDB.INCLUSION, db.GetMembership(account.Hex()))
+}
@@ -2,7 +2,7 @@ package baseeas
 
 const BASE_CHAIN_ID = "0x2105"
 const COINBASE_EAS_ATTEST_TOPIC = "0x8bf46bf4cfd674fa735a3d63ec1c9ad4153f033c290341f3a588b75685141b35"
-const COINBASE_EAS_REVOKE_TOPIC = "f930a6e2523c9cc298691873087a740550b8fc85a0680830414c148ed927f615"
+const COINBASE_EAS_REVOKE_TOPIC = "0xf930a6e2523c9cc298691873087a740550b8fc85a0680830414c148ed927f615"
 const BASE_EAS_ADDR = "0x4200000000000000000000000000000000000021"
 const COINBASE_EAS_HASH = "0x000000000000000000000000357458739f90461b99789350868cd7cf330dd7ee"
 const COINBASE_EAS_SCHEMA_ID = "0xf8b05c79f090979bf4a80270aba232dff11a10d9ca55c4f88de95317970f0de9"
@@ -0,0 +1,115 @@
+package baseeas
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// EASABI is the ABI of IEAS.getAttestation
+const EASABI = `[
+	{
+		"type": "function",
+		"name": "getAttestation",
+		"inputs": [{"name": "uid", "type": "bytes32", "internalType": "bytes32"}],
+		"outputs": [
+			{
+				"name": "",
+				"type": "tuple",
+				"internalType": "struct Attestation",
+				"components": [
+					{"name": "uid", "type": "bytes32", "internalType": "bytes32"},
+					{"name": "schema", "type": "bytes32", "internalType": "bytes32"},
+					{"name": "time", "type": "uint64", "internalType": "uint64"},
+					{"name": "expirationTime", "type": "uint64", "internalType": "uint64"},
+					{"name": "revocationTime", "type": "uint64", "internalType": "uint64"},
+					{"name": "refUID", "type": "bytes32", "internalType": "bytes32"},
+					{"name": "recipient", "type": "address", "internalType": "address"},
+					{"name": "attester", "type": "address", "internalType": "address"},
+					{"name": "revocable", "type": "bool", "internalType": "bool"},
+					{"name": "data", "type": "bytes", "internalType": "bytes"}
+				]
+			}
+		],
+		"stateMutability": "view"
+	}
+]`
+
+var easABI, _ = abi.JSON(strings.NewReader(EASABI))
+
+var ErrAttestationNotFound = errors.New("attestation not found")
+
+// Attestation mirrors the Attestation struct of the EAS contract,
+// the times are Unix timestamps & 0 when unset
+type Attestation struct {
+	UID            common.Hash    `abi:"uid" json:"uid"`
+	Schema         common.Hash    `abi:"schema" json:"schema"`
+	Time           uint64         `abi:"time" json:"time"`
+	ExpirationTime uint64         `abi:"expirationTime" json:"expirationTime"`
+	RevocationTime uint64         `abi:"revocationTime" json:"revocationTime"`
+	RefUID         common.Hash    `abi:"refUID" json:"refUID"`
+	Recipient      common.Address `abi:"recipient" json:"recipient"`
+	Attester       common.Address `abi:"attester" json:"attester"`
+	Revocable      bool           `abi:"revocable" json:"revocable"`
+	Data           []byte         `abi:"data" json:"data"`
+}
+
+// Revoked returns true if the attestation was revoked
+func (a Attestation) Revoked() bool {
+	return a.RevocationTime != 0
+}
+
+// Expired returns true if the attestation expired at the Unix time now
+func (a Attestation) Expired(now int64) bool {
+	return a.ExpirationTime != 0 && int64(a.ExpirationTime) <= now
+}
+
+// PackGetAttestation returns the calldata of EAS.getAttestation
+func PackGetAttestation(uid common.Hash) ([]byte, error) {
+	return easABI.Pack("getAttestation", uid)
+}
+
+// UnpackGetAttestation decodes calldata of EAS.getAttestation
+func UnpackGetAttestation(data []byte) (common.Hash, error) {
+	method, err := easABI.MethodById(data)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.Hash(args[0].([32]byte)), nil
+}
+
+// PackAttestation returns the output of EAS.getAttestation
+func PackAttestation(a Attestation) ([]byte, error) {
+	return easABI.Methods["getAttestation"].Outputs.Pack(a)
+}
+
+// GetAttestation calls EAS.getAttestation on the Base EAS contract,
+// the contract returns an empty attestation for an unknown uid
+func GetAttestation(ctx context.Context, caller ethereum.ContractCaller, uid common.Hash) (Attestation, error) {
+	input, err := PackGetAttestation(uid)
+	if err != nil {
+		return Attestation{}, err
+	}
+	to := common.HexToAddress(BASE_EAS_ADDR)
+	output, err := caller.CallContract(ctx, ethereum.CallMsg{To: &to, Data: input}, nil)
+	if err != nil {
+		return Attestation{}, errors.Wrap(err, "failed to call getAttestation")
+	}
+	out, err := easABI.Unpack("getAttestation", output)
+	if err != nil {
+		return Attestation{}, errors.Wrap(err, "failed to decode getAttestation")
+	}
+	a := *abi.ConvertType(out[0], new(Attestation)).(*Attestation)
+	if a.UID == (common.Hash{}) {
+		return Attestation{}, errors.Wrap(ErrAttestationNotFound, uid.Hex())
+	}
+	return a, nil
+}
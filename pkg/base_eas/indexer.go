@@ -0,0 +1,119 @@
+package baseeas
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// COINBASE_INDEXER_ADDR is the Coinbase attestation indexer on Base,
+// it indexes the latest Coinbase attestation of a recipient by schema
+const COINBASE_INDEXER_ADDR = "0x2c7eE1E5f416dfF40054c27A62f7B357C4E8619C"
+
+// IndexerABI is the ABI of the indexer getAttestationUid
+const IndexerABI = `[
+	{
+		"type": "function",
+		"name": "getAttestationUid",
+		"inputs": [
+			{"name": "recipient", "type": "address", "internalType": "address"},
+			{"name": "schemaUid", "type": "bytes32", "internalType": "bytes32"}
+		],
+		"outputs": [{"name": "", "type": "bytes32", "internalType": "bytes32"}],
+		"stateMutability": "view"
+	}
+]`
+
+var indexerABI, _ = abi.JSON(strings.NewReader(IndexerABI))
+
+// PackGetAttestationUID returns the calldata of the indexer getAttestationUid
+func PackGetAttestationUID(recipient common.Address, schema common.Hash) ([]byte, error) {
+	return indexerABI.Pack("getAttestationUid", recipient, schema)
+}
+
+// UnpackGetAttestationUID decodes calldata of the indexer getAttestationUid
+func UnpackGetAttestationUID(data []byte) (common.Address, common.Hash, error) {
+	method, err := indexerABI.MethodById(data)
+	if err != nil {
+		return common.Address{}, common.Hash{}, err
+	}
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return common.Address{}, common.Hash{}, err
+	}
+	return args[0].(common.Address), common.Hash(args[1].([32]byte)), nil
+}
+
+// PackAttestationUID returns the output of the indexer getAttestationUid
+func PackAttestationUID(uid common.Hash) ([]byte, error) {
+	return indexerABI.Methods["getAttestationUid"].Outputs.Pack(uid)
+}
+
+// GetAttestationUID calls getAttestationUid on the Coinbase indexer,
+// the zero hash is returned if the recipient has no attestation of the schema
+func GetAttestationUID(ctx context.Context, caller ethereum.ContractCaller, recipient common.Address, schema common.Hash) (common.Hash, error) {
+	input, err := PackGetAttestationUID(recipient, schema)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	to := common.HexToAddress(COINBASE_INDEXER_ADDR)
+	output, err := caller.CallContract(ctx, ethereum.CallMsg{To: &to, Data: input}, nil)
+	if err != nil {
+		return common.Hash{}, errors.Wrap(err, "failed to call getAttestationUid")
+	}
+	out, err := indexerABI.Unpack("getAttestationUid", output)
+	if err != nil {
+		return common.Hash{}, errors.Wrap(err, "failed to decode getAttestationUid")
+	}
+	return common.Hash(out[0].([32]byte)), nil
+}
+
+/*
+ChainEvidence reads the attestations of an account for the schemas of a
+policy, the Coinbase account verifications are read from the EAS events.
+
+The uid of the latest attestation of a schema is read from the Coinbase
+indexer & the attestation from the EAS contract, it must be attested by
+Coinbase to the account.
+*/
+type ChainEvidence struct {
+	caller  ethereum.ContractCaller
+	timeout time.Duration
+}
+
+func NewChainEvidence(caller ethereum.ContractCaller) *ChainEvidence {
+	return &ChainEvidence{caller: caller, timeout: 10 * time.Second}
+}
+
+// Evidence returns the evidence of the attestations of the account for the
+// schemas, the schemas without attestation have no evidence
+func (c *ChainEvidence) Evidence(account common.Hash, schemas []common.Hash) ([]Evidence, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	recipient := common.BytesToAddress(account.Bytes())
+	var out []Evidence
+	for _, schema := range schemas {
+		uid, err := GetAttestationUID(ctx, c.caller, recipient, schema)
+		if err != nil {
+			return nil, err
+		}
+		if uid == (common.Hash{}) {
+			continue
+		}
+		a, err := GetAttestation(ctx, c.caller, uid)
+		if err != nil {
+			return nil, err
+		}
+		if a.Schema != schema || a.Recipient != recipient || a.Attester != common.HexToAddress(COINBASE_EAS_HASH) {
+			return nil, errors.Wrapf(ErrAttestationNotFound, "attestation %s is not a Coinbase attestation of schema %s to %s", uid.Hex(), schema.Hex(), recipient.Hex())
+		}
+		out = append(out, NewEvidence(a))
+	}
+	return out, nil
+}
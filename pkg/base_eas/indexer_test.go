@@ -0,0 +1,81 @@
+package baseeas
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// chain serves the indexer & the EAS contract
+type chain struct {
+	uids         map[common.Address]map[common.Hash]common.Hash
+	attestations map[common.Hash]Attestation
+}
+
+func (c *chain) CallContract(_ context.Context, call ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	switch *call.To {
+	case common.HexToAddress(COINBASE_INDEXER_ADDR):
+		recipient, schema, err := UnpackGetAttestationUID(call.Data)
+		if err != nil {
+			return nil, err
+		}
+		return PackAttestationUID(c.uids[recipient][schema])
+	case common.HexToAddress(BASE_EAS_ADDR):
+		uid, err := UnpackGetAttestation(call.Data)
+		if err != nil {
+			return nil, err
+		}
+		return PackAttestation(c.attestations[uid])
+	}
+	return nil, nil
+}
+
+func Test_ChainEvidence(t *testing.T) {
+	acc := common.HexToHash("0xa")
+	recipient := common.BytesToAddress(acc.Bytes())
+	data, err := stringArgs.Pack("US")
+	require.NoError(t, err)
+	a := Attestation{
+		UID:            common.HexToHash("0xc1"),
+		Schema:         countrySchema,
+		ExpirationTime: 5,
+		Recipient:      recipient,
+		Attester:       common.HexToAddress(COINBASE_EAS_HASH),
+		Data:           data,
+	}
+	c := &chain{
+		uids:         map[common.Address]map[common.Hash]common.Hash{recipient: {countrySchema: a.UID}},
+		attestations: map[common.Hash]Attestation{a.UID: a},
+	}
+
+	evidence, err := NewChainEvidence(c).Evidence(acc, []common.Hash{countrySchema, otherSchema})
+	require.NoError(t, err)
+	require.Equal(t, []Evidence{{UID: a.UID, Schema: countrySchema, ExpirationTime: 5, Fields: map[string]string{"country": "US"}}}, evidence)
+
+	// no attestation of the schemas
+	evidence, err = NewChainEvidence(c).Evidence(common.HexToHash("0xb"), []common.Hash{countrySchema})
+	require.NoError(t, err)
+	require.Empty(t, evidence)
+
+	// the indexed attestation is not a Coinbase attestation to the account
+	for _, tamper := range []func(a *Attestation){
+		func(a *Attestation) { a.Attester = common.HexToAddress("0x01") },
+		func(a *Attestation) { a.Recipient = common.HexToAddress("0x01") },
+		func(a *Attestation) { a.Schema = otherSchema },
+	} {
+		tampered := a
+		tamper(&tampered)
+		c.attestations[a.UID] = tampered
+		_, err = NewChainEvidence(c).Evidence(acc, []common.Hash{countrySchema})
+		require.ErrorIs(t, err, ErrAttestationNotFound)
+	}
+
+	// the indexed uid is unknown to the EAS contract
+	delete(c.attestations, a.UID)
+	_, err = NewChainEvidence(c).Evidence(acc, []common.Hash{countrySchema})
+	require.ErrorIs(t, err, ErrAttestationNotFound)
+}
@@ -28,4 +28,12 @@ type EAS struct {
 	UUID    common.Hash `json:"uuid"`
 	Account common.Hash `json:"address"`
 	Type    EAS_TYPE    `json:"type"`
+	// Unix time the attestation expires at, 0 if it doesn't expire or is unknown.
+	// The EAS logs don't carry it, it is read from the attestation.
+	ExpirationTime uint64 `json:"expirationTime,omitempty"`
+}
+
+// Expired returns true if the attestation expired at the Unix time now
+func (e EAS) Expired(now int64) bool {
+	return e.ExpirationTime != 0 && int64(e.ExpirationTime) <= now
 }
@@ -0,0 +1,419 @@
+package baseeas
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	sDB "github.com/0xBow-io/base-eas-asp/pkg/statedb"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+)
+
+// COINBASE_EAS_COUNTRY_SCHEMA_ID is the schema of the Coinbase Verified Country
+// attestations, their data is the ISO 3166-1 alpha-2 country code
+const COINBASE_EAS_COUNTRY_SCHEMA_ID = "0x1801901fabd0e6189356b4fb52bb0ab855276d84f7ec140839fbd1f6801ca065"
+
+var (
+	ErrInvalidPolicy    = errors.New("invalid policy")
+	ErrNoEvidenceSource = errors.New("policy requires an evidence source")
+)
+
+// states of an attestation matched by a policy
+const (
+	StateValid   = "valid"
+	StateRevoked = "revoked"
+	StateExpired = "expired"
+)
+
+// Evidence is an attestation of an account evaluated by a Policy
+type Evidence struct {
+	UID            common.Hash `json:"uid"`
+	Schema         common.Hash `json:"schema"`
+	Revoked        bool        `json:"revoked"`
+	ExpirationTime uint64      `json:"expirationTime,omitempty"`
+	// decoded attestation data, e.g. the country of a country verification
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// State returns the state of the attestation at the Unix time now,
+// a revoked attestation is revoked even if it expired
+func (e Evidence) State(now int64) string {
+	switch {
+	case e.Revoked:
+		return StateRevoked
+	case e.ExpirationTime != 0 && int64(e.ExpirationTime) <= now:
+		return StateExpired
+	default:
+		return StateValid
+	}
+}
+
+var stringArgs = abi.Arguments{{Type: abi.Type{T: abi.StringTy}}}
+
+// NewEvidence returns the evidence of an attestation,
+// the data of the country verifications is decoded in the country field
+func NewEvidence(a Attestation) Evidence {
+	e := Evidence{UID: a.UID, Schema: a.Schema, Revoked: a.Revoked(), ExpirationTime: a.ExpirationTime}
+	if a.Schema == common.HexToHash(COINBASE_EAS_COUNTRY_SCHEMA_ID) {
+		if out, err := stringArgs.Unpack(a.Data); err == nil {
+			e.Fields = map[string]string{"country": out[0].(string)}
+		}
+	}
+	return e
+}
+
+// EventEvidence returns the evidence of the Coinbase account verifications
+// of the EAS events of an account, in order. A revocation revokes the
+// attestation with the same uid.
+func EventEvidence(events []EAS) []Evidence {
+	var (
+		out   []Evidence
+		index = make(map[common.Hash]int)
+	)
+	for _, e := range events {
+		i, ok := index[e.UUID]
+		if !ok {
+			i = len(out)
+			index[e.UUID] = i
+			out = append(out, Evidence{UID: e.UUID, Schema: common.HexToHash(COINBASE_EAS_SCHEMA_ID)})
+		}
+		if e.ExpirationTime != 0 {
+			out[i].ExpirationTime = e.ExpirationTime
+		}
+		if e.Type == EAS_REVOKE {
+			out[i].Revoked = true
+		}
+	}
+	return out
+}
+
+// EvidenceSource reads the attestations of an account for schemas,
+// ChainEvidence reads them on chain
+type EvidenceSource interface {
+	Evidence(account common.Hash, schemas []common.Hash) ([]Evidence, error)
+}
+
+/*
+Policy maps the attestations of an account to its membership.
+
+The rules are evaluated in order, the membership of the first rule which
+condition holds is the membership of the account, Default if none holds.
+
+	{
+	  "schemas": {"country": "0x1801...a065"},
+	  "rules": [
+	    {"name": "revoked", "membership": "exclusion", "when": {"attestation": {"state": "revoked"}}},
+	    {"name": "verified", "membership": "inclusion", "when": {"all": [
+	      {"attestation": {"schema": "account", "state": "valid"}},
+	      {"attestation": {"schema": "country", "state": "valid", "field": "country", "notIn": ["KP", "IR"]}}
+	    ]}}
+	  ],
+	  "default": "partial_inclusion"
+	}
+
+The schemas are named by Schemas, account & country name the Coinbase
+account & country verifications unless redefined.
+*/
+type Policy struct {
+	Schemas map[string]string   `json:"schemas,omitempty"`
+	Rules   []Rule              `json:"rules"`
+	Default sDB.MEMBERSHIP_TYPE `json:"default"`
+}
+
+// Rule of a Policy
+type Rule struct {
+	Name       string              `json:"name,omitempty"`
+	Membership sDB.MEMBERSHIP_TYPE `json:"membership"`
+	When       Condition           `json:"when"`
+}
+
+// Condition over the attestations of an account, exactly one field is set
+type Condition struct {
+	// all the conditions hold
+	All []Condition `json:"all,omitempty"`
+	// any of the conditions holds
+	Any []Condition `json:"any,omitempty"`
+	// the condition doesn't hold
+	Not *Condition `json:"not,omitempty"`
+	// an attestation of the account matches
+	Attestation *Match `json:"attestation,omitempty"`
+}
+
+// Match of an attestation, the empty fields match any attestation
+type Match struct {
+	// name of a schema of the policy or schema id
+	Schema string `json:"schema,omitempty"`
+	// valid, revoked or expired
+	State string `json:"state,omitempty"`
+	// field of the attestation data which value is In or NotIn the values,
+	// an attestation without the field doesn't match
+	Field string   `json:"field,omitempty"`
+	In    []string `json:"in,omitempty"`
+	NotIn []string `json:"notIn,omitempty"`
+}
+
+// Decision of a Policy
+type Decision struct {
+	Membership sDB.MEMBERSHIP_TYPE `json:"membership"`
+	// name of the rule which decided, empty for the default
+	Rule string `json:"rule,omitempty"`
+}
+
+// DefaultPolicy derives the membership like EasTypeToMembership for the
+// Coinbase account verifications: inclusion with a valid attestation,
+// exclusion with a revoked one & partial inclusion otherwise
+func DefaultPolicy() *Policy {
+	return &Policy{
+		Rules: []Rule{
+			{Name: "verified", Membership: sDB.INCLUSION, When: Condition{Attestation: &Match{Schema: "account", State: StateValid}}},
+			{Name: "revoked", Membership: sDB.EXCLUSION, When: Condition{Attestation: &Match{Schema: "account", State: StateRevoked}}},
+		},
+		Default: sDB.PARTIAL_INCLUSION,
+	}
+}
+
+// ParsePolicy reads & validates a JSON policy
+func ParsePolicy(r io.Reader) (*Policy, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	p := new(Policy)
+	if err := dec.Decode(p); err != nil {
+		return nil, errors.Wrap(ErrInvalidPolicy, err.Error())
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Validate checks the memberships, schemas & conditions of the policy
+func (p *Policy) Validate() error {
+	if !p.Default.IsValid() {
+		return errors.Wrapf(ErrInvalidPolicy, "default membership %q", p.Default)
+	}
+	for name, id := range p.Schemas {
+		if !isSchemaID(id) {
+			return errors.Wrapf(ErrInvalidPolicy, "schema %s id %q", name, id)
+		}
+	}
+	for i, rule := range p.Rules {
+		if !rule.Membership.IsValid() {
+			return errors.Wrapf(ErrInvalidPolicy, "rule %d membership %q", i, rule.Membership)
+		}
+		if err := p.validate(rule.When); err != nil {
+			return errors.Wrapf(err, "rule %d", i)
+		}
+	}
+	return nil
+}
+
+func (p *Policy) validate(c Condition) error {
+	set := 0
+	for _, ok := range []bool{c.All != nil, c.Any != nil, c.Not != nil, c.Attestation != nil} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return errors.Wrap(ErrInvalidPolicy, "condition must set exactly one of all, any, not & attestation")
+	}
+
+	switch {
+	case c.Not != nil:
+		return p.validate(*c.Not)
+	case c.Attestation != nil:
+		return p.validateMatch(*c.Attestation)
+	}
+	conditions := c.All
+	if c.Any != nil {
+		conditions = c.Any
+	}
+	if len(conditions) == 0 {
+		return errors.Wrap(ErrInvalidPolicy, "empty all or any")
+	}
+	for _, sub := range conditions {
+		if err := p.validate(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Policy) validateMatch(m Match) error {
+	if m.Schema != "" {
+		if _, ok := p.schema(m.Schema); !ok {
+			return errors.Wrapf(ErrInvalidPolicy, "unknown schema %q", m.Schema)
+		}
+	}
+	switch m.State {
+	case "", StateValid, StateRevoked, StateExpired:
+	default:
+		return errors.Wrapf(ErrInvalidPolicy, "unknown state %q", m.State)
+	}
+	if m.Field == "" && (m.In != nil || m.NotIn != nil) {
+		return errors.Wrap(ErrInvalidPolicy, "in & notIn require a field")
+	}
+	if m.Field != "" && m.In == nil && m.NotIn == nil {
+		return errors.Wrapf(ErrInvalidPolicy, "field %s requires in or notIn", m.Field)
+	}
+	return nil
+}
+
+// schema returns the id of a schema name or id
+func (p *Policy) schema(name string) (common.Hash, bool) {
+	if id, ok := p.Schemas[name]; ok {
+		return common.HexToHash(id), true
+	}
+	switch name {
+	case "account":
+		return common.HexToHash(COINBASE_EAS_SCHEMA_ID), true
+	case "country":
+		return common.HexToHash(COINBASE_EAS_COUNTRY_SCHEMA_ID), true
+	}
+	if isSchemaID(name) {
+		return common.HexToHash(name), true
+	}
+	return common.Hash{}, false
+}
+
+func isSchemaID(s string) bool {
+	b, err := hexutil.Decode(s)
+	return err == nil && len(b) == common.HashLength
+}
+
+// EvidenceSchemas returns the schemas matched by the rules which evidence
+// is read from an EvidenceSource, all but the account verifications which
+// are the EAS events of the reports
+func (p *Policy) EvidenceSchemas() []common.Hash {
+	var (
+		out  []common.Hash
+		seen = map[common.Hash]bool{common.HexToHash(COINBASE_EAS_SCHEMA_ID): true}
+	)
+	var walk func(c Condition)
+	walk = func(c Condition) {
+		for _, sub := range append(c.All, c.Any...) {
+			walk(sub)
+		}
+		if c.Not != nil {
+			walk(*c.Not)
+		}
+		if c.Attestation != nil && c.Attestation.Schema != "" {
+			if id, ok := p.schema(c.Attestation.Schema); ok && !seen[id] {
+				seen[id] = true
+				out = append(out, id)
+			}
+		}
+	}
+	for _, rule := range p.Rules {
+		walk(rule.When)
+	}
+	return out
+}
+
+// Derive returns the membership of the account with the evidence of its EAS
+// events & its attestations of the EvidenceSchemas read from src at the Unix
+// time now, src may be nil if the policy has no EvidenceSchemas
+func (p *Policy) Derive(account common.Hash, events []EAS, src EvidenceSource, now int64) (Decision, error) {
+	evidence := EventEvidence(events)
+	if schemas := p.EvidenceSchemas(); len(schemas) > 0 {
+		if src == nil {
+			return Decision{}, ErrNoEvidenceSource
+		}
+		more, err := src.Evidence(account, schemas)
+		if err != nil {
+			return Decision{}, errors.Wrap(err, "failed to read the evidence of "+account.Hex())
+		}
+		evidence = append(evidence, more...)
+	}
+	return p.Evaluate(evidence, now), nil
+}
+
+// DeriveMembership returns the membership of the account derived from its
+// EAS events in order, by the policy p if set, else the membership of the
+// last event like EasTypeToMembership. ok is false without event.
+func DeriveMembership(p *Policy, account common.Hash, events []EAS, src EvidenceSource, now int64) (m sDB.MEMBERSHIP_TYPE, ok bool, err error) {
+	if len(events) == 0 {
+		return sDB.NONE, false, nil
+	}
+	if p == nil {
+		return EasTypeToMembership(events[len(events)-1].Type), true, nil
+	}
+	d, err := p.Derive(account, events, src, now)
+	if err != nil {
+		return sDB.NONE, false, err
+	}
+	return d.Membership, true, nil
+}
+
+// Evaluate returns the membership of an account with the attestations
+// at the Unix time now. The policy must be valid.
+func (p *Policy) Evaluate(evidence []Evidence, now int64) Decision {
+	for _, rule := range p.Rules {
+		if p.holds(rule.When, evidence, now) {
+			return Decision{Membership: rule.Membership, Rule: rule.Name}
+		}
+	}
+	return Decision{Membership: p.Default}
+}
+
+func (p *Policy) holds(c Condition, evidence []Evidence, now int64) bool {
+	switch {
+	case c.All != nil:
+		for _, sub := range c.All {
+			if !p.holds(sub, evidence, now) {
+				return false
+			}
+		}
+		return true
+	case c.Any != nil:
+		for _, sub := range c.Any {
+			if p.holds(sub, evidence, now) {
+				return true
+			}
+		}
+		return false
+	case c.Not != nil:
+		return !p.holds(*c.Not, evidence, now)
+	case c.Attestation != nil:
+		for _, e := range evidence {
+			if p.matches(*c.Attestation, e, now) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *Policy) matches(m Match, e Evidence, now int64) bool {
+	if m.Schema != "" {
+		if id, _ := p.schema(m.Schema); id != e.Schema {
+			return false
+		}
+	}
+	if m.State != "" && m.State != e.State(now) {
+		return false
+	}
+	if m.Field == "" {
+		return true
+	}
+	v, ok := e.Fields[m.Field]
+	if !ok {
+		return false
+	}
+	if m.In != nil && !containsFold(m.In, v) {
+		return false
+	}
+	return !containsFold(m.NotIn, v)
+}
+
+func containsFold(values []string, v string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, v) {
+			return true
+		}
+	}
+	return false
+}
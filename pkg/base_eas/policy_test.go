@@ -0,0 +1,384 @@
+package baseeas
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	sDB "github.com/0xBow-io/base-eas-asp/pkg/statedb"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+const now = 1700000000
+
+// compliancePolicy requires an unexpired account verification & a country
+// verification out of the deny list, any revocation excludes
+const compliancePolicy = `{
+	"rules": [
+		{"name": "revoked", "membership": "exclusion", "when": {"attestation": {"state": "revoked"}}},
+		{"name": "verified", "membership": "inclusion", "when": {"all": [
+			{"attestation": {"schema": "account", "state": "valid"}},
+			{"attestation": {"schema": "country", "state": "valid", "field": "country", "notIn": ["KP", "IR"]}}
+		]}}
+	],
+	"default": "partial_inclusion"
+}`
+
+var (
+	accountSchema = common.HexToHash(COINBASE_EAS_SCHEMA_ID)
+	countrySchema = common.HexToHash(COINBASE_EAS_COUNTRY_SCHEMA_ID)
+	otherSchema   = common.HexToHash("0x01")
+)
+
+func account(opts ...func(e *Evidence)) Evidence {
+	e := Evidence{UID: common.HexToHash("0xa1"), Schema: accountSchema}
+	for _, opt := range opts {
+		opt(&e)
+	}
+	return e
+}
+
+func country(code string, opts ...func(e *Evidence)) Evidence {
+	e := Evidence{UID: common.HexToHash("0xc1"), Schema: countrySchema, Fields: map[string]string{"country": code}}
+	for _, opt := range opts {
+		opt(&e)
+	}
+	return e
+}
+
+func revoked(e *Evidence) { e.Revoked = true }
+func expired(e *Evidence) { e.ExpirationTime = now }
+func expires(e *Evidence) { e.ExpirationTime = now + 1 }
+
+func Test_Evidence_State(t *testing.T) {
+	for _, tc := range []struct {
+		e     Evidence
+		state string
+	}{
+		{account(), StateValid},
+		{account(expires), StateValid},
+		{account(expired), StateExpired},
+		{account(revoked), StateRevoked},
+		{account(revoked, expired), StateRevoked},
+		{account(revoked, expires), StateRevoked},
+	} {
+		require.Equal(t, tc.state, tc.e.State(now), "%+v", tc.e)
+	}
+}
+
+func Test_Policy_Evaluate(t *testing.T) {
+	p, err := ParsePolicy(strings.NewReader(compliancePolicy))
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name     string
+		evidence []Evidence
+		want     Decision
+	}{
+		{"no attestation", nil, Decision{Membership: sDB.PARTIAL_INCLUSION}},
+		{"account only", []Evidence{account()}, Decision{Membership: sDB.PARTIAL_INCLUSION}},
+		{"country only", []Evidence{country("US")}, Decision{Membership: sDB.PARTIAL_INCLUSION}},
+		{"account & country", []Evidence{account(), country("US")}, Decision{sDB.INCLUSION, "verified"}},
+		{"unexpired attestations", []Evidence{account(expires), country("US", expires)}, Decision{sDB.INCLUSION, "verified"}},
+		{"denied country", []Evidence{account(), country("KP")}, Decision{Membership: sDB.PARTIAL_INCLUSION}},
+		{"denied country case", []Evidence{account(), country("ir")}, Decision{Membership: sDB.PARTIAL_INCLUSION}},
+		{"allowed & denied countries", []Evidence{account(), country("KP"), country("US")}, Decision{sDB.INCLUSION, "verified"}},
+		{"country without field", []Evidence{account(), {Schema: countrySchema}}, Decision{Membership: sDB.PARTIAL_INCLUSION}},
+		{"country of another schema", []Evidence{account(), {Schema: otherSchema, Fields: map[string]string{"country": "US"}}}, Decision{Membership: sDB.PARTIAL_INCLUSION}},
+		{"expired account", []Evidence{account(expired), country("US")}, Decision{Membership: sDB.PARTIAL_INCLUSION}},
+		{"expired country", []Evidence{account(), country("US", expired)}, Decision{Membership: sDB.PARTIAL_INCLUSION}},
+		{"expired & valid account", []Evidence{account(expired), account(), country("US")}, Decision{sDB.INCLUSION, "verified"}},
+		{"revoked account", []Evidence{account(revoked), country("US")}, Decision{sDB.EXCLUSION, "revoked"}},
+		{"revoked country", []Evidence{account(), country("US", revoked)}, Decision{sDB.EXCLUSION, "revoked"}},
+		{"revoked & valid account", []Evidence{account(revoked), account(), country("US")}, Decision{sDB.EXCLUSION, "revoked"}},
+		{"revoked other schema", []Evidence{account(), country("US"), {Schema: otherSchema, Revoked: true}}, Decision{sDB.EXCLUSION, "revoked"}},
+		{"revoked expired", []Evidence{account(revoked, expired)}, Decision{sDB.EXCLUSION, "revoked"}},
+	} {
+		require.Equal(t, tc.want, p.Evaluate(tc.evidence, now), tc.name)
+	}
+}
+
+func Test_Policy_Conditions(t *testing.T) {
+	match := func(m Match) Condition { return Condition{Attestation: &m} }
+	var (
+		us      = country("US")
+		kp      = country("KP")
+		acc     = account()
+		accRev  = account(revoked)
+		accExp  = account(expired)
+		other   = Evidence{Schema: otherSchema}
+		custom  = Evidence{Schema: common.HexToHash("0xbeef"), Fields: map[string]string{"level": "2"}}
+		schemas = map[string]string{"kyc": common.HexToHash("0xbeef").Hex(), "country": common.HexToHash("0xbeef").Hex()}
+	)
+
+	for _, tc := range []struct {
+		name     string
+		schemas  map[string]string
+		when     Condition
+		evidence []Evidence
+		holds    bool
+	}{
+		{"any attestation", nil, match(Match{}), []Evidence{other}, true},
+		{"any attestation of none", nil, match(Match{}), nil, false},
+		{"schema name", nil, match(Match{Schema: "account"}), []Evidence{acc}, true},
+		{"schema name mismatch", nil, match(Match{Schema: "account"}), []Evidence{us}, false},
+		{"schema id", nil, match(Match{Schema: COINBASE_EAS_COUNTRY_SCHEMA_ID}), []Evidence{us}, true},
+		{"custom schema", schemas, match(Match{Schema: "kyc"}), []Evidence{custom}, true},
+		{"redefined schema", schemas, match(Match{Schema: "country"}), []Evidence{us}, false},
+		{"state valid", nil, match(Match{State: StateValid}), []Evidence{accRev, accExp}, false},
+		{"state revoked", nil, match(Match{State: StateRevoked}), []Evidence{acc, accRev}, true},
+		{"state expired", nil, match(Match{State: StateExpired}), []Evidence{acc, accExp}, true},
+		{"state expired of revoked", nil, match(Match{State: StateExpired}), []Evidence{account(revoked, expired)}, false},
+		{"in", nil, match(Match{Field: "country", In: []string{"US", "FR"}}), []Evidence{us}, true},
+		{"in case", nil, match(Match{Field: "country", In: []string{"us"}}), []Evidence{us}, true},
+		{"not in", nil, match(Match{Field: "country", In: []string{"FR"}}), []Evidence{us}, false},
+		{"empty in", nil, match(Match{Field: "country", In: []string{}}), []Evidence{us}, false},
+		{"notIn", nil, match(Match{Field: "country", NotIn: []string{"KP"}}), []Evidence{kp}, false},
+		{"notIn other", nil, match(Match{Field: "country", NotIn: []string{"KP"}}), []Evidence{us}, true},
+		{"in & notIn", nil, match(Match{Field: "country", In: []string{"US", "KP"}, NotIn: []string{"KP"}}), []Evidence{kp}, false},
+		{"missing field", nil, match(Match{Field: "country", NotIn: []string{"KP"}}), []Evidence{acc}, false},
+		{"other field", schemas, match(Match{Schema: "kyc", Field: "level", In: []string{"2", "3"}}), []Evidence{custom}, true},
+		{"all", nil, Condition{All: []Condition{match(Match{Schema: "account"}), match(Match{Schema: "country"})}}, []Evidence{acc, us}, true},
+		{"all missing one", nil, Condition{All: []Condition{match(Match{Schema: "account"}), match(Match{Schema: "country"})}}, []Evidence{acc}, false},
+		{"any", nil, Condition{Any: []Condition{match(Match{Schema: "account"}), match(Match{Schema: "country"})}}, []Evidence{us}, true},
+		{"any of none", nil, Condition{Any: []Condition{match(Match{Schema: "account"}), match(Match{Schema: "country"})}}, []Evidence{other}, false},
+		{"not", nil, Condition{Not: &Condition{Attestation: &Match{State: StateRevoked}}}, []Evidence{acc}, true},
+		{"not revoked", nil, Condition{Not: &Condition{Attestation: &Match{State: StateRevoked}}}, []Evidence{acc, accRev}, false},
+		{"not of none", nil, Condition{Not: &Condition{Attestation: &Match{}}}, nil, true},
+		{
+			// no valid country attestation is denied
+			"nested", nil,
+			Condition{All: []Condition{
+				match(Match{Schema: "account", State: StateValid}),
+				{Not: &Condition{Attestation: &Match{Schema: "country", State: StateValid, Field: "country", In: []string{"KP"}}}},
+			}},
+			[]Evidence{acc, us, kp}, false,
+		},
+		{
+			"nested without denied", nil,
+			Condition{All: []Condition{
+				match(Match{Schema: "account", State: StateValid}),
+				{Not: &Condition{Attestation: &Match{Schema: "country", State: StateValid, Field: "country", In: []string{"KP"}}}},
+			}},
+			[]Evidence{acc, us, country("KP", revoked)}, true,
+		},
+	} {
+		p := &Policy{
+			Schemas: tc.schemas,
+			Rules:   []Rule{{Name: "rule", Membership: sDB.INCLUSION, When: tc.when}},
+			Default: sDB.EXCLUSION,
+		}
+		require.NoError(t, p.Validate(), tc.name)
+		want := sDB.EXCLUSION
+		if tc.holds {
+			want = sDB.INCLUSION
+		}
+		require.Equal(t, want, p.Evaluate(tc.evidence, now).Membership, tc.name)
+	}
+}
+
+func Test_Policy_RuleOrder(t *testing.T) {
+	p := &Policy{
+		Rules: []Rule{
+			{Name: "first", Membership: sDB.EXCLUSION, When: Condition{Attestation: &Match{Schema: "country"}}},
+			{Name: "second", Membership: sDB.INCLUSION, When: Condition{Attestation: &Match{}}},
+		},
+		Default: sDB.PARTIAL_INCLUSION,
+	}
+	require.NoError(t, p.Validate())
+	require.Equal(t, Decision{sDB.EXCLUSION, "first"}, p.Evaluate([]Evidence{account(), country("US")}, now))
+	require.Equal(t, Decision{sDB.INCLUSION, "second"}, p.Evaluate([]Evidence{account()}, now))
+	require.Equal(t, Decision{Membership: sDB.PARTIAL_INCLUSION}, p.Evaluate(nil, now))
+
+	// without rule
+	p.Rules = nil
+	require.NoError(t, p.Validate())
+	require.Equal(t, Decision{Membership: sDB.PARTIAL_INCLUSION}, p.Evaluate([]Evidence{account()}, now))
+}
+
+func Test_DefaultPolicy(t *testing.T) {
+	p := DefaultPolicy()
+	require.NoError(t, p.Validate())
+
+	// a single event derives the membership of EasTypeToMembership
+	for _, typ := range []EAS_TYPE{EAS_ATTEST, EAS_REVOKE} {
+		e := EAS{UUID: common.HexToHash("0x01"), Type: typ}
+		require.Equal(t, EasTypeToMembership(typ), p.Evaluate(EventEvidence([]EAS{e}), now).Membership, typ)
+	}
+	require.Equal(t, EasTypeToMembership(EAS_UNKNOWN), p.Evaluate(nil, now).Membership)
+
+	for _, tc := range []struct {
+		name   string
+		events []EAS
+		want   sDB.MEMBERSHIP_TYPE
+	}{
+		{"attested then revoked", []EAS{{UUID: common.HexToHash("0x01"), Type: EAS_ATTEST}, {UUID: common.HexToHash("0x01"), Type: EAS_REVOKE}}, sDB.EXCLUSION},
+		{"revoked then attested again", []EAS{{UUID: common.HexToHash("0x01"), Type: EAS_REVOKE}, {UUID: common.HexToHash("0x02"), Type: EAS_ATTEST}}, sDB.INCLUSION},
+		{"expired", []EAS{{UUID: common.HexToHash("0x01"), Type: EAS_ATTEST, ExpirationTime: now}}, sDB.PARTIAL_INCLUSION},
+		{"unexpired", []EAS{{UUID: common.HexToHash("0x01"), Type: EAS_ATTEST, ExpirationTime: now + 1}}, sDB.INCLUSION},
+		{"expired & revoked", []EAS{{UUID: common.HexToHash("0x01"), Type: EAS_ATTEST, ExpirationTime: now}, {UUID: common.HexToHash("0x02"), Type: EAS_REVOKE}}, sDB.EXCLUSION},
+	} {
+		require.Equal(t, tc.want, p.Evaluate(EventEvidence(tc.events), now).Membership, tc.name)
+	}
+
+	// the policy is encoded in the config format
+	b, err := json.Marshal(p)
+	require.NoError(t, err)
+	parsed, err := ParsePolicy(bytes.NewReader(b))
+	require.NoError(t, err)
+	require.Equal(t, p, parsed)
+}
+
+func Test_EventEvidence(t *testing.T) {
+	a, b := common.HexToHash("0x01"), common.HexToHash("0x02")
+	require.Empty(t, EventEvidence(nil))
+	require.Equal(t, []Evidence{
+		{UID: a, Schema: accountSchema, Revoked: true, ExpirationTime: 5},
+		{UID: b, Schema: accountSchema, Revoked: true},
+	}, EventEvidence([]EAS{
+		{UUID: a, Type: EAS_ATTEST, ExpirationTime: 5},
+		{UUID: b, Type: EAS_REVOKE},
+		{UUID: a, Type: EAS_REVOKE},
+	}))
+}
+
+func Test_NewEvidence(t *testing.T) {
+	data, err := stringArgs.Pack("US")
+	require.NoError(t, err)
+
+	e := NewEvidence(Attestation{UID: common.HexToHash("0x01"), Schema: countrySchema, RevocationTime: 1, ExpirationTime: 2, Data: data})
+	require.Equal(t, Evidence{
+		UID:            common.HexToHash("0x01"),
+		Schema:         countrySchema,
+		Revoked:        true,
+		ExpirationTime: 2,
+		Fields:         map[string]string{"country": "US"},
+	}, e)
+
+	// the data of the other schemas is not decoded
+	e = NewEvidence(Attestation{UID: common.HexToHash("0x01"), Schema: accountSchema, Data: data})
+	require.Nil(t, e.Fields)
+
+	// invalid country data
+	e = NewEvidence(Attestation{UID: common.HexToHash("0x01"), Schema: countrySchema, Data: []byte{1}})
+	require.Nil(t, e.Fields)
+}
+
+func Test_ParsePolicy_Invalid(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		policy string
+	}{
+		{"not json", `rules`},
+		{"unknown field", `{"rules": [], "default": "exclusion", "fallback": "none"}`},
+		{"missing default", `{"rules": []}`},
+		{"invalid default", `{"default": "member"}`},
+		{"none default", `{"default": "none"}`},
+		{"invalid schema id", `{"schemas": {"kyc": "0x01"}, "default": "exclusion"}`},
+		{"schema id without prefix", `{"schemas": {"kyc": "` + strings.Repeat("ab", 32) + `"}, "default": "exclusion"}`},
+		{"invalid membership", `{"rules": [{"membership": "member", "when": {"attestation": {}}}], "default": "exclusion"}`},
+		{"empty condition", `{"rules": [{"membership": "inclusion", "when": {}}], "default": "exclusion"}`},
+		{"missing condition", `{"rules": [{"membership": "inclusion"}], "default": "exclusion"}`},
+		{"two conditions", `{"rules": [{"membership": "inclusion", "when": {"attestation": {}, "not": {"attestation": {}}}}], "default": "exclusion"}`},
+		{"empty all", `{"rules": [{"membership": "inclusion", "when": {"all": []}}], "default": "exclusion"}`},
+		{"empty any", `{"rules": [{"membership": "inclusion", "when": {"any": []}}], "default": "exclusion"}`},
+		{"unknown schema", `{"rules": [{"membership": "inclusion", "when": {"attestation": {"schema": "kyc"}}}], "default": "exclusion"}`},
+		{"unknown state", `{"rules": [{"membership": "inclusion", "when": {"attestation": {"state": "pending"}}}], "default": "exclusion"}`},
+		{"in without field", `{"rules": [{"membership": "inclusion", "when": {"attestation": {"in": ["US"]}}}], "default": "exclusion"}`},
+		{"notIn without field", `{"rules": [{"membership": "inclusion", "when": {"attestation": {"notIn": ["US"]}}}], "default": "exclusion"}`},
+		{"field without values", `{"rules": [{"membership": "inclusion", "when": {"attestation": {"field": "country"}}}], "default": "exclusion"}`},
+		{"invalid nested in all", `{"rules": [{"membership": "inclusion", "when": {"all": [{"attestation": {}}, {}]}}], "default": "exclusion"}`},
+		{"invalid nested in any", `{"rules": [{"membership": "inclusion", "when": {"any": [{"attestation": {"state": "x"}}]}}], "default": "exclusion"}`},
+		{"invalid nested in not", `{"rules": [{"membership": "inclusion", "when": {"not": {"all": []}}}], "default": "exclusion"}`},
+	} {
+		_, err := ParsePolicy(strings.NewReader(tc.policy))
+		require.ErrorIs(t, err, ErrInvalidPolicy, tc.name)
+	}
+}
+
+func Test_Policy_EvidenceSchemas(t *testing.T) {
+	p, err := ParsePolicy(strings.NewReader(compliancePolicy))
+	require.NoError(t, err)
+	require.Equal(t, []common.Hash{countrySchema}, p.EvidenceSchemas())
+	require.Empty(t, DefaultPolicy().EvidenceSchemas())
+
+	// the schemas are listed once, in order, from any condition
+	p = &Policy{
+		Schemas: map[string]string{"other": otherSchema.Hex()},
+		Rules: []Rule{
+			{Membership: sDB.EXCLUSION, When: Condition{Not: &Condition{Attestation: &Match{Schema: "other"}}}},
+			{Membership: sDB.INCLUSION, When: Condition{Any: []Condition{
+				{Attestation: &Match{Schema: countrySchema.Hex()}},
+				{Attestation: &Match{Schema: "other"}},
+				{Attestation: &Match{}},
+			}}},
+		},
+		Default: sDB.PARTIAL_INCLUSION,
+	}
+	require.NoError(t, p.Validate())
+	require.Equal(t, []common.Hash{otherSchema, countrySchema}, p.EvidenceSchemas())
+}
+
+// evidenceSource returns the evidence set for the accounts
+type evidenceSource struct {
+	evidence map[common.Hash][]Evidence
+	schemas  []common.Hash
+	err      error
+}
+
+func (s *evidenceSource) Evidence(account common.Hash, schemas []common.Hash) ([]Evidence, error) {
+	s.schemas = schemas
+	return s.evidence[account], s.err
+}
+
+func Test_DeriveMembership(t *testing.T) {
+	p, err := ParsePolicy(strings.NewReader(compliancePolicy))
+	require.NoError(t, err)
+	acc := common.HexToHash("0xa")
+	attest := EAS{UUID: common.HexToHash("0x01"), Account: acc, Type: EAS_ATTEST}
+	revoke := EAS{UUID: common.HexToHash("0x01"), Account: acc, Type: EAS_REVOKE}
+
+	// the membership of the last event without policy
+	m, ok, err := DeriveMembership(nil, acc, []EAS{revoke, attest}, nil, now)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, sDB.INCLUSION, m)
+	_, ok, err = DeriveMembership(p, acc, nil, nil, now)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// the country is read from the source
+	src := &evidenceSource{evidence: map[common.Hash][]Evidence{acc: {country("US")}}}
+	for _, tc := range []struct {
+		name     string
+		events   []EAS
+		evidence []Evidence
+		want     sDB.MEMBERSHIP_TYPE
+	}{
+		{"verified", []EAS{attest}, []Evidence{country("US")}, sDB.INCLUSION},
+		{"denied country", []EAS{attest}, []Evidence{country("KP")}, sDB.PARTIAL_INCLUSION},
+		{"no country", []EAS{attest}, nil, sDB.PARTIAL_INCLUSION},
+		{"revoked country", []EAS{attest}, []Evidence{country("US", revoked)}, sDB.EXCLUSION},
+		{"revoked account", []EAS{attest, revoke}, []Evidence{country("US")}, sDB.EXCLUSION},
+	} {
+		src.evidence[acc] = tc.evidence
+		m, ok, err := DeriveMembership(p, acc, tc.events, src, now)
+		require.NoError(t, err, tc.name)
+		require.True(t, ok, tc.name)
+		require.Equal(t, tc.want, m, tc.name)
+		require.Equal(t, []common.Hash{countrySchema}, src.schemas, tc.name)
+	}
+
+	src.err = ErrAttestationNotFound
+	_, _, err = DeriveMembership(p, acc, []EAS{attest}, src, now)
+	require.ErrorIs(t, err, ErrAttestationNotFound)
+	_, _, err = DeriveMembership(p, acc, []EAS{attest}, nil, now)
+	require.ErrorIs(t, err, ErrNoEvidenceSource)
+
+	// the default policy reads no evidence
+	m, _, err = DeriveMembership(DefaultPolicy(), acc, []EAS{attest}, nil, now)
+	require.NoError(t, err)
+	require.Equal(t, sDB.INCLUSION, m)
+}
@@ -3,6 +3,8 @@ package changeRequest
 import (
 	poa "github.com/0xBow-io/base-eas-asp/pkg/proof-of-audit"
 	stateDB "github.com/0xBow-io/base-eas-asp/pkg/statedb"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
 type ChangeRequest struct {
@@ -10,3 +12,13 @@ type ChangeRequest struct {
 	Membership stateDB.MEMBERSHIP_TYPE `json:"membership"`
 	Proof      poa.SP1Proof            `json:"sp1Proof"`
 }
+
+// ID identifies the change request by its namespace, membership & proof
+func (c ChangeRequest) ID() common.Hash {
+	return crypto.Keccak256Hash(
+		common.BytesToHash(c.Ns).Bytes(),
+		crypto.Keccak256([]byte(c.Membership)),
+		crypto.Keccak256([]byte(c.Proof.Proof)),
+		crypto.Keccak256(c.Proof.Stdout.Buffer.Data),
+	)
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"encoding/hex"
 	"math/big"
+
+	"github.com/0xbow-io/go-iden3-crypto/constants"
 )
 
 const ElementSize = 32 // internal hash value of a node is 32 bytes
@@ -18,6 +20,12 @@ func (e Element) BigInt() *big.Int {
 	return big.NewInt(0).SetBytes(e[:])
 }
 
+// InField returns true if e is an element
+// of the SNARK scalar field (e < constants.Q)
+func (e Element) InField() bool {
+	return e.BigInt().Cmp(constants.Q) < 0
+}
+
 func (e Element) Eq(x Element) bool {
 	return bytes.Equal(e[:], x[:])
 }
@@ -0,0 +1,346 @@
+package nmt
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+/*
+Binary encoding of a Proof (all integers are big-endian):
+
+	version        : 1 byte
+	namespace size : 1 byte
+	hash id        : 1 byte
+	start          : 4 bytes
+	end            : 4 bytes
+	leafHash len   : 4 bytes
+	leafHash       : leafHash len bytes
+	pathLayers     : Layers binary encoding
+
+Layers are encoded as a 4 byte layer count followed by
+each Layer prefixed with its 4 byte encoded length.
+
+A Layer is encoded as a 4 byte node count & 4 byte node size
+followed by the raw bytes of each node.
+
+New versions of the encoding must bump ProofEncodingVersion
+and keep a decoder for every previous version.
+*/
+const (
+	ProofEncodingV1      uint8 = 1
+	ProofEncodingVersion       = ProofEncodingV1
+
+	proofHeaderSize = 3 + 4 + 4 + 4
+)
+
+func putUint32(out []byte, v int) []byte {
+	return binary.BigEndian.AppendUint32(out, uint32(v))
+}
+
+func readUint32(in []byte, offset int) (int, int, error) {
+	if len(in) < offset+4 {
+		return 0, offset, fmt.Errorf("%w: unexpected end of data at offset %d", ErrInvalidEncoding, offset)
+	}
+	return int(binary.BigEndian.Uint32(in[offset : offset+4])), offset + 4, nil
+}
+
+func readBytes(in []byte, offset, n int) ([]byte, int, error) {
+	if n < 0 || len(in) < offset+n {
+		return nil, offset, fmt.Errorf("%w: unexpected end of data at offset %d", ErrInvalidEncoding, offset)
+	}
+	out := make([]byte, n)
+	copy(out, in[offset:offset+n])
+	return out, offset + n, nil
+}
+
+func (n Node) MarshalBinary() ([]byte, error) {
+	out := make([]byte, len(n))
+	copy(out, n)
+	return out, nil
+}
+
+func (n *Node) UnmarshalBinary(data []byte) error {
+	*n = make(Node, len(data))
+	copy(*n, data)
+	return nil
+}
+
+func (n Node) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hexutil.Bytes(n))
+}
+
+func (n *Node) UnmarshalJSON(data []byte) error {
+	var b hexutil.Bytes
+	if err := json.Unmarshal(data, &b); err != nil {
+		return err
+	}
+	*n = Node(b)
+	return nil
+}
+
+func (l Layer) MarshalBinary() ([]byte, error) {
+	nodeSize := 0
+	if len(l) > 0 {
+		nodeSize = len(l[0])
+	}
+
+	out := make([]byte, 0, 8+len(l)*nodeSize)
+	out = putUint32(out, len(l))
+	out = putUint32(out, nodeSize)
+	for i, n := range l {
+		if len(n) != nodeSize {
+			return nil, fmt.Errorf("%w: node %d has size %d, want %d", ErrInvalidEncoding, i, len(n), nodeSize)
+		}
+		out = append(out, n...)
+	}
+	return out, nil
+}
+
+func (l *Layer) UnmarshalBinary(data []byte) error {
+	layer, offset, err := decodeLayer(data, 0)
+	if err != nil {
+		return err
+	}
+	if offset != len(data) {
+		return fmt.Errorf("%w: %d trailing bytes", ErrInvalidEncoding, len(data)-offset)
+	}
+	*l = layer
+	return nil
+}
+
+func decodeLayer(in []byte, offset int) (Layer, int, error) {
+	count, offset, err := readUint32(in, offset)
+	if err != nil {
+		return nil, offset, err
+	}
+	nodeSize, offset, err := readUint32(in, offset)
+	if err != nil {
+		return nil, offset, err
+	}
+	if count > 0 && (nodeSize == 0 || count > (len(in)-offset)/nodeSize) {
+		return nil, offset, fmt.Errorf("%w: layer of %d nodes with size %d exceeds data", ErrInvalidEncoding, count, nodeSize)
+	}
+
+	l := make(Layer, count)
+	for i := 0; i < count; i++ {
+		var node []byte
+		if node, offset, err = readBytes(in, offset, nodeSize); err != nil {
+			return nil, offset, err
+		}
+		l[i] = node
+	}
+	return l, offset, nil
+}
+
+func (l Layers) MarshalBinary() ([]byte, error) {
+	out := putUint32(nil, len(l))
+	for _, layer := range l {
+		b, err := layer.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		out = putUint32(out, len(b))
+		out = append(out, b...)
+	}
+	return out, nil
+}
+
+func (l *Layers) UnmarshalBinary(data []byte) error {
+	layers, offset, err := decodeLayers(data, 0)
+	if err != nil {
+		return err
+	}
+	if offset != len(data) {
+		return fmt.Errorf("%w: %d trailing bytes", ErrInvalidEncoding, len(data)-offset)
+	}
+	*l = layers
+	return nil
+}
+
+func decodeLayers(in []byte, offset int) (Layers, int, error) {
+	count, offset, err := readUint32(in, offset)
+	if err != nil {
+		return nil, offset, err
+	}
+	// every layer takes at least 12 bytes
+	if count > (len(in)-offset)/12 {
+		return nil, offset, fmt.Errorf("%w: %d layers exceeds data", ErrInvalidEncoding, count)
+	}
+
+	var layers Layers
+	if count > 0 {
+		layers = make(Layers, count)
+	}
+	for i := 0; i < count; i++ {
+		var size int
+		if size, offset, err = readUint32(in, offset); err != nil {
+			return nil, offset, err
+		}
+		end := offset + size
+		if size < 0 || end > len(in) {
+			return nil, offset, fmt.Errorf("%w: layer %d exceeds data", ErrInvalidEncoding, i)
+		}
+		if layers[i], offset, err = decodeLayer(in[:end], offset); err != nil {
+			return nil, offset, err
+		}
+		if offset != end {
+			return nil, offset, fmt.Errorf("%w: layer %d length mismatch", ErrInvalidEncoding, i)
+		}
+	}
+	return layers, offset, nil
+}
+
+// validate checks that every node of the proof matches
+// the size implied by the proof's namespace size.
+func (proof Proof) validate() error {
+	if proof.start < 0 || proof.end < proof.start {
+		return fmt.Errorf("%w: start: %d end: %d", ErrInvalidRange, proof.start, proof.end)
+	}
+	if proof.IsEmptyProof() {
+		return nil
+	}
+	if _, err := proof.hashID.Hasher(); err != nil {
+		return err
+	}
+
+	for level, layer := range proof.pathLayers {
+		for i, n := range layer {
+			if err := n.Validate(proof.nsSize); err != nil {
+				return fmt.Errorf("%w: node %d of layer %d: %v", ErrInvalidEncoding, i, level, err)
+			}
+		}
+	}
+	if len(proof.leafHash) > 0 {
+		if err := Node(proof.leafHash).Validate(proof.nsSize); err != nil {
+			return fmt.Errorf("%w: leaf hash: %v", ErrInvalidEncoding, err)
+		}
+	}
+	return nil
+}
+
+func (proof Proof) MarshalBinary() ([]byte, error) {
+	if err := proof.validate(); err != nil {
+		return nil, err
+	}
+
+	out := []byte{ProofEncodingVersion, byte(proof.nsSize), byte(proof.hashID)}
+	out = putUint32(out, proof.start)
+	out = putUint32(out, proof.end)
+	out = putUint32(out, len(proof.leafHash))
+	out = append(out, proof.leafHash...)
+
+	layers, err := proof.pathLayers.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return append(out, layers...), nil
+}
+
+func (proof *Proof) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("%w: empty data", ErrInvalidEncoding)
+	}
+
+	switch version := data[0]; version {
+	case ProofEncodingV1:
+		return proof.decodeBinaryV1(data)
+	default:
+		return fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+}
+
+func (proof *Proof) decodeBinaryV1(data []byte) error {
+	if len(data) < proofHeaderSize {
+		return fmt.Errorf("%w: proof header too short", ErrInvalidEncoding)
+	}
+
+	var (
+		p      Proof
+		offset = 3
+		err    error
+		size   int
+	)
+	p.nsSize = IDSize(data[1])
+	p.hashID = HashID(data[2])
+
+	if p.start, offset, err = readUint32(data, offset); err != nil {
+		return err
+	}
+	if p.end, offset, err = readUint32(data, offset); err != nil {
+		return err
+	}
+	if size, offset, err = readUint32(data, offset); err != nil {
+		return err
+	}
+	if size > 0 {
+		if p.leafHash, offset, err = readBytes(data, offset, size); err != nil {
+			return err
+		}
+	}
+	if p.pathLayers, offset, err = decodeLayers(data, offset); err != nil {
+		return err
+	}
+	if offset != len(data) {
+		return fmt.Errorf("%w: %d trailing bytes", ErrInvalidEncoding, len(data)-offset)
+	}
+	if err := p.validate(); err != nil {
+		return err
+	}
+
+	*proof = p
+	return nil
+}
+
+type proofJSON struct {
+	Version       uint8         `json:"version"`
+	NamespaceSize IDSize        `json:"namespaceSize"`
+	HashID        HashID        `json:"hashId"`
+	Start         int           `json:"start"`
+	End           int           `json:"end"`
+	PathLayers    Layers        `json:"pathLayers"`
+	LeafHash      hexutil.Bytes `json:"leafHash,omitempty"`
+}
+
+func (proof Proof) MarshalJSON() ([]byte, error) {
+	if err := proof.validate(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(proofJSON{
+		Version:       ProofEncodingVersion,
+		NamespaceSize: proof.nsSize,
+		HashID:        proof.hashID,
+		Start:         proof.start,
+		End:           proof.end,
+		PathLayers:    proof.pathLayers,
+		LeafHash:      proof.leafHash,
+	})
+}
+
+func (proof *Proof) UnmarshalJSON(data []byte) error {
+	var v proofJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	switch v.Version {
+	case ProofEncodingV1:
+		p := Proof{
+			nsSize:     v.NamespaceSize,
+			hashID:     v.HashID,
+			start:      v.Start,
+			end:        v.End,
+			pathLayers: v.PathLayers,
+			leafHash:   v.LeafHash,
+		}
+		if err := p.validate(); err != nil {
+			return err
+		}
+		*proof = p
+		return nil
+	default:
+		return fmt.Errorf("%w: %d", ErrUnsupportedVersion, v.Version)
+	}
+}
@@ -0,0 +1,166 @@
+package nmt
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func genTestProof(t *testing.T, nID ID) (Proof, *NsGroups) {
+	nsgroup := gen_ngs(t, 3, 3, true)
+
+	zero, err := hex.DecodeString(MerkleZeroHex)
+	require.NoError(t, err)
+
+	if nID == nil {
+		nID = nsgroup.namespaces[1]
+	}
+
+	proof, err := ProveNamespace(nsgroup, Poseidon2Hasher, Element(zero), nID)
+	require.NoError(t, err)
+	return proof, nsgroup
+}
+
+func requireProofEqual(t *testing.T, expected, got Proof) {
+	require.Equal(t, expected.NamespaceSize(), got.NamespaceSize())
+	require.Equal(t, expected.HashID(), got.HashID())
+	require.Equal(t, expected.Start(), got.Start())
+	require.Equal(t, expected.End(), got.End())
+	require.Equal(t, hex.EncodeToString(expected.LeafHash()), hex.EncodeToString(got.LeafHash()))
+	require.Equal(t, expected.PathLayers().Depth(), got.PathLayers().Depth())
+	for i, layer := range expected.PathLayers() {
+		require.Equal(t, len(layer), len(got.PathLayers()[i]))
+		for j, n := range layer {
+			require.True(t, n.Equal(got.PathLayers()[i][j]), "nodes do not match layer: %d index: %d", i, j)
+		}
+	}
+}
+
+func Test_Proof_Binary_RoundTrip(t *testing.T) {
+	proof, _ := genTestProof(t, nil)
+	require.False(t, proof.IsEmptyProof())
+	require.False(t, proof.IsAbsenceProof())
+	require.Equal(t, IDSize(32), proof.NamespaceSize())
+	require.Equal(t, HashPoseidon2, proof.HashID())
+
+	b, err := proof.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, ProofEncodingVersion, b[0])
+
+	var decoded Proof
+	require.NoError(t, decoded.UnmarshalBinary(b))
+	requireProofEqual(t, proof, decoded)
+}
+
+func Test_Proof_JSON_RoundTrip(t *testing.T) {
+	proof, _ := genTestProof(t, nil)
+
+	b, err := json.Marshal(proof)
+	require.NoError(t, err)
+
+	var decoded Proof
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	requireProofEqual(t, proof, decoded)
+}
+
+func Test_Proof_Absence_RoundTrip(t *testing.T) {
+	_, nsgroup := genTestProof(t, nil)
+
+	// namespace in between the first two namespaces of the tree
+	nID := make(ID, 32)
+	copy(nID, nsgroup.namespaces[0])
+	nID[31]++
+	require.True(t, nID.Less(nsgroup.namespaces[1]))
+
+	zero, err := hex.DecodeString(MerkleZeroHex)
+	require.NoError(t, err)
+
+	proof, err := ProveNamespace(nsgroup, Poseidon2Hasher, Element(zero), nID)
+	require.NoError(t, err)
+	require.True(t, proof.IsAbsenceProof())
+
+	b, err := proof.MarshalBinary()
+	require.NoError(t, err)
+	var fromBinary Proof
+	require.NoError(t, fromBinary.UnmarshalBinary(b))
+	requireProofEqual(t, proof, fromBinary)
+
+	b, err = json.Marshal(proof)
+	require.NoError(t, err)
+	var fromJSON Proof
+	require.NoError(t, json.Unmarshal(b, &fromJSON))
+	requireProofEqual(t, proof, fromJSON)
+}
+
+func Test_Proof_Empty_RoundTrip(t *testing.T) {
+	proof := NewEmptyRangeProof()
+
+	b, err := proof.MarshalBinary()
+	require.NoError(t, err)
+	var fromBinary Proof
+	require.NoError(t, fromBinary.UnmarshalBinary(b))
+	require.True(t, fromBinary.IsEmptyProof())
+
+	b, err = json.Marshal(proof)
+	require.NoError(t, err)
+	var fromJSON Proof
+	require.NoError(t, json.Unmarshal(b, &fromJSON))
+	require.True(t, fromJSON.IsEmptyProof())
+}
+
+func Test_Proof_Decode_Invalid(t *testing.T) {
+	proof, _ := genTestProof(t, nil)
+	b, err := proof.MarshalBinary()
+	require.NoError(t, err)
+
+	var decoded Proof
+
+	// unknown version
+	unknown := append([]byte{}, b...)
+	unknown[0] = ProofEncodingVersion + 1
+	require.ErrorIs(t, decoded.UnmarshalBinary(unknown), ErrUnsupportedVersion)
+	require.ErrorIs(t, json.Unmarshal([]byte(`{"version":2}`), &decoded), ErrUnsupportedVersion)
+
+	// unknown hash function
+	badHash := append([]byte{}, b...)
+	badHash[2] = 0xff
+	require.ErrorIs(t, decoded.UnmarshalBinary(badHash), ErrUnknownHashID)
+
+	// truncated & trailing data
+	require.ErrorIs(t, decoded.UnmarshalBinary(b[:len(b)-1]), ErrInvalidEncoding)
+	require.ErrorIs(t, decoded.UnmarshalBinary(append(b, 0)), ErrInvalidEncoding)
+
+	// namespace size does not match the node sizes
+	badNs := append([]byte{}, b...)
+	badNs[1] = 8
+	require.ErrorIs(t, decoded.UnmarshalBinary(badNs), ErrInvalidEncoding)
+}
+
+func Test_Layers_Binary_RoundTrip(t *testing.T) {
+	proof, _ := genTestProof(t, nil)
+
+	b, err := proof.PathLayers().MarshalBinary()
+	require.NoError(t, err)
+
+	var layers Layers
+	require.NoError(t, layers.UnmarshalBinary(b))
+	require.Equal(t, proof.PathLayers().Depth(), layers.Depth())
+
+	leaf := proof.PathLayers().GetLayer(0)
+	b, err = leaf.MarshalBinary()
+	require.NoError(t, err)
+
+	var layer Layer
+	require.NoError(t, layer.UnmarshalBinary(b))
+	require.Equal(t, leaf.Hashes(32), layer.Hashes(32))
+
+	b, err = json.Marshal(leaf[0])
+	require.NoError(t, err)
+	require.Equal(t, `"0x`+leaf[0].Hex()+`"`, string(b))
+
+	var node Node
+	require.NoError(t, json.Unmarshal(b, &node))
+	require.True(t, leaf[0].Equal(node))
+}
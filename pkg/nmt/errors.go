@@ -3,10 +3,18 @@ package nmt
 import "errors"
 
 var (
-	ErrNilHashFunction  error = errors.New("nil hash function")
-	ErrInvalidLeafLen   error = errors.New("invalid leaf length")
-	ErrInvalidOrder     error = errors.New("invalid order")
-	ErrInvalidNamespace error = errors.New("invalid namespace")
-	ErrInvalidRange     error = errors.New("invalid range")
-	ErrInvalidLevel     error = errors.New("invalid level")
+	ErrNilHashFunction    error = errors.New("nil hash function")
+	ErrInvalidLeafLen     error = errors.New("invalid leaf length")
+	ErrInvalidOrder       error = errors.New("invalid order")
+	ErrInvalidNamespace   error = errors.New("invalid namespace")
+	ErrInvalidRange       error = errors.New("invalid range")
+	ErrInvalidLevel       error = errors.New("invalid level")
+	ErrUnknownHashID      error = errors.New("unknown hash function id")
+	ErrInvalidEncoding    error = errors.New("invalid encoding")
+	ErrUnsupportedVersion error = errors.New("unsupported encoding version")
+	ErrInvalidProof       error = errors.New("invalid proof")
+	ErrEmptyTree          error = errors.New("empty tree")
+	ErrNotInField         error = errors.New("element not in field")
+	ErrHashFailed         error = errors.New("hash failed")
+	ErrInvalidNodeLen     error = errors.New("invalid node length")
 )
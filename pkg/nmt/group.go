@@ -55,7 +55,7 @@ type NsGroups struct {
 	ngs NamespaceGroups
 }
 
-func newNsGroups(namespaceLen IDSize) *NsGroups {
+func NewNsGroups(namespaceLen IDSize) *NsGroups {
 	return &NsGroups{
 		nsSize: namespaceLen,
 		nsIdxs: make(map[string]int),
@@ -135,8 +135,8 @@ func (ng *NsGroups) Add(d Record) (string, ID, error) {
 		nIDStr string
 	)
 	// data should be min namespace + hash
-	if len(d) < int(ng.nsSize)+ElementSize {
-		return "", nil, fmt.Errorf("%w: got: %v, want >= %v", ErrInvalidLeafLen, len(d), int(ng.nsSize)+ElementSize)
+	if err := d.Validate(ng.nsSize); err != nil {
+		return "", nil, err
 	}
 
 	nID = d.NID(ng.nsSize)
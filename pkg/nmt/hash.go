@@ -2,15 +2,16 @@ package nmt
 
 import (
 	"crypto/sha256"
+	"fmt"
 	"math/big"
 
 	"github.com/0xbow-io/go-iden3-crypto/mimc7"
 	"github.com/0xbow-io/go-iden3-crypto/poseidon"
 )
 
-type HashFunction func(left Element, right Element) Element
+type HashFunction func(left Element, right Element) (Element, error)
 
-func SHA256Hash(left Element, right Element) Element {
+func SHA256Hash(left Element, right Element) (Element, error) {
 	hash := sha256.New()
 	sRight := right.Hex()
 	sLeft := left.Hex()
@@ -22,37 +23,61 @@ func SHA256Hash(left Element, right Element) Element {
 	}
 	hash.Write([]byte(sLeft))
 	hash.Write([]byte(sRight))
-	return ToElement(hash.Sum(nil))
+	return ToElement(hash.Sum(nil)), nil
 }
 
-func Poseidon(left Element, right Element) Element {
+// checkField returns ErrNotInField unless
+// both inputs are elements of the SNARK scalar field
+func checkField(left Element, right Element) error {
+	if !left.InField() {
+		return fmt.Errorf("%w: left %s", ErrNotInField, left.Hex())
+	}
+	if !right.InField() {
+		return fmt.Errorf("%w: right %s", ErrNotInField, right.Hex())
+	}
+	return nil
+}
+
+func Poseidon(left Element, right Element) (Element, error) {
+	if err := checkField(left, right); err != nil {
+		return Element{}, err
+	}
+
 	result, err := poseidon.Hash([]*big.Int{
 		left.BigInt(),
 		right.BigInt(),
 	})
-
 	if err != nil {
-		panic(err.Error())
+		return Element{}, fmt.Errorf("%w: %v", ErrHashFailed, err)
 	}
-	return ToElement(result.Bytes())
+	return ToElement(result.Bytes()), nil
 }
 
-func Poseidon2(left Element, right Element) Element {
+func Poseidon2(left Element, right Element) (Element, error) {
+	if err := checkField(left, right); err != nil {
+		return Element{}, err
+	}
+
 	result, err := poseidon.Poseidon2([]*big.Int{
 		left.BigInt(),
 		right.BigInt(),
 	})
-
 	if err != nil {
-		panic(err.Error())
+		return Element{}, fmt.Errorf("%w: %v", ErrHashFailed, err)
 	}
-	return ToElement(result.Bytes())
+	return ToElement(result.Bytes()), nil
 }
 
-func MIMC7(left Element, right Element) Element {
+// MIMC7 would silently reduce inputs outside of the field
+// so they are rejected instead.
+func MIMC7(left Element, right Element) (Element, error) {
+	if err := checkField(left, right); err != nil {
+		return Element{}, err
+	}
+
 	result := mimc7.MIMC7Hash(
 		left.BigInt(),
 		right.BigInt(),
 	)
-	return ToElement(result.Bytes())
+	return ToElement(result.Bytes()), nil
 }
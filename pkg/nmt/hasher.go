@@ -0,0 +1,235 @@
+package nmt
+
+import (
+	"fmt"
+	"sync"
+)
+
+// HashID identifies the Hasher a tree was built with
+// so that it can be recorded alongside the tree and its proofs.
+type HashID uint8
+
+const (
+	HashUnknown HashID = iota
+
+	// plain hashers, inner nodes are HashFunction(left, right)
+	// and leaves are the record hashes.
+	HashSHA256
+	HashPoseidon
+	HashPoseidon2
+	HashMIMC7
+
+	// domain separated hashers, refer to namespacedHasher
+	HashNsSHA256
+	HashNsPoseidon
+	HashNsPoseidon2
+	HashNsMIMC7
+)
+
+// Domain separation prefixes for leaf & inner node digests
+// Refer to: https://github.com/celestiaorg/nmt/blob/master/docs/spec/nmt.md#namespaced-hashing
+var (
+	LeafPrefix = ToElement([]byte{0x00})
+	NodePrefix = ToElement([]byte{0x01})
+)
+
+// namespaces are split into limbs of nsLimbSize bytes
+// so that every hash input fits in a ~254 bit field element.
+const nsLimbSize = 16
+
+// Hasher computes the digests of the nodes of a tree
+type Hasher interface {
+	ID() HashID
+	Name() string
+	// HashFunction is the underlying 2-to-1 hash function
+	HashFunction() HashFunction
+	// HashLeaf returns the digest of a leaf holding the record hash data
+	HashLeaf(nID ID, data Element) (Element, error)
+	// HashNode returns the digest of a node with the namespace range
+	// [minNs, maxNs] and the children digests left & right
+	HashNode(minNs, maxNs ID, left, right Element) (Element, error)
+	// HashZero returns the zero value of the level above the one of zero
+	HashZero(namespaceLen IDSize, zero Element) (Element, error)
+}
+
+// plainHasher only hashes the children digests,
+// leaves are the record hashes as is.
+type plainHasher struct {
+	id   HashID
+	name string
+	fn   HashFunction
+}
+
+func (h plainHasher) ID() HashID                 { return h.id }
+func (h plainHasher) Name() string               { return h.name }
+func (h plainHasher) HashFunction() HashFunction { return h.fn }
+
+func (h plainHasher) HashLeaf(_ ID, data Element) (Element, error) {
+	return data, nil
+}
+
+func (h plainHasher) HashNode(_, _ ID, left, right Element) (Element, error) {
+	return h.fn(left, right)
+}
+
+func (h plainHasher) HashZero(_ IDSize, zero Element) (Element, error) {
+	return h.fn(zero, zero)
+}
+
+/*
+namespacedHasher domain separates leaves from inner nodes
+and commits to the namespace range of every node:
+
+	leaf = H(H(LeafPrefix, ns(nID)), data)
+	node = H(H(NodePrefix, H(ns(minNs), ns(maxNs))), H(left, right))
+
+where ns() folds the namespace limbs with H starting from the zero element.
+*/
+type namespacedHasher struct {
+	plainHasher
+}
+
+func hashNamespace(fn HashFunction, nID ID) (acc Element, err error) {
+	for i := 0; i < len(nID); i += nsLimbSize {
+		end := i + nsLimbSize
+		if end > len(nID) {
+			end = len(nID)
+		}
+		if acc, err = fn(acc, ToElement(nID[i:end])); err != nil {
+			return Element{}, err
+		}
+	}
+	return acc, nil
+}
+
+func (h namespacedHasher) HashLeaf(nID ID, data Element) (Element, error) {
+	ns, err := hashNamespace(h.fn, nID)
+	if err != nil {
+		return Element{}, err
+	}
+	prefixed, err := h.fn(LeafPrefix, ns)
+	if err != nil {
+		return Element{}, err
+	}
+	return h.fn(prefixed, data)
+}
+
+func (h namespacedHasher) HashNode(minNs, maxNs ID, left, right Element) (Element, error) {
+	min, err := hashNamespace(h.fn, minNs)
+	if err != nil {
+		return Element{}, err
+	}
+	max, err := hashNamespace(h.fn, maxNs)
+	if err != nil {
+		return Element{}, err
+	}
+	nsRange, err := h.fn(min, max)
+	if err != nil {
+		return Element{}, err
+	}
+	prefixed, err := h.fn(NodePrefix, nsRange)
+	if err != nil {
+		return Element{}, err
+	}
+	children, err := h.fn(left, right)
+	if err != nil {
+		return Element{}, err
+	}
+	return h.fn(prefixed, children)
+}
+
+func (h namespacedHasher) HashZero(namespaceLen IDSize, zero Element) (Element, error) {
+	zeroNs := make(ID, namespaceLen)
+	return h.HashNode(zeroNs, zeroNs, zero, zero)
+}
+
+func NewPlainHasher(id HashID, name string, fn HashFunction) Hasher {
+	return plainHasher{id, name, fn}
+}
+
+func NewNamespacedHasher(id HashID, name string, fn HashFunction) Hasher {
+	return namespacedHasher{plainHasher{id, name, fn}}
+}
+
+var (
+	SHA256Hasher    = NewPlainHasher(HashSHA256, "sha256", SHA256Hash)
+	PoseidonHasher  = NewPlainHasher(HashPoseidon, "poseidon", Poseidon)
+	Poseidon2Hasher = NewPlainHasher(HashPoseidon2, "poseidon2", Poseidon2)
+	MIMC7Hasher     = NewPlainHasher(HashMIMC7, "mimc7", MIMC7)
+
+	NsSHA256Hasher    = NewNamespacedHasher(HashNsSHA256, "ns-sha256", SHA256Hash)
+	NsPoseidonHasher  = NewNamespacedHasher(HashNsPoseidon, "ns-poseidon", Poseidon)
+	NsPoseidon2Hasher = NewNamespacedHasher(HashNsPoseidon2, "ns-poseidon2", Poseidon2)
+	NsMIMC7Hasher     = NewNamespacedHasher(HashNsMIMC7, "ns-mimc7", MIMC7)
+)
+
+var (
+	hashersMu sync.RWMutex
+	hashers   = make(map[HashID]Hasher)
+)
+
+func init() {
+	for _, h := range []Hasher{
+		SHA256Hasher, PoseidonHasher, Poseidon2Hasher, MIMC7Hasher,
+		NsSHA256Hasher, NsPoseidonHasher, NsPoseidon2Hasher, NsMIMC7Hasher,
+	} {
+		if err := RegisterHasher(h); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterHasher makes a Hasher available by its ID & name
+func RegisterHasher(h Hasher) error {
+	if h == nil || h.HashFunction() == nil {
+		return ErrNilHashFunction
+	}
+
+	hashersMu.Lock()
+	defer hashersMu.Unlock()
+
+	if h.ID() == HashUnknown {
+		return fmt.Errorf("%w: %d is reserved", ErrUnknownHashID, h.ID())
+	}
+	if _, ok := hashers[h.ID()]; ok {
+		return fmt.Errorf("hasher id %d already registered", h.ID())
+	}
+	for _, other := range hashers {
+		if other.Name() == h.Name() {
+			return fmt.Errorf("hasher name %s already registered", h.Name())
+		}
+	}
+	hashers[h.ID()] = h
+	return nil
+}
+
+// Hasher returns the registered Hasher identified by id
+func (id HashID) Hasher() (Hasher, error) {
+	hashersMu.RLock()
+	defer hashersMu.RUnlock()
+
+	if h, ok := hashers[id]; ok {
+		return h, nil
+	}
+	return nil, fmt.Errorf("%w: %d", ErrUnknownHashID, id)
+}
+
+func (id HashID) String() string {
+	if h, err := id.Hasher(); err == nil {
+		return h.Name()
+	}
+	return fmt.Sprintf("unknown(%d)", uint8(id))
+}
+
+// HasherByName returns the registered Hasher with the given name
+func HasherByName(name string) (Hasher, error) {
+	hashersMu.RLock()
+	defer hashersMu.RUnlock()
+
+	for _, h := range hashers {
+		if h.Name() == name {
+			return h, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownHashID, name)
+}
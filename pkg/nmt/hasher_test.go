@@ -0,0 +1,112 @@
+package nmt
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_HashID_Hasher(t *testing.T) {
+	for _, h := range []Hasher{
+		SHA256Hasher, PoseidonHasher, Poseidon2Hasher, MIMC7Hasher,
+		NsSHA256Hasher, NsPoseidonHasher, NsPoseidon2Hasher, NsMIMC7Hasher,
+	} {
+		byID, err := h.ID().Hasher()
+		require.NoError(t, err)
+		require.Equal(t, h.Name(), byID.Name())
+		require.Equal(t, h.Name(), h.ID().String())
+
+		byName, err := HasherByName(h.Name())
+		require.NoError(t, err)
+		require.Equal(t, h.ID(), byName.ID())
+	}
+
+	_, err := HashUnknown.Hasher()
+	require.ErrorIs(t, err, ErrUnknownHashID)
+	_, err = HasherByName("unknown")
+	require.ErrorIs(t, err, ErrUnknownHashID)
+}
+
+func Test_RegisterHasher(t *testing.T) {
+	require.ErrorIs(t, RegisterHasher(nil), ErrNilHashFunction)
+	require.ErrorIs(t, RegisterHasher(NewPlainHasher(HashUnknown, "unknown", SHA256Hash)), ErrUnknownHashID)
+	require.Error(t, RegisterHasher(NewPlainHasher(HashPoseidon, "other", Poseidon)))
+	require.Error(t, RegisterHasher(NewPlainHasher(HashID(200), "poseidon", Poseidon)))
+
+	_, err := HashID(200).Hasher()
+	require.ErrorIs(t, err, ErrUnknownHashID)
+}
+
+func Test_NamespacedHasher_DomainSeparation(t *testing.T) {
+	must := func(e Element, err error) Element {
+		require.NoError(t, err)
+		return e
+	}
+
+	h := NsPoseidonHasher
+	data := ToElement([]byte{0x0a})
+	a, b := ToElement([]byte{0x01}), ToElement([]byte{0x02})
+	nsA, nsB := ID(a[:]), ID(b[:])
+
+	// leaf digests commit to the namespace
+	require.NotEqual(t, must(h.HashLeaf(nsA, data)), must(h.HashLeaf(nsB, data)))
+
+	// node digests commit to the namespace range
+	require.NotEqual(t, must(h.HashNode(nsA, nsA, data, data)), must(h.HashNode(nsA, nsB, data, data)))
+
+	// a leaf can't be passed off as an inner node
+	require.NotEqual(t, must(h.HashLeaf(nsA, data)), must(h.HashNode(nsA, nsA, data, data)))
+	require.NotEqual(t, must(h.HashNode(nsA, nsA, data, data)), must(h.HashFunction()(data, data)))
+
+	// plain hashers are unchanged
+	require.Equal(t, data, must(PoseidonHasher.HashLeaf(nsA, data)))
+	require.Equal(t, must(Poseidon(data, data)), must(PoseidonHasher.HashNode(nsA, nsB, data, data)))
+}
+
+func Test_Tree(t *testing.T) {
+	nsgroup := gen_ngs(t, 4, 2, true)
+	zero, err := hex.DecodeString(MerkleZeroHex)
+	require.NoError(t, err)
+
+	_, err = NewTree(nsgroup, nil, Element(zero))
+	require.ErrorIs(t, err, ErrNilHashFunction)
+
+	tree, err := NewTree(nsgroup, NsPoseidonHasher, Element(zero))
+	require.NoError(t, err)
+	require.Equal(t, HashNsPoseidon, tree.HashID())
+
+	plain, err := NewTree(nsgroup, PoseidonHasher, Element(zero))
+	require.NoError(t, err)
+	require.NotEqual(t, plain.Root(), tree.Root())
+
+	for _, nID := range nsgroup.namespaces {
+		proof, err := tree.Prove(nID)
+		require.NoError(t, err)
+		require.Equal(t, HashNsPoseidon, proof.HashID())
+
+		// proofs built from the layers of the tree match ProveNamespace
+		rebuilt, err := ProveNamespace(nsgroup, NsPoseidonHasher, Element(zero), nID)
+		require.NoError(t, err)
+		require.Equal(t, rebuilt, proof)
+
+		// the hasher id survives encoding
+		b, err := proof.MarshalBinary()
+		require.NoError(t, err)
+		var decoded Proof
+		require.NoError(t, decoded.UnmarshalBinary(b))
+		require.Equal(t, HashNsPoseidon, decoded.HashID())
+		require.NoError(t, decoded.VerifyNamespace(Element(zero), nID, tree.Root()))
+	}
+
+	absent := make(ID, 32)
+	copy(absent, nsgroup.namespaces[1])
+	absent[31]++
+	proof, err := tree.Prove(absent)
+	require.NoError(t, err)
+	require.True(t, proof.IsAbsenceProof())
+	rebuilt, err := ProveNamespace(nsgroup, NsPoseidonHasher, Element(zero), absent)
+	require.NoError(t, err)
+	require.Equal(t, rebuilt, proof)
+	require.NoError(t, proof.VerifyNamespace(Element(zero), absent, tree.Root()))
+}
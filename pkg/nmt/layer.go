@@ -45,26 +45,38 @@ type leafRange struct {
 	start, end int
 }
 
-func genleafLayer(ns NameSpaces) (Layer, map[string]leafRange) {
+func genleafLayer(ns NameSpaces, hasher Hasher) (Layer, map[string]leafRange, error) {
 	var (
 		leafLayer       = make(Layer, ns.Size())
 		namespaceRanges = make(map[string]leafRange)
 	)
 	i := 0
 	for _, namespace := range ns.ValidateAndSort() {
+		start := i
 		for _, rec := range ns.GetRecords(namespace) {
-			leafLayer[i] = DataToNode(ns.NamespaceSize(), rec)
+			if i >= len(leafLayer) {
+				return nil, nil, fmt.Errorf("%w: more records than the reported size %d", ErrInvalidRange, len(leafLayer))
+			}
+			node, err := DataToNode(ns.NamespaceSize(), rec, hasher)
+			if err != nil {
+				return nil, nil, fmt.Errorf("record %d: %w", i, err)
+			}
+			leafLayer[i] = node
 			i++
 		}
+		namespaceRanges[string(namespace)] = leafRange{start, i}
 	}
-	return leafLayer, namespaceRanges
+	if i != len(leafLayer) {
+		return nil, nil, fmt.Errorf("%w: %d records, reported size %d", ErrInvalidRange, i, len(leafLayer))
+	}
+	return leafLayer, namespaceRanges, nil
 }
 
 // calculateAbsenceIndex returns the index of a leaf of the tree that 1) its
 // namespace ID is the smallest namespace ID larger than nID and 2) the
 // namespace ID of the leaf to the left of it is smaller than the nID.
 // assuming leafLayer is sorted by namespace ID.
-func calculateAbsenceIndex(namespaceLen IDSize, nID ID, leafLayer Layer) int {
+func calculateAbsenceIndex(namespaceLen IDSize, nID ID, leafLayer Layer) (int, error) {
 	var prevLeaf Node
 	for index, curLeaf := range leafLayer {
 		if index == 0 {
@@ -79,14 +91,14 @@ func calculateAbsenceIndex(namespaceLen IDSize, nID ID, leafLayer Layer) int {
 		// would have found the namespace before.
 		// For leaf nodes MinNS or MaxNS is just it's NS.
 		if prevLeaf.MinNs(namespaceLen).Less(nID) && nID.Less(curLeaf.MinNs(namespaceLen)) {
-			return index
+			return index, nil
 		}
 
 		prevLeaf = curLeaf
 	}
 	// the case (nID < minNID) or (maxNID < nID) should be handled before
 	// calling this private helper!
-	panic("calculateAbsenceIndex() called although (nID < minNID) or (maxNID < nID) for provided nID")
+	return 0, fmt.Errorf("%w: %s is out of the range of the leaves", ErrInvalidNamespace, nID.String())
 }
 
 type Layer []Node
@@ -113,7 +125,7 @@ func (l Layer) Hashes(namespaceLen IDSize) []Element {
 func (l Layer) String(namespaceLen IDSize) string {
 	out := fmt.Sprintf("Num Of Nodes: %d \n", len(l))
 	for i := 0; i < len(l); i++ {
-		out += fmt.Sprintf("**** maxID: %s minID: %s hash: %s ****\n", l[i].MaxNs(32).String(), l[i].MinNs(32).String(), l[i].Hash(32).Hex())
+		out += fmt.Sprintf("**** %s ****\n", l[i].String(namespaceLen))
 	}
 	out += "\n"
 	return out
@@ -123,7 +135,10 @@ func GetLayerCount(level, numLeaves int) int {
 	return int(math.Ceil(float64(numLeaves) / math.Pow(2, float64(level))))
 }
 
-func BuildLayer(namespaceLen IDSize, hashFn HashFunction, nodeSize int, prevLayer Layer, zero Node) Layer {
+func BuildLayer(namespaceLen IDSize, hasher Hasher, nodeSize int, prevLayer Layer, zero Node) (Layer, error) {
+	if nodeSize*2 < len(prevLayer) || (nodeSize-1)*2 >= len(prevLayer) {
+		return nil, fmt.Errorf("%w: %d nodes from a layer of %d", ErrInvalidRange, nodeSize, len(prevLayer))
+	}
 	l := make(Layer, nodeSize)
 
 	prevLen := len(prevLayer)
@@ -138,10 +153,14 @@ func BuildLayer(namespaceLen IDSize, hashFn HashFunction, nodeSize int, prevLaye
 			zeroSide = 0
 		}
 
-		l[i] = BuildNode(namespaceLen, prevLayer[i*2], right, zeroSide, hashFn)
+		node, err := BuildNode(namespaceLen, prevLayer[i*2], right, zeroSide, hasher)
+		if err != nil {
+			return nil, err
+		}
+		l[i] = node
 	}
 
-	return l
+	return l, nil
 }
 
 type Layers []Layer
@@ -166,39 +185,55 @@ func (l Layers) GetLayer(level int) Layer {
 	return l[level]
 }
 
-func CalcRoot(namespaceLen IDSize, hashFn HashFunction, leafNodes Layer, zeroValue Element) (Node, int) {
+func CalcRoot(namespaceLen IDSize, hasher Hasher, leafNodes Layer, zeroValue Element) (Node, int, error) {
+	if len(leafNodes) == 0 {
+		return nil, 0, ErrEmptyTree
+	}
 
 	var (
 		numLeaves = len(leafNodes)
 		depth     = int(math.Ceil(math.Log2(float64(numLeaves)))) + 1
 		zero      = zeroValue
 		currLayer = leafNodes
+		err       error
 	)
 
 	for level := 1; level < depth; level++ {
-		currLayer = BuildLayer(namespaceLen, hashFn, GetLayerCount(level, numLeaves), currLayer, NodeValueFromZero(namespaceLen, zero))
-		zero = hashFn(zero, zero)
+		if currLayer, err = BuildLayer(namespaceLen, hasher, GetLayerCount(level, numLeaves), currLayer, NodeValueFromZero(namespaceLen, zero)); err != nil {
+			return nil, 0, err
+		}
+		if zero, err = hasher.HashZero(namespaceLen, zero); err != nil {
+			return nil, 0, err
+		}
 	}
-	return currLayer[0], depth - 1
+	return currLayer[0], depth - 1, nil
 }
 
-func BuildLayers(namespaceLen IDSize, hashFn HashFunction, leafNodes Layer, zeroValue Element) (Layers, []Element) {
+func BuildLayers(namespaceLen IDSize, hasher Hasher, leafNodes Layer, zeroValue Element) (Layers, []Element, error) {
+	if len(leafNodes) == 0 {
+		return nil, nil, ErrEmptyTree
+	}
 
 	var (
 		numLeaves = len(leafNodes)
 		depth     = int(math.Ceil(math.Log2(float64(numLeaves)))) + 1
 		layers    = make([]Layer, depth)
 		zeroes    = make([]Element, depth)
+		err       error
 	)
 
 	layers[0] = leafNodes
 	zeroes[0] = zeroValue
 
 	for level := 1; level < depth; level++ {
-		layers[level] = BuildLayer(namespaceLen, hashFn, GetLayerCount(level, numLeaves), layers[level-1], NodeValueFromZero(namespaceLen, zeroes[level-1]))
-		zeroes[level] = hashFn(zeroes[level-1], zeroes[level-1])
+		if layers[level], err = BuildLayer(namespaceLen, hasher, GetLayerCount(level, numLeaves), layers[level-1], NodeValueFromZero(namespaceLen, zeroes[level-1])); err != nil {
+			return nil, nil, err
+		}
+		if zeroes[level], err = hasher.HashZero(namespaceLen, zeroes[level-1]); err != nil {
+			return nil, nil, err
+		}
 	}
-	return layers, zeroes
+	return layers, zeroes, nil
 }
 
 // ProveNamespace returns a range proof for the given NamespaceID.
@@ -235,21 +270,34 @@ func BuildLayers(namespaceLen IDSize, hashFn HashFunction, leafNodes Layer, zero
 // the HashNode method in the Hasher.
 // Any error returned by this method is irrecoverable and indicates an illegal state of the tree (n).
 
-func ProveNamespace(ns NameSpaces, hashFn HashFunction, zeroValue Element, nID ID) (Proof, error) {
+func ProveNamespace(ns NameSpaces, hasher Hasher, zeroValue Element, nID ID) (Proof, error) {
 	if ns.Size() == 0 {
 		return NewEmptyRangeProof(), nil
 	}
 
-	leafLayer, leafRange := genleafLayer(ns)
+	leafLayer, leafRange, err := genleafLayer(ns, hasher)
+	if err != nil {
+		return Proof{}, err
+	}
+
+	layers, zeroes, err := BuildLayers(ns.NamespaceSize(), hasher, leafLayer, zeroValue)
+	if err != nil {
+		return Proof{}, err
+	}
+	return proveLayers(ns.NamespaceSize(), hasher.ID(), layers, zeroes, leafRange, nID)
+}
 
-	root, levels := CalcRoot(32, hashFn, leafLayer, zeroValue)
-	if levels == 0 || root == nil {
+// proveLayers returns the namespace proof of nID from the layers & zeroes
+// built by BuildLayers, refer to ProveNamespace
+func proveLayers(namespaceLen IDSize, hashID HashID, layers Layers, zeroes []Element, leafRange map[string]leafRange, nID ID) (Proof, error) {
+	if layers.Levels() == 0 {
 		return Proof{}, errors.New("failed to calculate root")
 	}
+	root := layers.GetRootNode()
 
 	// case 1) In the cases (n.nID < treeMinNs) or (treeMaxNs < nID), return empty
 	// range proof
-	if nID.Less(root.MinNs(ns.NamespaceSize())) || root.MaxNs(ns.NamespaceSize()).Less(nID) {
+	if nID.Less(root.MinNs(namespaceLen)) || root.MaxNs(namespaceLen).Less(nID) {
 		return NewEmptyRangeProof(), nil
 	}
 
@@ -262,26 +310,65 @@ func ProveNamespace(ns NameSpaces, hashFn HashFunction, zeroValue Element, nID I
 	if !found {
 		// To generate a proof for an absence we calculate the position of the
 		// leaf that is in the place of where the namespace would be in:
-		proofStart = calculateAbsenceIndex(ns.NamespaceSize(), nID, leafLayer)
+		var err error
+		if proofStart, err = calculateAbsenceIndex(namespaceLen, nID, layers[0]); err != nil {
+			return Proof{}, err
+		}
 		proofEnd = proofStart + 1
 	}
 
 	// case 3) At this point we either found leaves with the namespace nID in
 	// the tree or calculated the range it would be in (to generate a proof of
 	// absence and to return the corresponding leaf hashes).
-	pathLayers, err := BuildRangeProof(ns.NamespaceSize(), hashFn, leafLayer, zeroValue, proofStart, proofEnd)
+	pathLayers, err := rangeProof(namespaceLen, layers, zeroes, proofStart, proofEnd)
 	if err != nil {
 		return Proof{}, err
 	}
 
 	if found {
-		return NewInclusionProof(proofStart, proofEnd, pathLayers), nil
+		return NewInclusionProof(namespaceLen, hashID, proofStart, proofEnd, pathLayers), nil
 	}
 
-	return NewAbsenceProof(proofStart, proofEnd, pathLayers, leafLayer[proofStart]), nil
+	return NewAbsenceProof(namespaceLen, hashID, proofStart, proofEnd, pathLayers, layers[0][proofStart]), nil
 }
 
-func BuildRangeProof(namespaceLen IDSize, hashFn HashFunction, leafNodes Layer, zeroValue Element, proofStart, proofEnd int) (Layers, error) {
+// rangeProof collects the path layers of [proofStart, proofEnd) from the
+// layers & zeroes built by BuildLayers, refer to BuildRangeProof
+func rangeProof(namespaceLen IDSize, layers Layers, zeroes []Element, proofStart, proofEnd int) (Layers, error) {
+	if err := layers[0].ValidateRange(proofStart, proofEnd); err != nil {
+		return nil, err
+	}
+
+	var (
+		depth        = layers.Depth()
+		pathLayers   = make([]Layer, depth)
+		elStartIndex = proofStart
+		elEndIndex   = proofEnd
+	)
+	for level := 1; level < depth; level++ {
+		startIndex := elStartIndex - (elStartIndex % 2) // default arity is 2
+		endIndex := elEndIndex - (elEndIndex % 2) + 2
+
+		currLayer := layers[level-1]
+		for i := startIndex; i < endIndex; i++ {
+			if i < len(currLayer) {
+				pathLayers[level-1] = append(pathLayers[level-1], currLayer[i])
+			} else {
+				pathLayers[level-1] = append(pathLayers[level-1], NodeValueFromZero(namespaceLen, zeroes[level-1]))
+			}
+		}
+
+		elStartIndex >>= 1
+		elEndIndex >>= 1
+	}
+
+	// add root node
+	pathLayers[depth-1] = append(pathLayers[depth-1], layers.GetRootNode())
+
+	return pathLayers, nil
+}
+
+func BuildRangeProof(namespaceLen IDSize, hasher Hasher, leafNodes Layer, zeroValue Element, proofStart, proofEnd int) (Layers, error) {
 	var (
 		numLeaves    = len(leafNodes)
 		depth        = int(math.Ceil(math.Log2(float64(numLeaves)))) + 1
@@ -290,6 +377,7 @@ func BuildRangeProof(namespaceLen IDSize, hashFn HashFunction, leafNodes Layer,
 		elEndIndex   = proofEnd
 		zero         = zeroValue
 		currLayer    = leafNodes
+		err          error
 	)
 
 	if err := leafNodes.ValidateRange(proofStart, proofEnd); err != nil {
@@ -309,8 +397,12 @@ func BuildRangeProof(namespaceLen IDSize, hashFn HashFunction, leafNodes Layer,
 			}
 		}
 
-		currLayer = BuildLayer(namespaceLen, hashFn, GetLayerCount(level, numLeaves), currLayer, NodeValueFromZero(namespaceLen, zero))
-		zero = hashFn(zero, zero)
+		if currLayer, err = BuildLayer(namespaceLen, hasher, GetLayerCount(level, numLeaves), currLayer, NodeValueFromZero(namespaceLen, zero)); err != nil {
+			return nil, err
+		}
+		if zero, err = hasher.HashZero(namespaceLen, zero); err != nil {
+			return nil, err
+		}
 
 		elStartIndex >>= 1
 		elEndIndex >>= 1
@@ -322,20 +414,24 @@ func BuildRangeProof(namespaceLen IDSize, hashFn HashFunction, leafNodes Layer,
 	return pathLayers, nil
 }
 
-func VerifyRangeProof(namespaceLen IDSize, hashFn HashFunction, pathLayers Layers) bool {
+func VerifyRangeProof(namespaceLen IDSize, hasher Hasher, pathLayers Layers) bool {
 	// verify that the hashes of the nodes in pathlayers are correct
+	if len(pathLayers) == 0 {
+		return false
+	}
 	var (
 		numLeaves = len(pathLayers[0])
 		depth     = int(math.Ceil(math.Log2(float64(numLeaves)))) + 1
 	)
 	for level := 1; level < depth; level++ {
 		for i := 0; i < len(pathLayers[level]); i++ {
-			if i*2 >= len(pathLayers[level-1]) && i*2+1 >= len(pathLayers[level-1]) {
+			if i*2+1 >= len(pathLayers[level-1]) {
 				continue
 			}
 			left := pathLayers[level-1][i*2]
 			right := pathLayers[level-1][i*2+1]
-			if !pathLayers[level][i].Equal(BuildNode(namespaceLen, left, right, 0, hashFn)) {
+			node, err := BuildNode(namespaceLen, left, right, 0, hasher)
+			if err != nil || !pathLayers[level][i].Equal(node) {
 				return false
 			}
 		}
@@ -50,8 +50,10 @@ func Test_Layers_Build(t *testing.T) {
 	require.Equal(t, zeroNode.Hash(32).Hex(), hex.EncodeToString(zero))
 
 	start := time.Now()
-	leafNodes, _ := genleafLayer(nsgroup)
-	layers, _ := BuildLayers(32, Poseidon2, leafNodes, Element(zero))
+	leafNodes, _, err := genleafLayer(nsgroup, Poseidon2Hasher)
+	require.NoError(t, err)
+	layers, _, err := BuildLayers(32, Poseidon2Hasher, leafNodes, Element(zero))
+	require.NoError(t, err)
 	fmt.Printf("Time to generate layers, leaf size: %d .. %d levels .. took %dms \n", len(leafNodes), layers.Levels(), time.Since(start).Milliseconds())
 
 	// Create non-nmt Tree from another pkg to compare root
@@ -90,8 +92,10 @@ func Test_Layers_CalcRoot(t *testing.T) {
 	require.Equal(t, zeroNode.Hash(32).Hex(), hex.EncodeToString(zero))
 
 	start := time.Now()
-	leafNodes, _ := genleafLayer(nsgroup)
-	rootNode, level := CalcRoot(32, Poseidon2, leafNodes, Element(zero))
+	leafNodes, _, err := genleafLayer(nsgroup, Poseidon2Hasher)
+	require.NoError(t, err)
+	rootNode, level, err := CalcRoot(32, Poseidon2Hasher, leafNodes, Element(zero))
+	require.NoError(t, err)
 	require.NotNil(t, rootNode)
 	fmt.Printf("Time to calc root, leaf size: %d .. %d levels .. took %dms \n", len(leafNodes), level, time.Since(start).Milliseconds())
 
@@ -121,17 +125,36 @@ func Test_Layers_RangeProof(t *testing.T) {
 	require.Equal(t, zeroNode.Hash(32).Hex(), hex.EncodeToString(zero))
 
 	start := time.Now()
-	leafNodes, _ := genleafLayer(nsgroup)
-	pathLayers, err := BuildRangeProof(32, Poseidon2, leafNodes, Element(zero), testProofStart, testProofEnd)
+	leafNodes, _, err := genleafLayer(nsgroup, Poseidon2Hasher)
+	require.NoError(t, err)
+	pathLayers, err := BuildRangeProof(32, Poseidon2Hasher, leafNodes, Element(zero), testProofStart, testProofEnd)
 	require.NoError(t, err)
 	require.NotNil(t, pathLayers)
 	fmt.Printf("Time to calc root, leaf size: %d ..  took %dms \n", len(leafNodes), time.Since(start).Milliseconds())
 
-	rootNode, levels := CalcRoot(32, Poseidon2, leafNodes, Element(zero))
+	rootNode, levels, err := CalcRoot(32, Poseidon2Hasher, leafNodes, Element(zero))
+	require.NoError(t, err)
 
 	require.Equal(t, pathLayers.Levels(), levels, "Proof levels do not match")
 	// compare root
 	require.True(t, pathLayers.GetRootNode().Hash(32).BigInt().Cmp(rootNode.Hash(32).BigInt()) == 0, "Root hashes do not match")
 
-	require.True(t, VerifyRangeProof(32, Poseidon2, pathLayers), "Proof verification failed")
+	require.True(t, VerifyRangeProof(32, Poseidon2Hasher, pathLayers), "Proof verification failed")
+}
+
+func Test_CalculateAbsenceIndex_OutOfRange(t *testing.T) {
+	nsgroup := gen_ngs(t, 3, 1, true)
+	leafNodes, _, err := genleafLayer(nsgroup, PoseidonHasher)
+	require.NoError(t, err)
+
+	// smaller than every namespace in the tree
+	_, err = calculateAbsenceIndex(32, make(ID, 32), leafNodes)
+	require.ErrorIs(t, err, ErrInvalidNamespace)
+}
+
+func Test_BuildLayers_Empty(t *testing.T) {
+	_, _, err := BuildLayers(32, PoseidonHasher, Layer{}, Element{})
+	require.ErrorIs(t, err, ErrEmptyTree)
+	_, _, err = CalcRoot(32, PoseidonHasher, nil, Element{})
+	require.ErrorIs(t, err, ErrEmptyTree)
 }
@@ -10,7 +10,28 @@ import (
 // Hash is ElementSize bytes
 type Node []byte
 
+// NodeSize returns the byte size of a node with namespaceLen namespaces
+func NodeSize(namespaceLen IDSize) int {
+	return int(namespaceLen)*2 + ElementSize
+}
+
+// Validate checks that n is exactly NodeSize(namespaceLen) bytes
+// and that minNs <= maxNs. The accessors below return empty values
+// for a node that is too short, validate untrusted nodes first.
+func (n Node) Validate(namespaceLen IDSize) error {
+	if len(n) != NodeSize(namespaceLen) {
+		return fmt.Errorf("%w: got %d, want %d", ErrInvalidNodeLen, len(n), NodeSize(namespaceLen))
+	}
+	if n.MaxNs(namespaceLen).Less(n.MinNs(namespaceLen)) {
+		return fmt.Errorf("%w: maxNs < minNs", ErrInvalidNamespace)
+	}
+	return nil
+}
+
 func (n Node) String(namespaceLen IDSize) string {
+	if len(n) != NodeSize(namespaceLen) {
+		return fmt.Sprintf("Invalid Node: %s", n.Hex())
+	}
 	return fmt.Sprintf("Min: %s Max: %s Hash: %s", n.MinNs(namespaceLen).String(), n.MaxNs(namespaceLen).String(), n.Hash(namespaceLen).Hex())
 }
 
@@ -19,15 +40,24 @@ func (n Node) Hex() string {
 }
 
 func (n Node) MinNs(namespaceLen IDSize) ID {
+	if len(n) < int(namespaceLen) {
+		return nil
+	}
 	return ID(n[:namespaceLen])
 }
 
 func (n Node) MaxNs(namespaceLen IDSize) ID {
-	return ID(n[namespaceLen : namespaceLen*2])
+	if len(n) < int(namespaceLen)*2 {
+		return nil
+	}
+	return ID(n[namespaceLen : int(namespaceLen)*2])
 }
 
 func (n Node) Hash(namespaceLen IDSize) Element {
-	return Element(n[namespaceLen*2 : namespaceLen*2+ElementSize])
+	if len(n) < NodeSize(namespaceLen) {
+		return Element{}
+	}
+	return Element(n[int(namespaceLen)*2 : NodeSize(namespaceLen)])
 }
 
 func (n Node) Equal(other Node) bool {
@@ -50,13 +80,16 @@ func NodeValueFromZero(namespaceLen IDSize, zero Element) (out Node) {
 
 /*
 Hash the data according to nmt specs:
-  - hash = hash(data)
+  - hash = hasher.HashLeaf(data.NID, data.Hash)
   - minNs = data.NID
   - maxNs = data.NID
 
 Used to generate leaf nodes from set leaf data
 */
-func DataToNode(namespaceLen IDSize, data Record) (out Node) {
+func DataToNode(namespaceLen IDSize, data Record, hasher Hasher) (out Node, err error) {
+	if err := data.Validate(namespaceLen); err != nil {
+		return nil, err
+	}
 	out = make([]byte, namespaceLen*2+ElementSize)
 
 	nsID := data.NID(namespaceLen)
@@ -65,12 +98,15 @@ func DataToNode(namespaceLen IDSize, data Record) (out Node) {
 		out[i+int(namespaceLen)] = nsID[i]
 	}
 
-	hash := data.Hash(namespaceLen)
+	hash, err := hasher.HashLeaf(nsID, data.Hash(namespaceLen))
+	if err != nil {
+		return nil, err
+	}
 	for i := 0; i < ElementSize; i++ {
 		out[i+int(namespaceLen*2)] = hash[i]
 	}
 
-	return out
+	return out, nil
 }
 
 /*
@@ -79,7 +115,14 @@ Unless one of the node is a zero node, the zeroSide parameter should be set to 0
 If the left node is a zero node, the zeroSide parameter should be set to 1.
 If the right node is a zero node, the zeroSide parameter should be set to 2.
 */
-func BuildNode(namespaceLen IDSize, left Node, right Node, zeroSide int, hashFn HashFunction) (n Node) {
+func BuildNode(namespaceLen IDSize, left Node, right Node, zeroSide int, hasher Hasher) (n Node, err error) {
+	if err := left.Validate(namespaceLen); err != nil {
+		return nil, fmt.Errorf("left node: %w", err)
+	}
+	if err := right.Validate(namespaceLen); err != nil {
+		return nil, fmt.Errorf("right node: %w", err)
+	}
+
 	n = make([]byte, (namespaceLen*2)+ElementSize)
 
 	nsOffset := int(namespaceLen)
@@ -101,11 +144,14 @@ func BuildNode(namespaceLen IDSize, left Node, right Node, zeroSide int, hashFn
 		}
 
 	}
-	hash := hashFn(left.Hash(namespaceLen), right.Hash(namespaceLen))
+	hash, err := hasher.HashNode(n.MinNs(namespaceLen), n.MaxNs(namespaceLen), left.Hash(namespaceLen), right.Hash(namespaceLen))
+	if err != nil {
+		return nil, err
+	}
 
 	for i := 0; i < ElementSize; i++ {
 		n[nsOffset*2+i] = hash[i]
 	}
 
-	return n
+	return n, nil
 }
@@ -26,7 +26,8 @@ func Test_DataToNode(t *testing.T) {
 
 	for _, nsgroup := range nsgroups.ngs {
 		for _, rec := range nsgroup {
-			node := DataToNode(32, rec)
+			node, err := DataToNode(32, rec, Poseidon2Hasher)
+			require.NoError(t, err)
 			require.Equal(t, rec.Hash(32).Hex(), node.Hash(32).Hex())
 			require.Equal(t, rec.NID(32).String(), node.MinNs(32).String())
 			require.Equal(t, rec.NID(32).String(), node.MaxNs(32).String())
@@ -39,11 +40,14 @@ func Test_BuildNode_NonZero_NonZero(t *testing.T) {
 	testRecordSize := 1
 	nsgroups := gen_ngs(t, testGroupSize, testRecordSize, true)
 
-	allNodes, _ := genleafLayer(nsgroups)
+	allNodes, _, err := genleafLayer(nsgroups, Poseidon2Hasher)
+	require.NoError(t, err)
 
-	newNode := BuildNode(32, allNodes[0], allNodes[1], 0, Poseidon2)
+	newNode, err := BuildNode(32, allNodes[0], allNodes[1], 0, Poseidon2Hasher)
+	require.NoError(t, err)
 
-	expectedHash := Poseidon2(allNodes[0].Hash(32), allNodes[1].Hash(32))
+	expectedHash, err := Poseidon2(allNodes[0].Hash(32), allNodes[1].Hash(32))
+	require.NoError(t, err)
 	require.NotEqual(t, "0000000000000000000000000000000000000000000000000000000000000000", expectedHash.Hex(), "Hashes should not 0")
 
 	expectedMinID := nsgroups.namespaces[0]
@@ -64,11 +68,14 @@ func Test_BuildNode_Zero_NonZero(t *testing.T) {
 
 	nsgroups := gen_ngs(t, 1, 1, true)
 
-	allNodes, _ := genleafLayer(nsgroups)
+	allNodes, _, err := genleafLayer(nsgroups, Poseidon2Hasher)
+	require.NoError(t, err)
 
-	newNode := BuildNode(32, zeroNode, allNodes[0], 1, Poseidon2)
+	newNode, err := BuildNode(32, zeroNode, allNodes[0], 1, Poseidon2Hasher)
+	require.NoError(t, err)
 
-	expectedHash := Poseidon2(zeroNode.Hash(32), allNodes[0].Hash(32))
+	expectedHash, err := Poseidon2(zeroNode.Hash(32), allNodes[0].Hash(32))
+	require.NoError(t, err)
 	require.NotEqual(t, "0000000000000000000000000000000000000000000000000000000000000000", expectedHash.Hex(), "Hashes should not 0")
 
 	expectedMinID := nsgroups.namespaces[0]
@@ -89,11 +96,14 @@ func Test_BuildNode_NonZero_Zero(t *testing.T) {
 
 	nsgroups := gen_ngs(t, 1, 1, true)
 
-	allNodes, _ := genleafLayer(nsgroups)
+	allNodes, _, err := genleafLayer(nsgroups, Poseidon2Hasher)
+	require.NoError(t, err)
 
-	newNode := BuildNode(32, allNodes[0], zeroNode, 2, Poseidon2)
+	newNode, err := BuildNode(32, allNodes[0], zeroNode, 2, Poseidon2Hasher)
+	require.NoError(t, err)
 
-	expectedHash := Poseidon2(allNodes[0].Hash(32), zeroNode.Hash(32))
+	expectedHash, err := Poseidon2(allNodes[0].Hash(32), zeroNode.Hash(32))
+	require.NoError(t, err)
 	require.NotEqual(t, "0000000000000000000000000000000000000000000000000000000000000000", expectedHash.Hex(), "Hashes should not 0")
 
 	expectedMinID := nsgroups.namespaces[0]
@@ -104,3 +114,60 @@ func Test_BuildNode_NonZero_Zero(t *testing.T) {
 	require.Equal(t, expectedMaxID.String(), newNode.MaxNs(32).String(), "Max IDs do not match")
 
 }
+
+func Test_Node_Validate(t *testing.T) {
+	zero, err := hex.DecodeString(MerkleZeroHex)
+	require.NoError(t, err)
+	zeroNode := NodeValueFromZero(32, Element(zero))
+
+	require.NoError(t, zeroNode.Validate(32))
+	require.ErrorIs(t, zeroNode[:40].Validate(32), ErrInvalidNodeLen)
+	require.ErrorIs(t, Node(nil).Validate(32), ErrInvalidNodeLen)
+
+	// maxNs < minNs
+	bad := NodeValueFromZero(32, Element(zero))
+	bad[0] = 1
+	require.ErrorIs(t, bad.Validate(32), ErrInvalidNamespace)
+
+	_, err = BuildNode(32, zeroNode, zeroNode[:40], 0, Poseidon2Hasher)
+	require.ErrorIs(t, err, ErrInvalidNodeLen)
+
+	// accessors of short nodes & records don't panic
+	require.Len(t, zeroNode[:40].MinNs(32), 32)
+	require.Nil(t, zeroNode[:40].MaxNs(32))
+	require.Equal(t, Element{}, zeroNode[:90].Hash(32))
+	require.Nil(t, Node(nil).MinNs(32))
+	require.Nil(t, Record(zeroNode[:10]).NID(32))
+	require.Equal(t, Element{}, Record(zeroNode[:40]).Hash(32))
+	require.Nil(t, Record(zeroNode[:40]).Data(32))
+}
+
+func Test_DataToNode_Invalid(t *testing.T) {
+	_, err := DataToNode(32, Record(make([]byte, 40)), PoseidonHasher)
+	require.ErrorIs(t, err, ErrInvalidLeafLen)
+
+	// record hash outside of the field
+	rec := make(Record, 64)
+	for i := 32; i < 64; i++ {
+		rec[i] = 0xff
+	}
+	_, err = DataToNode(32, rec, NsPoseidonHasher)
+	require.ErrorIs(t, err, ErrNotInField)
+}
+
+func Test_HashFunction_NotInField(t *testing.T) {
+	var max Element
+	for i := range max {
+		max[i] = 0xff
+	}
+	for _, fn := range []HashFunction{Poseidon, Poseidon2, MIMC7} {
+		_, err := fn(max, Element{})
+		require.ErrorIs(t, err, ErrNotInField)
+		_, err = fn(Element{}, max)
+		require.ErrorIs(t, err, ErrNotInField)
+	}
+
+	// sha256 accepts any input
+	_, err := SHA256Hash(max, max)
+	require.NoError(t, err)
+}
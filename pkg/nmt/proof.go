@@ -11,6 +11,9 @@ var ErrFailedCompletenessCheck = errors.New("failed completeness check")
 // proof proves the absence of a namespace.ID in a tree it also contains the
 // leaf hashes of the range where that namespace would be.
 type Proof struct {
+	// namespace size & hash function of the tree the proof was generated from
+	nsSize IDSize
+	hashID HashID
 
 	// start index of the leaves that match the queried namespace.ID.
 	start int
@@ -37,19 +40,54 @@ type Proof struct {
 // NewEmptyRangeProof constructs a proof that proves that a namespace.ID does
 // not fall within the range of an NMT.
 func NewEmptyRangeProof() Proof {
-	return Proof{0, 0, nil, nil}
+	return Proof{0, HashUnknown, 0, 0, nil, nil}
 }
 
 // NewInclusionProof constructs a proof that proves that a namespace.ID is
 // included in an NMT.
-func NewInclusionProof(proofStart, proofEnd int, pathLayers Layers) Proof {
-	return Proof{proofStart, proofEnd, pathLayers, nil}
+func NewInclusionProof(nsSize IDSize, hashID HashID, proofStart, proofEnd int, pathLayers Layers) Proof {
+	return Proof{nsSize, hashID, proofStart, proofEnd, pathLayers, nil}
 }
 
 // NewAbsenceProof constructs a proof that proves that a namespace.ID falls
 // within the range of an NMT but no leaf with that namespace.ID is included.
-func NewAbsenceProof(proofStart, proofEnd int, pathLayers Layers, leafHash []byte) Proof {
-	return Proof{proofStart, proofEnd, pathLayers, leafHash}
+func NewAbsenceProof(nsSize IDSize, hashID HashID, proofStart, proofEnd int, pathLayers Layers, leafHash []byte) Proof {
+	return Proof{nsSize, hashID, proofStart, proofEnd, pathLayers, leafHash}
+}
+
+// NamespaceSize returns the namespace size of the tree the proof was generated from.
+func (proof Proof) NamespaceSize() IDSize {
+	return proof.nsSize
+}
+
+// HashID returns the id of the hash function used to build the tree.
+func (proof Proof) HashID() HashID {
+	return proof.hashID
+}
+
+// Start returns the start index of the proven range.
+func (proof Proof) Start() int {
+	return proof.start
+}
+
+// End returns the end index (non-inclusive) of the proven range.
+func (proof Proof) End() int {
+	return proof.end
+}
+
+// PathLayers returns the tree nodes of the range proof.
+func (proof Proof) PathLayers() Layers {
+	return proof.pathLayers
+}
+
+// LeafHash returns the leaf needed to verify a proof of absence.
+func (proof Proof) LeafHash() []byte {
+	return proof.leafHash
+}
+
+// IsAbsenceProof checks whether the proof proves the absence of a namespace.ID.
+func (proof Proof) IsAbsenceProof() bool {
+	return len(proof.leafHash) > 0
 }
 
 // IsEmptyProof checks whether the proof corresponds to an empty proof as defined in NMT specifications https://github.com/celestiaorg/nmt/blob/master/docs/spec/nmt.md.
@@ -12,7 +12,7 @@ import (
 
 func gen_ngs(t *testing.T, groupSize int, recordSize int, withSort bool) (group *NsGroups) {
 	// test group with namespace length 32 bytes
-	group = newNsGroups(32)
+	group = NewNsGroups(32)
 
 	for _, ns := range GenRandomPublicIds(groupSize) {
 		records, err := GenRandomRecords(ns, recordSize)
@@ -7,20 +7,41 @@ import (
 
 type Record []byte
 
+// Validate checks that r holds at least a namespace & a record hash.
+// The accessors below return empty values for a record that is too short.
+func (r Record) Validate(namespaceLen IDSize) error {
+	if len(r) < int(namespaceLen)+ElementSize {
+		return fmt.Errorf("%w: got %d, want at least %d", ErrInvalidLeafLen, len(r), int(namespaceLen)+ElementSize)
+	}
+	return nil
+}
+
 func (r Record) String(namespaceLen IDSize) string {
+	if r.Validate(namespaceLen) != nil {
+		return fmt.Sprintf("Invalid Record: %s", r.Hex())
+	}
 	return fmt.Sprintf("NID: %s Hash: %s", r.NID(namespaceLen).String(), r.Hash(namespaceLen).Hex())
 }
 
 func (r Record) NID(namespaceLen IDSize) ID {
+	if len(r) < int(namespaceLen) {
+		return nil
+	}
 	return ID(r[:namespaceLen])
 }
 
 func (r Record) Hash(namespaceLen IDSize) Element {
-	return Element(r[namespaceLen : namespaceLen+ElementSize])
+	if r.Validate(namespaceLen) != nil {
+		return Element{}
+	}
+	return Element(r[namespaceLen : int(namespaceLen)+ElementSize])
 }
 
 func (r Record) Data(namespaceLen IDSize) []byte {
-	return r[namespaceLen+ElementSize:]
+	if r.Validate(namespaceLen) != nil {
+		return nil
+	}
+	return r[int(namespaceLen)+ElementSize:]
 }
 
 func (r Record) Hex() string {
@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.20;
+
+/// circomlib Poseidon contract with 2 inputs (t = 3)
+interface IPoseidonT3 {
+    function poseidon(uint256[2] calldata input) external pure returns (uint256);
+}
+
+/*
+    Verifies namespace inclusion proofs generated by pkg/nmt (ProveNamespace)
+    for trees built with 32 byte namespaces and nmt.NsPoseidonHasher.
+
+    Nodes are namespaced: minNs || maxNs || digest, where
+        minNs  = left.minNs
+        maxNs  = right.maxNs, or left.maxNs when right is a padding node
+        digest = H(H(1, H(ns(minNs), ns(maxNs))), H(left.digest, right.digest))
+        ns(x)  = H(H(0, x >> 128), x & (2^128 - 1))
+    and H is the circomlib Poseidon hash with 2 inputs.
+
+    Incomplete layers are padded with NodeValueFromZero(zeros[level])
+    where zeros[0] is the configured zero value and
+    zeros[level + 1] = H(H(1, H(ns(0), ns(0))), H(zeros[level], zeros[level])).
+
+    proof.pathLayers mirrors nmt.Proof: layer i holds the nodes of level i
+    needed to recompute the range [start, end), starting at the even
+    aligned index of the range. The last layer holds the root.
+*/
+contract NMTVerifier {
+    struct Node {
+        bytes32 minNs;
+        bytes32 maxNs;
+        uint256 digest;
+    }
+
+    struct Proof {
+        uint256 start;
+        uint256 end;
+        Node[][] pathLayers;
+    }
+
+    IPoseidonT3 public immutable hasher;
+    uint256 public immutable zeroValue;
+
+    constructor(IPoseidonT3 _hasher, uint256 _zeroValue) {
+        hasher = _hasher;
+        zeroValue = _zeroValue;
+    }
+
+    function isPadding(Node calldata n, uint256 zero) internal pure returns (bool) {
+        return n.minNs == bytes32(0) && n.maxNs == bytes32(0) && n.digest == zero;
+    }
+
+    function hashNamespace(bytes32 ns) internal view returns (uint256) {
+        uint256 x = uint256(ns);
+        return hasher.poseidon([hasher.poseidon([uint256(0), x >> 128]), x & type(uint128).max]);
+    }
+
+    function hashDigest(bytes32 minNs, bytes32 maxNs, uint256 left, uint256 right) internal view returns (uint256) {
+        uint256 nsRange = hasher.poseidon([hashNamespace(minNs), hashNamespace(maxNs)]);
+        return hasher.poseidon([hasher.poseidon([uint256(1), nsRange]), hasher.poseidon([left, right])]);
+    }
+
+    function hashNode(Node calldata left, Node calldata right, uint256 zero) internal view returns (Node memory n) {
+        n.minNs = left.minNs;
+        n.maxNs = isPadding(right, zero) ? left.maxNs : right.maxNs;
+        n.digest = hashDigest(n.minNs, n.maxNs, left.digest, right.digest);
+    }
+
+    /// @notice checks that all leaves of namespace nID are within
+    /// [proof.start, proof.end) of the tree with the given root digest
+    function verifyInclusion(uint256 root, bytes32 nID, Proof calldata proof) external view returns (bool) {
+        uint256 depth = proof.pathLayers.length;
+        if (depth < 2 || proof.start >= proof.end) return false;
+
+        Node[] calldata top = proof.pathLayers[depth - 1];
+        if (top.length != 1 || top[0].digest != root) return false;
+
+        uint256 first = proof.start;
+        uint256 last = proof.end - 1;
+
+        // leaves within the range must belong to the namespace
+        Node[] calldata leaves = proof.pathLayers[0];
+        uint256 offset = first - (first % 2);
+        if (last - offset >= leaves.length) return false;
+        for (uint256 i = first; i <= last; i++) {
+            if (leaves[i - offset].minNs != nID || leaves[i - offset].maxNs != nID) return false;
+        }
+
+        uint256 zero = zeroValue;
+        for (uint256 level = 1; level < depth; level++) {
+            Node[] calldata children = proof.pathLayers[level - 1];
+            Node[] calldata parents = proof.pathLayers[level];
+            uint256 childOffset = first - (first % 2);
+
+            // completeness: siblings bordering the range hold other namespaces
+            if (first % 2 == 1 && children[first - 1 - childOffset].maxNs >= nID) return false;
+            if (last % 2 == 0) {
+                if (last + 1 - childOffset >= children.length) return false;
+                Node calldata right = children[last + 1 - childOffset];
+                if (!isPadding(right, zero) && right.minNs <= nID) return false;
+            }
+
+            first >>= 1;
+            last >>= 1;
+            uint256 parentOffset = first - (first % 2);
+            if (2 * last + 1 - childOffset >= children.length || last - parentOffset >= parents.length) return false;
+
+            for (uint256 p = first; p <= last; p++) {
+                Node memory expected = hashNode(children[2 * p - childOffset], children[2 * p + 1 - childOffset], zero);
+                Node calldata actual = parents[p - parentOffset];
+                if (
+                    expected.minNs != actual.minNs || expected.maxNs != actual.maxNs || expected.digest != actual.digest
+                ) return false;
+            }
+
+            zero = hashDigest(bytes32(0), bytes32(0), zero, zero);
+        }
+        return true;
+    }
+}
@@ -0,0 +1,67 @@
+package solidity
+
+// NMTVerifierABI is the ABI of NMTVerifier.sol
+const NMTVerifierABI = `[
+	{
+		"type": "constructor",
+		"inputs": [
+			{"name": "_hasher", "type": "address", "internalType": "contract IPoseidonT3"},
+			{"name": "_zeroValue", "type": "uint256", "internalType": "uint256"}
+		],
+		"stateMutability": "nonpayable"
+	},
+	{
+		"type": "function",
+		"name": "hasher",
+		"inputs": [],
+		"outputs": [{"name": "", "type": "address", "internalType": "contract IPoseidonT3"}],
+		"stateMutability": "view"
+	},
+	{
+		"type": "function",
+		"name": "zeroValue",
+		"inputs": [],
+		"outputs": [{"name": "", "type": "uint256", "internalType": "uint256"}],
+		"stateMutability": "view"
+	},
+	{
+		"type": "function",
+		"name": "verifyInclusion",
+		"inputs": [
+			{"name": "root", "type": "uint256", "internalType": "uint256"},
+			{"name": "nID", "type": "bytes32", "internalType": "bytes32"},
+			{
+				"name": "proof",
+				"type": "tuple",
+				"internalType": "struct NMTVerifier.Proof",
+				"components": [
+					{"name": "start", "type": "uint256", "internalType": "uint256"},
+					{"name": "end", "type": "uint256", "internalType": "uint256"},
+					{
+						"name": "pathLayers",
+						"type": "tuple[][]",
+						"internalType": "struct NMTVerifier.Node[][]",
+						"components": [
+							{"name": "minNs", "type": "bytes32", "internalType": "bytes32"},
+							{"name": "maxNs", "type": "bytes32", "internalType": "bytes32"},
+							{"name": "digest", "type": "uint256", "internalType": "uint256"}
+						]
+					}
+				]
+			}
+		],
+		"outputs": [{"name": "", "type": "bool", "internalType": "bool"}],
+		"stateMutability": "view"
+	}
+]`
+
+// PoseidonT3ABI is the ABI of the circomlib Poseidon contract with 2 inputs
+const PoseidonT3ABI = `[
+	{
+		"type": "function",
+		"name": "poseidon",
+		"inputs": [{"name": "input", "type": "uint256[2]", "internalType": "uint256[2]"}],
+		"outputs": [{"name": "", "type": "uint256", "internalType": "uint256"}],
+		"stateMutability": "pure"
+	}
+]`
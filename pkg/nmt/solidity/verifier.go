@@ -0,0 +1,159 @@
+package solidity
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	nmt "github.com/0xBow-io/base-eas-asp/pkg/nmt"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+// NMTVerifier.sol only supports 32 byte namespaces
+// and trees hashed with the namespaced circomlib Poseidon
+const (
+	NamespaceSize = nmt.IDSize(32)
+	HashID        = nmt.HashNsPoseidon
+)
+
+var (
+	Hasher = nmt.NsPoseidonHasher
+
+	ErrUnsupportedProof = errors.New("proof not supported by NMTVerifier")
+
+	verifierABI, _ = abi.JSON(strings.NewReader(NMTVerifierABI))
+)
+
+// Node mirrors NMTVerifier.Node
+type Node struct {
+	MinNs  [32]byte
+	MaxNs  [32]byte
+	Digest *big.Int
+}
+
+// Proof mirrors NMTVerifier.Proof
+type Proof struct {
+	Start      *big.Int
+	End        *big.Int
+	PathLayers [][]Node
+}
+
+func NewNode(n nmt.Node) (out Node, err error) {
+	if len(n) != int(NamespaceSize)*2+nmt.ElementSize {
+		return out, fmt.Errorf("%w: node size %d", ErrUnsupportedProof, len(n))
+	}
+	copy(out.MinNs[:], n.MinNs(NamespaceSize))
+	copy(out.MaxNs[:], n.MaxNs(NamespaceSize))
+	out.Digest = n.Hash(NamespaceSize).BigInt()
+	return out, nil
+}
+
+// ToNmt converts the node back to its nmt representation
+func (n Node) ToNmt() nmt.Node {
+	out := make(nmt.Node, 0, int(NamespaceSize)*2+nmt.ElementSize)
+	out = append(out, n.MinNs[:]...)
+	out = append(out, n.MaxNs[:]...)
+	digest := nmt.ToElement(n.Digest.Bytes())
+	return append(out, digest[:]...)
+}
+
+// NewProof ABI encodes an inclusion proof generated by nmt.ProveNamespace
+func NewProof(p nmt.Proof) (out Proof, err error) {
+	if p.IsEmptyProof() || p.IsAbsenceProof() {
+		return out, fmt.Errorf("%w: not an inclusion proof", ErrUnsupportedProof)
+	}
+	if p.NamespaceSize() != NamespaceSize {
+		return out, fmt.Errorf("%w: namespace size %d", ErrUnsupportedProof, p.NamespaceSize())
+	}
+	if p.HashID() != HashID {
+		return out, fmt.Errorf("%w: hash function %d", ErrUnsupportedProof, p.HashID())
+	}
+
+	out.Start = big.NewInt(int64(p.Start()))
+	out.End = big.NewInt(int64(p.End()))
+	out.PathLayers = make([][]Node, p.PathLayers().Depth())
+	for i, layer := range p.PathLayers() {
+		out.PathLayers[i] = make([]Node, len(layer))
+		for j, n := range layer {
+			if out.PathLayers[i][j], err = NewNode(n); err != nil {
+				return out, err
+			}
+		}
+	}
+	return out, nil
+}
+
+func namespaceToBytes32(nID nmt.ID) (out [32]byte, err error) {
+	if nID.Size() != int(NamespaceSize) {
+		return out, fmt.Errorf("%w: namespace size %d", ErrUnsupportedProof, nID.Size())
+	}
+	copy(out[:], nID)
+	return out, nil
+}
+
+// PackVerifyInclusion returns the calldata of NMTVerifier.verifyInclusion
+func PackVerifyInclusion(root nmt.Element, nID nmt.ID, p Proof) ([]byte, error) {
+	ns, err := namespaceToBytes32(nID)
+	if err != nil {
+		return nil, err
+	}
+	return verifierABI.Pack("verifyInclusion", root.BigInt(), ns, p)
+}
+
+// UnpackVerifyInclusion decodes calldata of NMTVerifier.verifyInclusion
+func UnpackVerifyInclusion(data []byte) (root nmt.Element, nID nmt.ID, p Proof, err error) {
+	method := verifierABI.Methods["verifyInclusion"]
+	if len(data) < 4 || !strings.EqualFold(common.Bytes2Hex(data[:4]), common.Bytes2Hex(method.ID)) {
+		return root, nil, p, errors.New("not a verifyInclusion call")
+	}
+
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return root, nil, p, err
+	}
+
+	root = nmt.ToElement(args[0].(*big.Int).Bytes())
+	ns := args[1].([32]byte)
+	p = *abi.ConvertType(args[2], new(Proof)).(*Proof)
+	return root, nmt.ID(ns[:]), p, nil
+}
+
+// NMTVerifier is a binding to a deployed NMTVerifier contract
+type NMTVerifier struct {
+	Address  common.Address
+	contract *bind.BoundContract
+}
+
+func NewNMTVerifier(address common.Address, backend bind.ContractBackend) *NMTVerifier {
+	return &NMTVerifier{
+		Address:  address,
+		contract: bind.NewBoundContract(address, verifierABI, backend, backend, backend),
+	}
+}
+
+// DeployNMTVerifier deploys the compiled NMTVerifier bytecode
+func DeployNMTVerifier(auth *bind.TransactOpts, backend bind.ContractBackend, bytecode []byte, hasher common.Address, zeroValue nmt.Element) (*NMTVerifier, *types.Transaction, error) {
+	address, tx, contract, err := bind.DeployContract(auth, verifierABI, bytecode, backend, hasher, zeroValue.BigInt())
+	if err != nil {
+		return nil, nil, err
+	}
+	return &NMTVerifier{Address: address, contract: contract}, tx, nil
+}
+
+// VerifyInclusion calls NMTVerifier.verifyInclusion
+func (v *NMTVerifier) VerifyInclusion(opts *bind.CallOpts, root nmt.Element, nID nmt.ID, p Proof) (bool, error) {
+	ns, err := namespaceToBytes32(nID)
+	if err != nil {
+		return false, err
+	}
+
+	var out []interface{}
+	if err := v.contract.Call(opts, &out, "verifyInclusion", root.BigInt(), ns, p); err != nil {
+		return false, err
+	}
+	return *abi.ConvertType(out[0], new(bool)).(*bool), nil
+}
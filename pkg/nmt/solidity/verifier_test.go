@@ -0,0 +1,183 @@
+package solidity
+
+import (
+	"context"
+	"encoding/hex"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	mock "github.com/0xBow-io/base-eas-asp/pkg/mock"
+	nmt "github.com/0xBow-io/base-eas-asp/pkg/nmt"
+	pp "github.com/0xBow-io/base-eas-asp/pkg/privacy_pool"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/stretchr/testify/require"
+)
+
+type testGroups struct {
+	namespaces []nmt.ID
+	records    map[string]nmt.NameSpaceGroup
+	size       int
+}
+
+func (g *testGroups) Size() int                               { return g.size }
+func (g *testGroups) ValidateAndSort() []nmt.ID               { return g.namespaces }
+func (g *testGroups) GetRecords(ns nmt.ID) nmt.NameSpaceGroup { return g.records[ns.String()] }
+func (g *testGroups) NamespaceSize() nmt.IDSize               { return NamespaceSize }
+
+// namespaces are generated in ascending order
+func genTestGroups(t *testing.T, groupSize, recordSize int) *testGroups {
+	g := &testGroups{records: make(map[string]nmt.NameSpaceGroup)}
+	for i := 0; i < groupSize; i++ {
+		ns := common.BigToHash(new(big.Int).Lsh(big.NewInt(int64(i+1)), 100))
+		for j := 0; j < recordSize; j++ {
+			e := pp.Event{
+				TxHash: common.BytesToHash(mock.GenRandomHash(31)),
+				From:   ns,
+				To:     common.BytesToHash(mock.GenRandomHash(20)),
+			}
+			se, err := e.Serialize()
+			require.NoError(t, err)
+			g.records[nmt.ID(ns[:]).String()] = append(g.records[nmt.ID(ns[:]).String()], nmt.Record(se[:]))
+			g.size++
+		}
+		g.namespaces = append(g.namespaces, nmt.ID(ns[:]))
+	}
+	return g
+}
+
+func genTestProof(t *testing.T, g *testGroups, nID nmt.ID) (nmt.Proof, nmt.Element, nmt.Element) {
+	zero, err := hex.DecodeString(nmt.MerkleZeroHex)
+	require.NoError(t, err)
+
+	p, err := nmt.ProveNamespace(g, Hasher, nmt.Element(zero), nID)
+	require.NoError(t, err)
+
+	root := p.PathLayers().GetRootNode()
+	require.NoError(t, p.VerifyNamespace(nmt.Element(zero), nID, root))
+	return p, root.Hash(NamespaceSize), nmt.Element(zero)
+}
+
+func Test_Proof_Pack_RoundTrip(t *testing.T) {
+	g := genTestGroups(t, 5, 3)
+	nID := g.namespaces[2]
+	p, root, _ := genTestProof(t, g, nID)
+
+	solProof, err := NewProof(p)
+	require.NoError(t, err)
+	require.Equal(t, int64(p.Start()), solProof.Start.Int64())
+	require.Equal(t, int64(p.End()), solProof.End.Int64())
+
+	data, err := PackVerifyInclusion(root, nID, solProof)
+	require.NoError(t, err)
+
+	gotRoot, gotNs, gotProof, err := UnpackVerifyInclusion(data)
+	require.NoError(t, err)
+	require.True(t, root.Eq(gotRoot))
+	require.True(t, nID.Equal(gotNs))
+	require.Equal(t, solProof.Start.Int64(), gotProof.Start.Int64())
+	require.Equal(t, solProof.End.Int64(), gotProof.End.Int64())
+	require.Equal(t, len(p.PathLayers()), len(gotProof.PathLayers))
+	for i, layer := range p.PathLayers() {
+		require.Equal(t, len(layer), len(gotProof.PathLayers[i]))
+		for j, n := range layer {
+			require.True(t, n.Equal(gotProof.PathLayers[i][j].ToNmt()), "layer: %d index: %d", i, j)
+		}
+	}
+}
+
+func Test_NewProof_Unsupported(t *testing.T) {
+	_, err := NewProof(nmt.NewEmptyRangeProof())
+	require.ErrorIs(t, err, ErrUnsupportedProof)
+
+	g := genTestGroups(t, 2, 1)
+	p, _, _ := genTestProof(t, g, g.namespaces[0])
+
+	_, err = NewProof(nmt.NewInclusionProof(NamespaceSize, nmt.HashPoseidon, p.Start(), p.End(), p.PathLayers()))
+	require.ErrorIs(t, err, ErrUnsupportedProof)
+
+	_, err = PackVerifyInclusion(nmt.Element{}, nmt.ID{0x01}, Proof{})
+	require.ErrorIs(t, err, ErrUnsupportedProof)
+}
+
+func loadBytecode(t *testing.T, path string) []byte {
+	b, err := os.ReadFile(path)
+	require.NoError(t, err, "missing contract artifact %s, run `make all` to build it", path)
+	return common.FromHex(strings.TrimSpace(string(b)))
+}
+
+// Cross-checks NMTVerifier.sol against nmt.Proof.VerifyNamespace
+// Uses the artifacts built by the makefile
+func Test_NMTVerifier_SimulatedBackend(t *testing.T) {
+	verifierBin := loadBytecode(t, "build/NMTVerifier.bin")
+	poseidonBin := loadBytecode(t, "build/PoseidonT3.bin")
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	deployer := crypto.PubkeyToAddress(key.PublicKey)
+
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		deployer: {Balance: new(big.Int).Lsh(big.NewInt(1), 100)},
+	})
+	defer backend.Close()
+	client := backend.Client()
+
+	chainID, err := client.ChainID(context.Background())
+	require.NoError(t, err)
+	auth, err := bind.NewKeyedTransactorWithChainID(key, chainID)
+	require.NoError(t, err)
+
+	// deploy poseidon
+	tx := types.NewContractCreation(0, big.NewInt(0), 5_000_000, big.NewInt(1_000_000_000), poseidonBin)
+	tx, err = auth.Signer(deployer, tx)
+	require.NoError(t, err)
+	require.NoError(t, client.SendTransaction(context.Background(), tx))
+	backend.Commit()
+	hasher := crypto.CreateAddress(deployer, 0)
+
+	g := genTestGroups(t, 7, 2)
+	_, _, zero := genTestProof(t, g, g.namespaces[0])
+
+	auth.Nonce = big.NewInt(1)
+	verifier, _, err := DeployNMTVerifier(auth, client, verifierBin, hasher, zero)
+	require.NoError(t, err)
+	backend.Commit()
+
+	for _, nID := range g.namespaces {
+		p, root, _ := genTestProof(t, g, nID)
+		solProof, err := NewProof(p)
+		require.NoError(t, err)
+
+		ok, err := verifier.VerifyInclusion(nil, root, nID, solProof)
+		require.NoError(t, err)
+		require.True(t, ok, "namespace %s", nID.String())
+
+		// proof for another namespace must be rejected
+		other := g.namespaces[(nsIndex(g, nID)+1)%len(g.namespaces)]
+		ok, err = verifier.VerifyInclusion(nil, root, other, solProof)
+		require.NoError(t, err)
+		require.False(t, ok)
+		require.Error(t, p.VerifyNamespace(zero, other, p.PathLayers().GetRootNode()))
+
+		// as must a proof omitting leaves of the namespace
+		partial := solProof
+		partial.End = new(big.Int).Sub(solProof.End, big.NewInt(1))
+		ok, err = verifier.VerifyInclusion(nil, root, nID, partial)
+		require.NoError(t, err)
+		require.False(t, ok)
+	}
+}
+
+func nsIndex(g *testGroups, nID nmt.ID) int {
+	for i, ns := range g.namespaces {
+		if ns.Equal(nID) {
+			return i
+		}
+	}
+	return -1
+}
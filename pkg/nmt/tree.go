@@ -0,0 +1,58 @@
+package nmt
+
+// Tree is a namespaced merkle tree built from NameSpaces
+// that records the Hasher & zero value it was built with.
+type Tree struct {
+	ns     NameSpaces
+	hasher Hasher
+	zero   Element
+	layers Layers
+	zeroes []Element
+	// leaf range of each namespace
+	ranges map[string]leafRange
+}
+
+func NewTree(ns NameSpaces, hasher Hasher, zeroValue Element) (*Tree, error) {
+	if hasher == nil {
+		return nil, ErrNilHashFunction
+	}
+	if ns.Size() == 0 {
+		return nil, ErrEmptyTree
+	}
+
+	leafLayer, ranges, err := genleafLayer(ns, hasher)
+	if err != nil {
+		return nil, err
+	}
+	layers, zeroes, err := BuildLayers(ns.NamespaceSize(), hasher, leafLayer, zeroValue)
+	if err != nil {
+		return nil, err
+	}
+	return &Tree{ns: ns, hasher: hasher, zero: zeroValue, layers: layers, zeroes: zeroes, ranges: ranges}, nil
+}
+
+func (t *Tree) HashID() HashID {
+	return t.hasher.ID()
+}
+
+func (t *Tree) NamespaceSize() IDSize {
+	return t.ns.NamespaceSize()
+}
+
+func (t *Tree) ZeroValue() Element {
+	return t.zero
+}
+
+func (t *Tree) Layers() Layers {
+	return t.layers
+}
+
+func (t *Tree) Root() Node {
+	return t.layers.GetRootNode()
+}
+
+// Prove returns a namespace proof for nID from the layers of the tree,
+// refer to ProveNamespace
+func (t *Tree) Prove(nID ID) (Proof, error) {
+	return proveLayers(t.NamespaceSize(), t.hasher.ID(), t.layers, t.zeroes, t.ranges, nID)
+}
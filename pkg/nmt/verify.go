@@ -0,0 +1,148 @@
+package nmt
+
+import "fmt"
+
+func nodeAt(l Layer, index int) (Node, error) {
+	if index < 0 || index >= len(l) {
+		return nil, fmt.Errorf("%w: missing node at index %d", ErrInvalidProof, index)
+	}
+	return l[index], nil
+}
+
+// VerifyNamespace checks that the proof is a complete namespace proof for nID
+// against the root node of the tree.
+//
+// For inclusion proofs every leaf in [start, end) must belong to nID, for absence
+// proofs the single leaf in range must equal the proof's leafHash and have a
+// larger namespace than nID. In both cases the leaves are hashed up to the root
+// and the siblings bordering the range are checked to have namespaces outside of
+// nID so that no leaves of the namespace can be omitted (completeness).
+//
+// Padding nodes are recognised by comparing them with NodeValueFromZero of the
+// zero value of their level, mirroring how BuildLayer pads incomplete layers.
+func (proof Proof) VerifyNamespace(zeroValue Element, nID ID, root Node) error {
+	if proof.IsEmptyProof() {
+		// case 1) namespace is outside the range of the tree
+		nsSize := IDSize(len(nID))
+		if err := root.Validate(nsSize); err != nil {
+			return fmt.Errorf("%w: root: %v", ErrInvalidProof, err)
+		}
+		if nID.Less(root.MinNs(nsSize)) || root.MaxNs(nsSize).Less(nID) {
+			return nil
+		}
+		return fmt.Errorf("%w: namespace is within the range of the tree", ErrInvalidProof)
+	}
+
+	// every node of the proof is validated before its namespaces are read
+	if err := proof.validate(); err != nil {
+		return err
+	}
+	if err := root.Validate(proof.nsSize); err != nil {
+		return fmt.Errorf("%w: root: %v", ErrInvalidProof, err)
+	}
+
+	var (
+		nsSize    = proof.nsSize
+		depth     = proof.pathLayers.Depth()
+		first     = proof.start
+		last      = proof.end - 1
+		zero      = zeroValue
+		hasher, _ = proof.hashID.Hasher()
+	)
+
+	if nID.Size() != nsSize.Size() {
+		return fmt.Errorf("%w: got size %d, want %d", ErrInvalidNamespace, nID.Size(), nsSize)
+	}
+	if first > last || depth < 2 {
+		return fmt.Errorf("%w: empty range", ErrInvalidProof)
+	}
+	if top := proof.pathLayers[depth-1]; len(top) != 1 || !top[0].Equal(root) {
+		return fmt.Errorf("%w: root mismatch", ErrInvalidProof)
+	}
+	if proof.IsAbsenceProof() && first != last {
+		return fmt.Errorf("%w: absence proof must cover a single leaf", ErrInvalidProof)
+	}
+
+	// leaves within the range must belong to the namespace
+	// or be the leaf proving its absence
+	leaves := proof.pathLayers[0]
+	for i := first; i <= last; i++ {
+		leaf, err := nodeAt(leaves, i-(first-first%2))
+		if err != nil {
+			return err
+		}
+		if proof.IsAbsenceProof() {
+			if !leaf.Equal(proof.leafHash) || !nID.Less(leaf.MinNs(nsSize)) {
+				return fmt.Errorf("%w: invalid absence leaf", ErrInvalidProof)
+			}
+		} else if !leaf.MinNs(nsSize).Equal(nID) || !leaf.MaxNs(nsSize).Equal(nID) {
+			return fmt.Errorf("%w: leaf %d is not in namespace", ErrInvalidProof, i)
+		}
+	}
+
+	for level := 1; level < depth; level++ {
+		var (
+			children    = proof.pathLayers[level-1]
+			parents     = proof.pathLayers[level]
+			childOffset = first - first%2
+			zeroNode    = NodeValueFromZero(nsSize, zero)
+		)
+
+		// completeness: the left sibling of the range holds smaller namespaces
+		if first%2 == 1 {
+			left, err := nodeAt(children, first-1-childOffset)
+			if err != nil {
+				return err
+			}
+			if !left.MaxNs(nsSize).Less(nID) {
+				return fmt.Errorf("%w: left sibling at level %d", ErrFailedCompletenessCheck, level-1)
+			}
+		}
+		// and the right sibling holds larger namespaces unless it is padding
+		if last%2 == 0 {
+			right, err := nodeAt(children, last+1-childOffset)
+			if err != nil {
+				return err
+			}
+			if !right.Equal(zeroNode) && !nID.Less(right.MinNs(nsSize)) {
+				return fmt.Errorf("%w: right sibling at level %d", ErrFailedCompletenessCheck, level-1)
+			}
+		}
+
+		first, last = first>>1, last>>1
+		parentOffset := first - first%2
+		for p := first; p <= last; p++ {
+			left, err := nodeAt(children, 2*p-childOffset)
+			if err != nil {
+				return err
+			}
+			right, err := nodeAt(children, 2*p+1-childOffset)
+			if err != nil {
+				return err
+			}
+			parent, err := nodeAt(parents, p-parentOffset)
+			if err != nil {
+				return err
+			}
+
+			zeroSide := 0
+			if right.Equal(zeroNode) {
+				zeroSide = 2
+			}
+			node, err := BuildNode(nsSize, left, right, zeroSide, hasher)
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidProof, err)
+			}
+			if !node.Equal(parent) {
+				return fmt.Errorf("%w: node %d at level %d does not match its children", ErrInvalidProof, p, level)
+			}
+		}
+
+		var err error
+		if zero, err = hasher.HashZero(nsSize, zero); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
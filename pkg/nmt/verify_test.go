@@ -0,0 +1,100 @@
+package nmt
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Proof_VerifyNamespace(t *testing.T) {
+	for _, hasher := range []Hasher{PoseidonHasher, NsPoseidonHasher, NsSHA256Hasher} {
+		t.Run(hasher.Name(), func(t *testing.T) {
+			testVerifyNamespace(t, hasher)
+		})
+	}
+}
+
+func testVerifyNamespace(t *testing.T, hasher Hasher) {
+	nsgroup := gen_ngs(t, 5, 3, true)
+
+	zero, err := hex.DecodeString(MerkleZeroHex)
+	require.NoError(t, err)
+
+	leafNodes, _, err := genleafLayer(nsgroup, hasher)
+	require.NoError(t, err)
+	layers, _, err := BuildLayers(32, hasher, leafNodes, Element(zero))
+	require.NoError(t, err)
+	root := layers.GetRootNode()
+
+	// inclusion of every namespace in the tree
+	for _, nID := range nsgroup.namespaces {
+		proof, err := ProveNamespace(nsgroup, hasher, Element(zero), nID)
+		require.NoError(t, err)
+		require.NoError(t, proof.VerifyNamespace(Element(zero), nID, root), "namespace %s", nID.String())
+	}
+
+	// absence of a namespace within the range of the tree
+	absent := make(ID, 32)
+	copy(absent, nsgroup.namespaces[2])
+	absent[31]++
+	proof, err := ProveNamespace(nsgroup, hasher, Element(zero), absent)
+	require.NoError(t, err)
+	require.True(t, proof.IsAbsenceProof())
+	require.NoError(t, proof.VerifyNamespace(Element(zero), absent, root))
+
+	// namespace outside the range of the tree
+	outside := make(ID, 32)
+	for i := range outside {
+		outside[i] = 0xff
+	}
+	proof, err = ProveNamespace(nsgroup, hasher, Element(zero), outside)
+	require.NoError(t, err)
+	require.True(t, proof.IsEmptyProof())
+	require.NoError(t, proof.VerifyNamespace(Element(zero), outside, root))
+	require.Error(t, proof.VerifyNamespace(Element(zero), nsgroup.namespaces[0], root))
+}
+
+func Test_Proof_VerifyNamespace_Invalid(t *testing.T) {
+	nsgroup := gen_ngs(t, 5, 3, true)
+
+	zero, err := hex.DecodeString(MerkleZeroHex)
+	require.NoError(t, err)
+
+	leafNodes, _, err := genleafLayer(nsgroup, NsPoseidonHasher)
+	require.NoError(t, err)
+	layers, _, err := BuildLayers(32, NsPoseidonHasher, leafNodes, Element(zero))
+	require.NoError(t, err)
+	root := layers.GetRootNode()
+
+	nID := nsgroup.namespaces[1]
+	proof, err := ProveNamespace(nsgroup, NsPoseidonHasher, Element(zero), nID)
+	require.NoError(t, err)
+
+	// wrong namespace
+	require.ErrorIs(t, proof.VerifyNamespace(Element(zero), nsgroup.namespaces[2], root), ErrInvalidProof)
+
+	// wrong root
+	require.ErrorIs(t, proof.VerifyNamespace(Element(zero), nID, leafNodes[0]), ErrInvalidProof)
+
+	// omitting a leaf of the namespace breaks completeness
+	partial := NewInclusionProof(proof.NamespaceSize(), proof.HashID(), proof.Start(), proof.End()-1, proof.PathLayers())
+	require.ErrorIs(t, partial.VerifyNamespace(Element(zero), nID, root), ErrFailedCompletenessCheck)
+
+	// tampered leaf
+	tampered := make(Layers, proof.PathLayers().Depth())
+	copy(tampered, proof.PathLayers())
+	tampered[0] = append(Layer{}, tampered[0]...)
+	leaf := append(Node{}, tampered[0][proof.Start()%2]...)
+	leaf[len(leaf)-1] ^= 1
+	tampered[0][proof.Start()%2] = leaf
+	forged := NewInclusionProof(proof.NamespaceSize(), proof.HashID(), proof.Start(), proof.End(), tampered)
+	require.ErrorIs(t, forged.VerifyNamespace(Element(zero), nID, root), ErrInvalidProof)
+
+	// truncated nodes & roots are rejected before their namespaces are read
+	tampered[0][proof.Start()%2] = leaf[:40]
+	truncated := NewInclusionProof(proof.NamespaceSize(), proof.HashID(), proof.Start(), proof.End(), tampered)
+	require.ErrorIs(t, truncated.VerifyNamespace(Element(zero), nID, root), ErrInvalidEncoding)
+	require.ErrorIs(t, proof.VerifyNamespace(Element(zero), nID, root[:40]), ErrInvalidProof)
+	require.ErrorIs(t, NewEmptyRangeProof().VerifyNamespace(Element(zero), nID, root[:40]), ErrInvalidProof)
+}
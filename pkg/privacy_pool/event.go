@@ -1,9 +1,11 @@
 package privacypool
 
 import (
+	"bytes"
 	"math/big"
 
 	"github.com/0xbow-io/go-iden3-crypto/poseidon"
+	"github.com/0xbow-io/go-iden3-crypto/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/pkg/errors"
 )
@@ -19,18 +21,53 @@ type Event struct {
 	Amount common.Hash `json:"amount"`
 }
 
-func (e *Event) Hash() ([]byte, error) {
-	hash, err := poseidon.Hash([]*big.Int{
-		e.TxHash.Big(),
+var (
+	ErrNotInField        = errors.New("input not in field")
+	ErrHashMismatch      = errors.New("stored hash does not match the event")
+	ErrNamespaceMismatch = errors.New("stored namespace does not match the event")
+)
+
+// limbSize is the byte size of the limbs 32 byte words are split into
+// so that every poseidon input is below the BN254 scalar field modulus.
+const limbSize = 16
+
+func appendLimbs(inputs []*big.Int, word common.Hash) []*big.Int {
+	return append(inputs,
+		new(big.Int).SetBytes(word[:limbSize]), // hi
+		new(big.Int).SetBytes(word[limbSize:]), // lo
+	)
+}
+
+/*
+FieldElements returns the canonical field encoding of the event
+used as the poseidon preimage:
+
+	txHash.hi, txHash.lo, logIndex, token,
+	from.hi, from.lo, to.hi, to.lo, amount.hi, amount.lo
+
+Where hi & lo are the big-endian 16 byte halves of the word.
+*/
+func (e *Event) FieldElements() []*big.Int {
+	inputs := make([]*big.Int, 0, 10)
+	inputs = appendLimbs(inputs, e.TxHash)
+	inputs = append(inputs,
 		big.NewInt(int64(e.LogIndex)),
-		big.NewInt(0).SetBytes(e.Token.Bytes()),
-		e.From.Big(),
-		e.To.Big(),
-		e.Amount.Big(),
-	})
+		new(big.Int).SetBytes(e.Token.Bytes()),
+	)
+	inputs = appendLimbs(inputs, e.From)
+	inputs = appendLimbs(inputs, e.To)
+	return appendLimbs(inputs, e.Amount)
+}
+
+func (e *Event) Hash() ([]byte, error) {
+	inputs := e.FieldElements()
+	if !utils.CheckBigIntArrayInField(inputs) {
+		return nil, ErrNotInField
+	}
 
+	hash, err := poseidon.Hash(inputs)
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrap(err, "failed to hash event")
 	}
 
 	bHash := hash.Bytes()
@@ -139,26 +176,29 @@ func (se SerialEvent) HashHex() string {
 	return common.Bytes2Hex(se[namespaceLen : namespaceLen+hashSize])
 }
 
+// ComputeHash recomputes the hash of the event held in the body
 func (se SerialEvent) ComputeHash() ([]byte, error) {
-	hash, err := poseidon.Hash([]*big.Int{
-		big.NewInt(0).SetBytes(se[headerSize : headerSize+32]),      // txHash
-		big.NewInt(int64(se[headerSize+32])),                        // logIndex
-		big.NewInt(0).SetBytes(se[headerSize+33 : headerSize+53]),   // token
-		big.NewInt(0).SetBytes(se[headerSize+53 : headerSize+85]),   // from
-		big.NewInt(0).SetBytes(se[headerSize+85 : headerSize+117]),  // to
-		big.NewInt(0).SetBytes(se[headerSize+117 : headerSize+149]), // amount
-	})
-
+	e, err := se.AsEvent()
 	if err != nil {
 		return nil, err
 	}
+	return e.Hash()
+}
 
-	bHash := hash.Bytes()
-	if len(bHash) < 32 {
-		pad := make([]byte, 32-len(bHash))
-		bHash = append(pad, bHash...)
-	} else if len(bHash) > 32 {
-		return nil, errors.New("hash is greater than 32 bytes")
+// Validate checks that the header of the serialized event
+// matches the namespace & hash of the event held in the body
+func (se SerialEvent) Validate() error {
+	from := se.From()
+	if !bytes.Equal(se[:namespaceLen], from[:]) {
+		return ErrNamespaceMismatch
 	}
-	return bHash, nil
+
+	hash, err := se.ComputeHash()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(se.Hash(), hash) {
+		return errors.Wrapf(ErrHashMismatch, "got %s want %s", se.HashHex(), common.Bytes2Hex(hash))
+	}
+	return nil
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/hex"
 	"testing"
 
+	"github.com/0xbow-io/go-iden3-crypto/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/require"
 )
@@ -42,3 +43,61 @@ func Test_Event_Record_Element(t *testing.T) {
 	require.Equal(t, e.From.Hex()[2:], rec.NsHex())
 
 }
+
+func Test_Event_Hash_FieldOverflow(t *testing.T) {
+	// every word is above the BN254 scalar field modulus
+	max := common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+	e := Event{
+		TxHash:   max,
+		LogIndex: 255,
+		Token:    common.HexToAddress("0xffffffffffffffffffffffffffffffffffffffff"),
+		From:     max,
+		To:       max,
+		Amount:   max,
+	}
+
+	for _, in := range e.FieldElements() {
+		require.True(t, utils.CheckBigIntInField(in))
+	}
+
+	hash, err := e.Hash()
+	require.NoError(t, err)
+	require.Len(t, hash, 32)
+
+	// limbs keep distinct words distinct
+	other := e
+	other.Amount = common.HexToHash("0xffffffffffffffffffffffffffffffff00000000000000000000000000000000")
+	otherHash, err := other.Hash()
+	require.NoError(t, err)
+	require.NotEqual(t, hash, otherHash)
+}
+
+func Test_SerialEvent_Validate(t *testing.T) {
+	e := Event{
+		TxHash:   common.HexToHash("0x0d95bebae9f1b39ccc72830e42411cf6cbb29c184cc8e67ecac5a678fb256045"),
+		LogIndex: 109,
+		Token:    common.HexToAddress("0x9fb9b8c43232fbe999b5b66c2050ea6c70353c96"),
+		From:     common.HexToHash("0xeF4fB24aD0916217251F553c0596F8Edc630EB66"),
+		To:       common.HexToHash("0x6D7A3177f3500BEA64914642a49D0B5C0a7Dae6D"),
+		Amount:   common.HexToHash("0x000000000000000000000000000000000000000000000000000000000bbbc803"),
+	}
+
+	rec, err := e.Serialize()
+	require.NoError(t, err)
+	require.NoError(t, rec.Validate())
+
+	// tampered body
+	tampered := rec
+	tampered[len(tampered)-1] ^= 1
+	require.ErrorIs(t, tampered.Validate(), ErrHashMismatch)
+
+	// tampered header hash
+	tampered = rec
+	tampered[namespaceLen] ^= 1
+	require.ErrorIs(t, tampered.Validate(), ErrHashMismatch)
+
+	// tampered namespace
+	tampered = rec
+	tampered[0] ^= 1
+	require.ErrorIs(t, tampered.Validate(), ErrNamespaceMismatch)
+}
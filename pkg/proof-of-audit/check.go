@@ -0,0 +1,124 @@
+package proofOfAudit
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidPayload is returned when the payload would make the program panic
+var ErrInvalidPayload = errors.New("invalid payload")
+
+// Check runs the proof-of-audit program in Go without generating a proof
+// and returns its output. It is meant for debugging, the SP1 program is the
+// source of truth & Check mirrors it step by step.
+func Check(secret, urlPath, nonce, timestamp, payload, signature, commitmentId, publicID string) (bool, error) {
+	if secret == "" || urlPath == "" {
+		return false, nil
+	}
+	if !VerifySignature(secret, urlPath, nonce, timestamp, payload, signature) {
+		return false, nil
+	}
+	return FindCommitment(payload, commitmentId, publicID)
+}
+
+// VerifySignature checks that the webhook signature of the payload
+// was generated with the secret & url path.
+func VerifySignature(secret, urlPath, nonce, timestamp, payload, signature string) bool {
+	bodyHash := sha256.Sum256([]byte(urlPath + payload))
+
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(nonce + hex.EncodeToString(bodyHash[:]) + timestamp))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)) == signature
+}
+
+// FindCommitment looks in the matched receipts of the payload for the
+// Coinbase attestation or revocation of publicID with the commitment ID
+// (EAS UUID) on Base.
+func FindCommitment(payload, commitmentId, publicID string) (bool, error) {
+	var body interface{}
+	dec := json.NewDecoder(strings.NewReader(payload))
+	dec.UseNumber()
+	if err := dec.Decode(&body); err != nil {
+		return false, errors.Wrap(ErrInvalidPayload, err.Error())
+	}
+
+	receipts, ok := field(body, "matchedReceipts").([]interface{})
+	if !ok {
+		return false, nil
+	}
+	for index, receipt := range receipts {
+		logs, ok := field(receipt, "logs").([]interface{})
+		if !ok {
+			continue
+		}
+		for _, log := range logs {
+			if text(field(log, "address")) != eas.BASE_EAS_ADDR ||
+				text(field(log, "data")) != commitmentId {
+				continue
+			}
+			topics, ok := field(log, "topics").([]interface{})
+			if !ok {
+				continue
+			}
+
+			match := true
+			for i, want := range []string{eas.COINBASE_EAS_ATTEST_TOPIC, publicID, eas.COINBASE_EAS_HASH, eas.COINBASE_EAS_SCHEMA_ID} {
+				// topics are indexed lazily like the program does
+				if i >= len(topics) {
+					return false, errors.Wrapf(ErrInvalidPayload, "missing topic %d", i)
+				}
+				// attestations & revocations are matched
+				if i == 0 && text(topics[i]) == eas.COINBASE_EAS_REVOKE_TOPIC {
+					continue
+				}
+				if text(topics[i]) != want {
+					match = false
+					break
+				}
+			}
+			if !match {
+				continue
+			}
+
+			txs, ok := field(body, "matchedTransactions").([]interface{})
+			if !ok {
+				continue
+			}
+			if index >= len(txs) {
+				return false, errors.Wrapf(ErrInvalidPayload, "missing transaction %d", index)
+			}
+			if text(field(txs[index], "chainId")) == eas.BASE_CHAIN_ID {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// field returns the value of key if v is an object, nil otherwise
+func field(v interface{}, key string) interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		return m[key]
+	}
+	return nil
+}
+
+// text returns the lowercase JSON text of v without its surrounding quotes,
+// the program compares values with `v.to_string().trim_matches('"').to_lowercase()`
+func text(v interface{}) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.Trim(strings.TrimSuffix(buf.String(), "\n"), `"`))
+}
@@ -0,0 +1,81 @@
+package proofOfAudit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	checkSecret    = "qnsec_dFzHeJ5iQbefXDH1akAKow=="
+	checkURLPath   = "/webhook/2057b8e5-de11-4c65-8e39-92507d20de80"
+	checkNonce     = "632e2d63-d253-4a06-ab77-d565e806e5e1"
+	checkTimestamp = "2024-03-12 04:04:14.11330824 +0000 UTC m=+17208.257632042"
+	checkSignature = "tsPXXWc53kXWob6ZbrHCxDSUrljtmk40d5vGGtFXvbs="
+	checkUUID      = "0x8133f214f7bdaf516f03655db7406ba3c7945e5e4849238e43fdea6ef7a25cd4"
+	checkPublicID  = "0x000000000000000000000000ff9418c67d18c8e067141bd77be43e32c4c3abe7"
+	checkPayload   = `{"matchedReceipts":[{"blockHash":"0x6644e74c3ff45ac8d514cdfc7393bcd193067c47c138842427a46a49d528573a","blockNumber":"0xb2bbad","contractAddress":"","cumulativeGasUsed":"0x70ef7","effectiveGasPrice":"0x187d3","from":"0x8844591d47f17bca6f5df8f6b64f4a739f1c0080","gasUsed":"0x450b5","logs":[{"address":"0x4200000000000000000000000000000000000021","blockHash":"0x6644e74c3ff45ac8d514cdfc7393bcd193067c47c138842427a46a49d528573a","blockNumber":"0xb2bbad","data":"0x8133f214f7bdaf516f03655db7406ba3c7945e5e4849238e43fdea6ef7a25cd4","logIndex":"0x1","removed":false,"topics":["0x8bf46bf4cfd674fa735a3d63ec1c9ad4153f033c290341f3a588b75685141b35","0x000000000000000000000000ff9418c67d18c8e067141bd77be43e32c4c3abe7","0x000000000000000000000000357458739f90461b99789350868cd7cf330dd7ee","0xf8b05c79f090979bf4a80270aba232dff11a10d9ca55c4f88de95317970f0de9"],"transactionHash":"0x63a3aef220d84947b12b0d771fa0df510eb753cab0c218efa1861b0d7c3d4567","transactionIndex":"0x4"},{"address":"0x2c7ee1e5f416dff40054c27a62f7b357c4e8619c","blockHash":"0x6644e74c3ff45ac8d514cdfc7393bcd193067c47c138842427a46a49d528573a","blockNumber":"0xb2bbad","data":"0x000000000000000000000000d867cbed445c37b0f95cc956fe6b539bdef7f32f","logIndex":"0x2","removed":false,"topics":["0x7fd54fcc14543b4db08cef4cd9fb23a6670c072d8a44cb0f1817d35b474176ca","0x000000000000000000000000ff9418c67d18c8e067141bd77be43e32c4c3abe7","0xf8b05c79f090979bf4a80270aba232dff11a10d9ca55c4f88de95317970f0de9","0x8133f214f7bdaf516f03655db7406ba3c7945e5e4849238e43fdea6ef7a25cd4"],"transactionHash":"0x63a3aef220d84947b12b0d771fa0df510eb753cab0c218efa1861b0d7c3d4567","transactionIndex":"0x4"}],"logsBloom":"0x00000000000000000000000040000000100000000000000000000000000000000001000000000000000000000000000000000000000000000040000000000000000000000000000000000000000000000000000000000008000000000000000000020040000000000000000000000000000000000000002000000000800000000000000000000000000000000400001000000000000000000000000010000000000000000000008010800000000020000000000010000000000000002000000000000000800000000000000000000000000000000004000000000000000000000000000800010000200000000000000000000000000000000000000000080000","status":"0x1","to":"0x357458739f90461b99789350868cd7cf330dd7ee","transactionHash":"0x63a3aef220d84947b12b0d771fa0df510eb753cab0c218efa1861b0d7c3d4567","transactionIndex":"0x4","type":"0x2"}],"matchedTransactions":[{"accessList":[],"blockHash":"0x6644e74c3ff45ac8d514cdfc7393bcd193067c47c138842427a46a49d528573a","blockNumber":"0xb2bbad","chainId":"0x2105","from":"0x8844591d47f17bca6f5df8f6b64f4a739f1c0080","gas":"0x927c0","gasPrice":"0x187d3","hash":"0x63a3aef220d84947b12b0d771fa0df510eb753cab0c218efa1861b0d7c3d4567","input":"0x56feed5e000000000000000000000000ff9418c67d18c8e067141bd77be43e32c4c3abe7","maxFeePerGas":"0x31214","maxPriorityFeePerGas":"0x186a0","nonce":"0x11211","r":"0xf015d09c9d8b274b703fad93020d38300aba7a9a0cbc786f05dac65dd0e0795b","s":"0x2d77121dc4ed30832b960e025b2b4b961e5103c8ad7e8f629a1eb381977c556c","to":"0x357458739f90461b99789350868cd7cf330dd7ee","transactionIndex":"0x4","type":"0x2","v":"0x0","value":"0x0"}]}`
+)
+
+func Test_Check(t *testing.T) {
+	type testCase struct {
+		secret       string
+		urlPath      string
+		signature    string
+		commitmentId string
+		publicID     string
+		ok           bool
+	}
+
+	for i, tc := range []testCase{
+		{checkSecret, checkURLPath, checkSignature, checkUUID, checkPublicID, true},
+		{"incorrectSecret", checkURLPath, checkSignature, checkUUID, checkPublicID, false},
+		{checkSecret, "incorrectPath", checkSignature, checkUUID, checkPublicID, false},
+		{checkSecret, checkURLPath, "incorrectSignature", checkUUID, checkPublicID, false},
+		{checkSecret, checkURLPath, checkSignature, "incorrectCommitmentID", checkPublicID, false},
+		{checkSecret, checkURLPath, checkSignature, checkUUID, "incorrectPublicID", false},
+		{"", checkURLPath, checkSignature, checkUUID, checkPublicID, false},
+		{checkSecret, "", checkSignature, checkUUID, checkPublicID, false},
+		// the IDs are compared with the lowercase values of the payload
+		{checkSecret, checkURLPath, checkSignature, strings.ToUpper(checkUUID), checkPublicID, false},
+	} {
+		ok, err := Check(tc.secret, tc.urlPath, checkNonce, checkTimestamp, checkPayload, tc.signature, tc.commitmentId, tc.publicID)
+		require.NoError(t, err, "test case %d", i)
+		require.Equal(t, tc.ok, ok, "test case %d", i)
+	}
+}
+
+func Test_FindCommitment(t *testing.T) {
+	ok, err := FindCommitment(checkPayload, checkUUID, checkPublicID)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// the payload is not parsed when the signature is invalid
+	ok, err = Check(checkSecret, checkURLPath, checkNonce, checkTimestamp, "{", checkSignature, checkUUID, checkPublicID)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, err = FindCommitment("{", checkUUID, checkPublicID)
+	require.ErrorIs(t, err, ErrInvalidPayload)
+
+	// wrong chain
+	ok, err = FindCommitment(strings.Replace(checkPayload, `"chainId":"0x2105"`, `"chainId":"0x1"`, 1), checkUUID, checkPublicID)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// uppercase payload values are lowercased
+	ok, err = FindCommitment(strings.Replace(checkPayload, checkUUID, strings.ToUpper(checkUUID), 1), checkUUID, checkPublicID)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	for i, payload := range []string{
+		// the attestation log with missing topics
+		`{"matchedReceipts":[{"logs":[{"address":"0x4200000000000000000000000000000000000021","data":"` + checkUUID + `","topics":["0x8bf46bf4cfd674fa735a3d63ec1c9ad4153f033c290341f3a588b75685141b35"]}]}]}`,
+		// the attestation log without its transaction
+		strings.Replace(checkPayload, `"matchedTransactions":[{`, `"matchedTransactions":[],"_":[{`, 1),
+	} {
+		_, err = FindCommitment(payload, checkUUID, checkPublicID)
+		require.ErrorIs(t, err, ErrInvalidPayload, "test case %d", i)
+	}
+}
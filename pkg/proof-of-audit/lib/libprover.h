@@ -1,3 +1,6 @@
 #include <stdbool.h>
 
-char* generate_sp1_proof_ffi(char *secret, char *url_path, char *nonce, char *timestamp, char *payload, char *signature, char *commitment_id, char *public_id);
\ No newline at end of file
+char* generate_sp1_proof_ffi(char *secret, char *url_path, char *nonce, char *timestamp, char *payload, char *signature, char *commitment_id, char *public_id);
+
+// returns an empty string if the JSON encoded SP1 proof is valid, the reason otherwise
+char* verify_sp1_proof_ffi(char *proof);
@@ -10,6 +10,7 @@ use serde_json::Value;
 
 const BASE_CHAIN_ID: &str = "0x2105";
 const COINBASE_EAS_TOPIC: &str = "0x8bf46bf4cfd674fa735a3d63ec1c9ad4153f033c290341f3a588b75685141b35";
+const COINBASE_EAS_REVOKE_TOPIC: &str = "0xf930a6e2523c9cc298691873087a740550b8fc85a0680830414c148ed927f615";
 const BASE_EAS_ADDR: &str = "0x4200000000000000000000000000000000000021";
 const COINBASE_EAS_HASH: &str = "0x000000000000000000000000357458739f90461b99789350868cd7cf330dd7ee";
 const COINBASE_EAS_SCHEMA_ID: &str = "0xf8b05c79f090979bf4a80270aba232dff11a10d9ca55c4f88de95317970f0de9";
@@ -27,6 +28,12 @@ const COINBASE_EAS_SCHEMA_ID: &str = "0xf8b05c79f090979bf4a80270aba232dff11a10d9
         - iterate through payload and find Tx Receipt log of EAS Attestation: 
             - commitment_id (EAS UUID)
             - public_id (Attested Wallet Address)
+        - the log is either an attestation or a revocation
+
+    Public values, so that a proof can't be replayed for another account or event:
+        - include: whether the log was found
+        - public_id & commitment_id
+        - topic of the log, empty if none was found
 */
 
 fn verify_sig(secret: &str, nonce: &str, timestamp: &str, body_hash: &str, signature: &str) -> bool {
@@ -45,7 +52,7 @@ fn verify_sig(secret: &str, nonce: &str, timestamp: &str, body_hash: &str, signa
     return expected_sig == signature;
 }
 
-fn verify_commitment(body: &str, commitment_id: &str, public_id: &str) -> bool  {
+fn verify_commitment(body: &str, commitment_id: &str, public_id: &str) -> Option<String>  {
     // Generic JSON parsing
     // Seek for evidence that commitmentID (Attestation UID) should be member of Inclusion Set.
     let json: Value = serde_json::from_str(&body).unwrap();
@@ -62,7 +69,8 @@ fn verify_commitment(body: &str, commitment_id: &str, public_id: &str) -> bool
                     {
                         if let serde_json::Value::Array(topics) = &log["topics"] {
                             // Verfy Attestation 
-                            if  topics[0].to_string().trim_matches('"').to_lowercase().eq(COINBASE_EAS_TOPIC) 
+                            let topic = topics[0].to_string().trim_matches('"').to_lowercase();
+                            if  (topic.eq(COINBASE_EAS_TOPIC) || topic.eq(COINBASE_EAS_REVOKE_TOPIC))
                                 && topics[1].to_string().trim_matches('"').to_lowercase().eq(&public_id)  // Attested Wallet Address
                                 && topics[2].to_string().trim_matches('"').to_lowercase().eq(COINBASE_EAS_HASH)   
                                 && topics[3].to_string().trim_matches('"').to_lowercase().eq(COINBASE_EAS_SCHEMA_ID)   
@@ -70,7 +78,7 @@ fn verify_commitment(body: &str, commitment_id: &str, public_id: &str) -> bool
                                 // check the chainID is correct 
                                 if let Value::Array(transactions) = matched_txs {
                                    if transactions[index] ["chainId"].to_string().trim_matches('"').to_lowercase().eq(BASE_CHAIN_ID) {
-                                    return true;
+                                    return Some(topic);
                                    }
                                 }
                             }
@@ -80,7 +88,7 @@ fn verify_commitment(body: &str, commitment_id: &str, public_id: &str) -> bool
             }
         }
     }
-    return false
+    return None
 }
 
 
@@ -89,6 +97,7 @@ pub fn main() {
    
     let mut hasher = Sha256::new();
     let mut include = false;
+    let mut topic = String::new();
 
     // private inputs
     let secret = sp1_zkvm::io::read::<String>();
@@ -124,9 +133,15 @@ pub fn main() {
         // verify signature
         // proof of payload origin
         if verify_sig(&secret, &nonce, &timestamp, &computed_body_hash, &signature)  {
-            include = verify_commitment(&body, &commitment_id, &public_id);
+            if let Some(t) = verify_commitment(&body, &commitment_id, &public_id) {
+                include = true;
+                topic = t;
+            }
         }
     }
 
     sp1_zkvm::io::write(&include);
+    sp1_zkvm::io::write(&public_id);
+    sp1_zkvm::io::write(&commitment_id);
+    sp1_zkvm::io::write(&topic);
 }
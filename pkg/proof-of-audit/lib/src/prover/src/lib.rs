@@ -1,4 +1,4 @@
-use sp1_core::{SP1Verifier, SP1Prover, SP1Stdin};
+use sp1_core::{SP1Verifier, SP1Prover, SP1Stdin, SP1ProofWithIO, utils::BabyBearPoseidon2};
 use serde_json;
 use libc;
 use std::ffi::{CStr, CString};
@@ -65,6 +65,20 @@ pub extern "C" fn generate_sp1_proof_ffi(
 }
 
 
+// Verifies a JSON encoded proof generated by generate_sp1_proof_ffi,
+// returns an empty string if it is valid & the reason otherwise.
+#[no_mangle]
+pub extern "C" fn verify_sp1_proof_ffi(proof: *const libc::c_char) -> *mut libc::c_char {
+    let proof: SP1ProofWithIO<BabyBearPoseidon2> = match serde_json::from_str(&get_string_c_char(proof)) {
+        Ok(proof) => proof,
+        Err(err) => return CString::new(format!("invalid proof encoding: {}", err)).unwrap().into_raw(),
+    };
+    match SP1Verifier::verify(ELF, &proof) {
+        Ok(_) => CString::new("").unwrap().into_raw(),
+        Err(err) => CString::new(format!("{:?}", err)).unwrap().into_raw(),
+    }
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
@@ -0,0 +1,142 @@
+package proofOfAudit
+
+import (
+	"encoding/binary"
+	"errors"
+
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	ErrEmptyProof   = errors.New("empty proof")
+	ErrNotCommitted = errors.New("proof does not commit to the report")
+	ErrInvalidSP1   = errors.New("SP1 proof verification failed")
+	ErrPublicValues = errors.New("invalid proof public values")
+)
+
+/*
+PublicValues written by the proof-of-audit program to stdout,
+each value is bincode encoded in order:
+
+	included      bool
+	public_id     String  attested account, as given to the prover
+	commitment_id String  EAS UUID, as given to the prover
+	topic         String  topic of the matched EAS log, empty if none
+*/
+type PublicValues struct {
+	Included     bool   `json:"included"`
+	PublicID     string `json:"publicID"`
+	CommitmentID string `json:"commitmentID"`
+	Topic        string `json:"topic"`
+}
+
+// EventPublicValues returns the public values of a proof of the event
+func EventPublicValues(e eas.EAS) PublicValues {
+	v := PublicValues{Included: true, PublicID: e.Account.Hex(), CommitmentID: e.UUID.Hex()}
+	switch e.Type {
+	case eas.EAS_ATTEST:
+		v.Topic = eas.COINBASE_EAS_ATTEST_TOPIC
+	case eas.EAS_REVOKE:
+		v.Topic = eas.COINBASE_EAS_REVOKE_TOPIC
+	default:
+		v.Included = false
+	}
+	return v
+}
+
+// Account returns the attested account
+func (v PublicValues) Account() common.Hash {
+	return common.HexToHash(v.PublicID)
+}
+
+// UUID returns the EAS UUID of the matched attestation
+func (v PublicValues) UUID() common.Hash {
+	return common.HexToHash(v.CommitmentID)
+}
+
+// Type returns the type of the matched EAS event
+func (v PublicValues) Type() eas.EAS_TYPE {
+	switch common.HexToHash(v.Topic) {
+	case common.HexToHash(eas.COINBASE_EAS_ATTEST_TOPIC):
+		return eas.EAS_ATTEST
+	case common.HexToHash(eas.COINBASE_EAS_REVOKE_TOPIC):
+		return eas.EAS_REVOKE
+	default:
+		return eas.EAS_UNKNOWN
+	}
+}
+
+// Matches returns true if the public values commit to the event
+func (v PublicValues) Matches(e eas.EAS) bool {
+	return v.Account() == e.Account && v.UUID() == e.UUID && v.Type() == e.Type
+}
+
+// Encode returns the bincode encoding of the public values
+func (v PublicValues) Encode() []byte {
+	out := []byte{0}
+	if v.Included {
+		out[0] = 1
+	}
+	for _, s := range []string{v.PublicID, v.CommitmentID, v.Topic} {
+		out = binary.LittleEndian.AppendUint64(out, uint64(len(s)))
+		out = append(out, s...)
+	}
+	return out
+}
+
+// DecodePublicValues decodes the public values written by the program
+func DecodePublicValues(data []byte) (v PublicValues, err error) {
+	if len(data) == 0 || data[0] > 1 {
+		return v, ErrPublicValues
+	}
+	v.Included = data[0] == 1
+	data = data[1:]
+
+	for _, s := range []*string{&v.PublicID, &v.CommitmentID, &v.Topic} {
+		if len(data) < 8 {
+			return v, ErrPublicValues
+		}
+		n := binary.LittleEndian.Uint64(data)
+		data = data[8:]
+		if n > uint64(len(data)) {
+			return v, ErrPublicValues
+		}
+		*s, data = string(data[:n]), data[n:]
+	}
+	if len(data) != 0 {
+		return v, ErrPublicValues
+	}
+	return v, nil
+}
+
+// PublicValues returns the public values committed by the proof
+func (p *SP1Proof) PublicValues() (PublicValues, error) {
+	return DecodePublicValues(p.Stdout.Buffer.Data)
+}
+
+// Committed returns whether the program found the attestation in the report
+func (p *SP1Proof) Committed() (bool, error) {
+	v, err := p.PublicValues()
+	if err != nil {
+		return false, err
+	}
+	return v.Included, nil
+}
+
+// CheckProof checks that the proof is well formed and that the program
+// committed to an EAS event of the report. It does not verify the proof
+// itself, VerifyProof runs CheckProof & then the SP1 verifier.
+func CheckProof(p SP1Proof) error {
+	if p.Proof == "" {
+		return ErrEmptyProof
+	}
+	v, err := p.PublicValues()
+	if err != nil {
+		return err
+	}
+	if !v.Included || v.Type() == eas.EAS_UNKNOWN {
+		return ErrNotCommitted
+	}
+	return nil
+}
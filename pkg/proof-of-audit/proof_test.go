@@ -0,0 +1,78 @@
+package proofOfAudit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strings"
+	"testing"
+
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PublicValues(t *testing.T) {
+	v := PublicValues{
+		Included:     true,
+		PublicID:     common.HexToHash("0x0a").Hex(),
+		CommitmentID: common.HexToHash("0x0b").Hex(),
+		Topic:        eas.COINBASE_EAS_ATTEST_TOPIC,
+	}
+	decoded, err := DecodePublicValues(v.Encode())
+	require.NoError(t, err)
+	require.Equal(t, v, decoded)
+	require.Equal(t, eas.EAS_ATTEST, decoded.Type())
+	require.True(t, decoded.Matches(eas.EAS{UUID: common.HexToHash("0x0b"), Account: common.HexToHash("0x0a"), Type: eas.EAS_ATTEST}))
+	require.False(t, decoded.Matches(eas.EAS{UUID: common.HexToHash("0x0b"), Account: common.HexToHash("0x0c"), Type: eas.EAS_ATTEST}))
+	require.False(t, decoded.Matches(eas.EAS{UUID: common.HexToHash("0x0b"), Account: common.HexToHash("0x0a"), Type: eas.EAS_REVOKE}))
+
+	v.Topic = eas.COINBASE_EAS_REVOKE_TOPIC
+	require.Equal(t, eas.EAS_REVOKE, v.Type())
+
+	// the bool alone isn't a valid output anymore
+	for _, data := range [][]byte{nil, {1}, {2}, append(v.Encode(), 0), v.Encode()[:20]} {
+		_, err := DecodePublicValues(data)
+		require.ErrorIs(t, err, ErrPublicValues)
+	}
+}
+
+func Test_CheckProof(t *testing.T) {
+	withOutput := func(proof string, data []byte) SP1Proof {
+		return SP1Proof{Proof: proof, Stdout: StdIO{Buffer: StdBuffer{Data: data}}}
+	}
+	committed := PublicValues{Included: true, PublicID: "0x0a", CommitmentID: "0x0b", Topic: eas.COINBASE_EAS_ATTEST_TOPIC}
+	missing := PublicValues{PublicID: "0x0a", CommitmentID: "0x0b"}
+	unknown := PublicValues{Included: true, PublicID: "0x0a", CommitmentID: "0x0b", Topic: "0x01"}
+
+	require.NoError(t, CheckProof(withOutput("proof", committed.Encode())))
+	require.ErrorIs(t, CheckProof(withOutput("", committed.Encode())), ErrEmptyProof)
+	require.ErrorIs(t, CheckProof(withOutput("proof", missing.Encode())), ErrNotCommitted)
+	require.ErrorIs(t, CheckProof(withOutput("proof", unknown.Encode())), ErrNotCommitted)
+	require.Error(t, CheckProof(withOutput("proof", nil)))
+	require.Error(t, CheckProof(withOutput("proof", []byte{1})))
+}
+
+// Test_ELF_Source checks that the embedded ELF is built from the program
+// sources, `make build-elf cp-elf` rebuilds it & records their digests
+func Test_ELF_Source(t *testing.T) {
+	elf, err := os.ReadFile("lib/elf/riscv32im-succinct-zkvm-elf")
+	require.NoError(t, err)
+	built, err := os.ReadFile("lib/src/program/elf/riscv32im-succinct-zkvm-elf")
+	require.NoError(t, err)
+	require.True(t, bytes.Equal(elf, built), "lib/elf is not the ELF built by the program, run make cp-elf")
+
+	digests, err := os.ReadFile("lib/elf/program.sha256")
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(digests)), "\n")
+	require.NotEmpty(t, lines)
+	for _, line := range lines {
+		digest, path, ok := strings.Cut(line, "  ")
+		require.True(t, ok, line)
+		src, err := os.ReadFile(path)
+		require.NoError(t, err)
+		sum := sha256.Sum256(src)
+		require.Equal(t, digest, hex.EncodeToString(sum[:]), "the ELF is stale, %s changed since it was built, run make build-elf cp-elf", path)
+	}
+}
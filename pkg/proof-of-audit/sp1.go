@@ -12,6 +12,8 @@ import "C"
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"unsafe"
 
 	_ "github.com/0xBow-io/base-eas-asp/pkg/proof-of-audit/lib"
 )
@@ -53,3 +55,37 @@ func VerifyCommitment(secret, urlPath, nonce, timestamp, payload, signature, com
 	}
 	return nil, errors.New("missing output")
 }
+
+// VerifyProof checks the public values of the proof with CheckProof
+// & then verifies it with VerifySP1.
+func VerifyProof(p SP1Proof) error {
+	if err := CheckProof(p); err != nil {
+		return err
+	}
+	return VerifySP1(p)
+}
+
+// VerifySP1 verifies the SP1 proof against the proof-of-audit program with
+// the SP1 verifier, whatever its public values. A proof the program didn't
+// commit to an event is valid.
+func VerifySP1(p SP1Proof) error {
+	if p.Proof == "" {
+		return ErrEmptyProof
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	in := C.CString(string(b))
+	defer C.free(unsafe.Pointer(in))
+	out := C.verify_sp1_proof_ffi(in)
+	if out == nil {
+		return errors.New("missing output")
+	}
+	defer C.free(unsafe.Pointer(out))
+	if msg := C.GoString(out); msg != "" {
+		return fmt.Errorf("%w: %s", ErrInvalidSP1, msg)
+	}
+	return nil
+}
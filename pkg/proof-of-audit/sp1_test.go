@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/require"
 )
 
@@ -109,9 +110,26 @@ func Test_VerifyCommitment(t *testing.T) {
 			// check Stdout value
 			require.Equal(t, uint8(1), uint8(proof.Stdout.Buffer.Data[0]), "test case %d", i)
 
+			// the account & event are public values
+			v, err := proof.PublicValues()
+			require.NoError(t, err, "test case %d", i)
+			require.Equal(t, tc.publicID, v.PublicID, "test case %d", i)
+			require.Equal(t, tc.commitmentId, v.CommitmentID, "test case %d", i)
+			require.NoError(t, VerifyProof(*proof), "test case %d", i)
+
 		} else {
 			require.Error(t, err, "test case %d", i)
 			require.Nil(t, proof, "test case %d", i)
 		}
 	}
 }
+
+func Test_VerifySP1(t *testing.T) {
+	require.ErrorIs(t, VerifySP1(SP1Proof{}), ErrEmptyProof)
+
+	// the public values are left to the caller, the SP1 verifier rejects a fake proof
+	v := PublicValues{PublicID: common.HexToHash("0x0a").Hex(), CommitmentID: common.HexToHash("0x0b").Hex()}
+	p := SP1Proof{Proof: "proof", Stdout: StdIO{Buffer: StdBuffer{Data: v.Encode()}}}
+	require.ErrorIs(t, VerifyProof(p), ErrNotCommitted)
+	require.ErrorIs(t, VerifySP1(p), ErrInvalidSP1)
+}
@@ -0,0 +1,202 @@
+package quiknode
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+var ErrUnknownFormat = errors.New("unknown payload format")
+
+// Format of the payloads delivered by a feed
+type Format string
+
+const (
+	// FormatQuickAlerts is the QuickAlerts payload,
+	// the matched receipts & transactions of a block
+	FormatQuickAlerts Format = "quickalerts"
+	// FormatStreams is a batch of the QuickNode Streams block_with_receipts
+	// or receipts datasets, optionally wrapped in a data field with metadata
+	FormatStreams Format = "streams"
+	// FormatLogs is the result array of eth_getLogs,
+	// optionally wrapped in its JSON-RPC response
+	FormatLogs Format = "logs"
+)
+
+// Formats lists the supported payload formats
+var Formats = []Format{FormatQuickAlerts, FormatStreams, FormatLogs}
+
+// ParseFormat returns the format named s, FormatQuickAlerts if s is empty
+func ParseFormat(s string) (Format, error) {
+	if s == "" {
+		return FormatQuickAlerts, nil
+	}
+	for _, f := range Formats {
+		if strings.EqualFold(s, string(f)) {
+			return f, nil
+		}
+	}
+	return "", errors.Wrap(ErrUnknownFormat, s)
+}
+
+// BlockRange of the logs of a payload, zero if no log has a block number
+type BlockRange struct {
+	First uint64 `json:"first"`
+	Last  uint64 `json:"last"`
+}
+
+// IsZero returns true if the range has no block
+func (b BlockRange) IsZero() bool {
+	return b == BlockRange{}
+}
+
+func (b *BlockRange) add(number uint64) {
+	if b.IsZero() {
+		b.First, b.Last = number, number
+		return
+	}
+	if number < b.First {
+		b.First = number
+	}
+	if number > b.Last {
+		b.Last = number
+	}
+}
+
+// Decode returns the Coinbase EAS events of a payload of the format,
+// the empty format is FormatQuickAlerts
+func Decode(format Format, r io.Reader) ([]eas.EAS, error) {
+	events, _, err := DecodeBlocks(format, r)
+	return events, err
+}
+
+// DecodeBlocks returns the Coinbase EAS events of a payload of the format
+// & the block range of all its logs
+func DecodeBlocks(format Format, r io.Reader) ([]eas.EAS, BlockRange, error) {
+	walk, err := walker(format)
+	if err != nil {
+		return nil, BlockRange{}, err
+	}
+	d, err := decode(r, walk)
+	if err != nil {
+		return nil, BlockRange{}, err
+	}
+	return d.output, d.blocks, nil
+}
+
+// EventLog is a Coinbase EAS event with the log it was decoded from
+type EventLog struct {
+	eas.EAS
+	BlockNumber uint64      `json:"blockNumber"`
+	BlockHash   common.Hash `json:"blockHash"`
+	TxHash      common.Hash `json:"transactionHash"`
+	LogIndex    uint        `json:"logIndex"`
+}
+
+// DecodeEventLogs returns the Coinbase EAS events of a payload of the format
+// with their logs, the location fields missing from the payload are zero
+func DecodeEventLogs(format Format, r io.Reader) ([]EventLog, error) {
+	walk, err := walker(format)
+	if err != nil {
+		return nil, err
+	}
+	d, err := decodeWith(&decoder{Decoder: json.NewDecoder(r), located: true}, walk)
+	if err != nil {
+		return nil, err
+	}
+	return d.events, nil
+}
+
+// walker returns the walk func of the format
+func walker(format Format) (func(d *decoder) error, error) {
+	switch format {
+	case "", FormatQuickAlerts:
+		return walkPayload, nil
+	case FormatStreams:
+		return walkStreams, nil
+	case FormatLogs:
+		return walkLogs, nil
+	default:
+		return nil, errors.Wrap(ErrUnknownFormat, string(format))
+	}
+}
+
+// DecodeStreams returns the Coinbase EAS events of a QuickNode Streams
+// batch, an array of blocks with their receipts or of receipt arrays.
+// The blocks are skipped.
+func DecodeStreams(r io.Reader) ([]eas.EAS, error) {
+	return Decode(FormatStreams, r)
+}
+
+func walkStreams(d *decoder) error {
+	delim, err := open(d.Decoder)
+	if err != nil || delim == 0 {
+		return err
+	}
+	if delim == '[' {
+		return elems(d.Decoder, d.streamsItem)
+	}
+	// batch wrapped with its metadata
+	return fields(d.Decoder, func(key string) error {
+		if key != "data" {
+			return d.skip()
+		}
+		return array(d.Decoder, d.streamsItem)
+	})
+}
+
+// streamsItem decodes a block with its receipts or a receipt array
+func (d *decoder) streamsItem() error {
+	delim, err := open(d.Decoder)
+	if err != nil || delim == 0 {
+		return err
+	}
+	if delim == '[' {
+		return elems(d.Decoder, func() error {
+			return object(d.Decoder, d.receiptField)
+		})
+	}
+	return fields(d.Decoder, func(key string) error {
+		if key != "receipts" {
+			return d.skip()
+		}
+		return d.receipts()
+	})
+}
+
+// DecodeLogs returns the Coinbase EAS events of the result of eth_getLogs,
+// an array of logs or the JSON-RPC response holding it
+func DecodeLogs(r io.Reader) ([]eas.EAS, error) {
+	return Decode(FormatLogs, r)
+}
+
+func walkLogs(d *decoder) error {
+	delim, err := open(d.Decoder)
+	if err != nil || delim == 0 {
+		return err
+	}
+	if delim == '[' {
+		return elems(d.Decoder, d.log)
+	}
+	return fields(d.Decoder, func(key string) error {
+		switch key {
+		case "result":
+			return d.logs()
+		case "error":
+			var rpcErr *struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			}
+			if err := d.Decode(&rpcErr); err != nil || rpcErr == nil {
+				return err
+			}
+			return errors.Errorf("JSON-RPC error %d: %s", rpcErr.Code, rpcErr.Message)
+		default:
+			return d.skip()
+		}
+	})
+}
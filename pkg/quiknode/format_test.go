@@ -0,0 +1,158 @@
+package quiknode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// formats returns the receipts of the scaled test payload in each format
+func formats(t *testing.T, n int) map[Format][]string {
+	b := scaledPayload(t, n)
+	var payload struct {
+		MatchedReceipts []json.RawMessage `json:"matchedReceipts"`
+	}
+	require.NoError(t, json.Unmarshal(b, &payload))
+	receipts := payload.MatchedReceipts
+
+	var logs []string
+	for _, r := range receipts {
+		var receipt struct {
+			Logs []json.RawMessage `json:"logs"`
+		}
+		require.NoError(t, json.Unmarshal(r, &receipt))
+		for _, l := range receipt.Logs {
+			logs = append(logs, string(l))
+		}
+	}
+	join := func(values []json.RawMessage) string {
+		var out []string
+		for _, v := range values {
+			out = append(out, string(v))
+		}
+		return strings.Join(out, ",")
+	}
+	block := `{"number":"0xb2bbad","transactions":[{"hash":"0x01"}],"logsBloom":"0x00"}`
+	half := len(receipts) / 2
+
+	return map[Format][]string{
+		FormatQuickAlerts: {string(b)},
+		FormatStreams: {
+			fmt.Sprintf(`[{"block":%s,"receipts":[%s]}]`, block, join(receipts)),
+			fmt.Sprintf(`[{"receipts":[%s],"block":%s},{"block":%s,"receipts":[%s]}]`, join(receipts[:half]), block, block, join(receipts[half:])),
+			fmt.Sprintf(`{"metadata":{"batch_start_range":1},"data":[{"block":%s,"receipts":[%s]}]}`, block, join(receipts)),
+			fmt.Sprintf(`[[%s],[%s]]`, join(receipts[:half]), join(receipts[half:])),
+		},
+		FormatLogs: {
+			"[" + strings.Join(logs, ",") + "]",
+			`{"jsonrpc":"2.0","id":1,"error":null,"result":[` + strings.Join(logs, ",") + `]}`,
+		},
+	}
+}
+
+func Test_Decode_Formats(t *testing.T) {
+	for _, n := range []int{1, 10} {
+		want, err := parse(scaledPayload(t, n))
+		require.NoError(t, err)
+		require.Len(t, want, n)
+
+		for format, payloads := range formats(t, n) {
+			for i, payload := range payloads {
+				got, blocks, err := DecodeBlocks(format, strings.NewReader(payload))
+				require.NoError(t, err, "%s payload %d", format, i)
+				require.Equal(t, want, got, "%s payload %d", format, i)
+				require.Equal(t, BlockRange{First: 0xb2bbad, Last: 0xb2bbad}, blocks, "%s payload %d", format, i)
+			}
+		}
+	}
+
+	// the default format
+	got, err := Decode("", bytes.NewReader(scaledPayload(t, 2)))
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+}
+
+func Test_DecodeBlocks(t *testing.T) {
+	_, blocks, err := DecodeBlocks(FormatLogs, strings.NewReader(`[{"blockNumber":"0x5","topics":[]},{"topics":[]},{"blockNumber":"0x3","topics":[]},{"blockNumber":"0x9","topics":[]}]`))
+	require.NoError(t, err)
+	require.Equal(t, BlockRange{First: 3, Last: 9}, blocks)
+
+	_, blocks, err = DecodeBlocks(FormatLogs, strings.NewReader(`[]`))
+	require.NoError(t, err)
+	require.True(t, blocks.IsZero())
+}
+
+func Test_DecodeEventLogs(t *testing.T) {
+	want, err := parse(scaledPayload(t, 2))
+	require.NoError(t, err)
+
+	for format, payloads := range formats(t, 2) {
+		for i, payload := range payloads {
+			got, err := DecodeEventLogs(format, strings.NewReader(payload))
+			require.NoError(t, err, "%s payload %d", format, i)
+			require.Len(t, got, len(want), "%s payload %d", format, i)
+			for j, l := range got {
+				require.Equal(t, EventLog{
+					EAS:         want[j],
+					BlockNumber: 0xb2bbad,
+					BlockHash:   common.HexToHash("0x6644e74c3ff45ac8d514cdfc7393bcd193067c47c138842427a46a49d528573a"),
+					TxHash:      common.HexToHash("0x63a3aef220d84947b12b0d771fa0df510eb753cab0c218efa1861b0d7c3d4567"),
+					LogIndex:    1,
+				}, l, "%s payload %d", format, i)
+			}
+		}
+	}
+
+	// the missing location fields are zero
+	got, err := DecodeEventLogs(FormatLogs, strings.NewReader(`[{"topics":[]}]`))
+	require.NoError(t, err)
+	require.Empty(t, got)
+	_, err = DecodeEventLogs("rss", strings.NewReader(`[]`))
+	require.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func Test_Decode_Invalid(t *testing.T) {
+	for _, tc := range []struct {
+		format  Format
+		payload string
+		err     error
+	}{
+		{format: "blocks", payload: `[]`, err: ErrUnknownFormat},
+		{format: FormatStreams, payload: `"batch"`, err: ErrInvalidPayload},
+		{format: FormatStreams, payload: `[{"receipts":{}}]`, err: ErrInvalidPayload},
+		{format: FormatStreams, payload: `{"data":[1]}`, err: ErrInvalidPayload},
+		{format: FormatLogs, payload: `{"matchedReceipts":[]}{}`, err: ErrInvalidPayload},
+		{format: FormatLogs, payload: `[{"topics":"0x01"}]`, err: ErrInvalidPayload},
+		{format: FormatLogs, payload: `{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"query returned more than 10000 results"}}`, err: ErrInvalidPayload},
+	} {
+		_, err := Decode(tc.format, strings.NewReader(tc.payload))
+		require.ErrorIs(t, err, tc.err, "%s %s", tc.format, tc.payload)
+	}
+
+	// empty batches
+	for _, format := range Formats {
+		for _, payload := range []string{`null`, `[]`, `{}`} {
+			if format == FormatQuickAlerts && payload == `[]` {
+				continue
+			}
+			events, err := Decode(format, strings.NewReader(payload))
+			require.NoError(t, err, "%s %s", format, payload)
+			require.Empty(t, events)
+		}
+	}
+}
+
+func Test_ParseFormat(t *testing.T) {
+	for s, want := range map[string]Format{"": FormatQuickAlerts, "quickalerts": FormatQuickAlerts, "Streams": FormatStreams, "logs": FormatLogs} {
+		got, err := ParseFormat(s)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	}
+	_, err := ParseFormat("webhook")
+	require.ErrorIs(t, err, ErrUnknownFormat)
+}
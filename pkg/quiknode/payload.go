@@ -54,31 +54,46 @@ func ParsePayload(p *Payload) ([]eas.EAS, error) {
 	// work out the attested addresses based on the logs
 	// return the attested addresses
 	var output []eas.EAS
-	var easType eas.EAS_TYPE
 	for _, receipt := range p.MatchedReceipts {
 		for _, log := range receipt.Logs {
-			if len(log.Topics) == 4 {
-
-				switch log.Topics[0] {
-				case common.HexToHash(eas.COINBASE_EAS_ATTEST_TOPIC):
-					easType = eas.EAS_ATTEST
-				case common.HexToHash(eas.COINBASE_EAS_REVOKE_TOPIC):
-					easType = eas.EAS_REVOKE
-				default:
-					easType = eas.EAS_UNKNOWN
-				}
-
-				if easType != eas.EAS_UNKNOWN &&
-					log.Topics[2] == common.HexToHash(eas.COINBASE_EAS_HASH) &&
-					log.Topics[3] == common.HexToHash(eas.COINBASE_EAS_SCHEMA_ID) {
-					output = append(output, eas.EAS{
-						UUID:    common.Hash(log.Data),
-						Account: log.Topics[1],
-						Type:    easType,
-					})
-				}
+			if e, ok := logToEAS(log.Topics, log.Data); ok {
+				output = append(output, e)
 			}
 		}
 	}
 	return output, nil
 }
+
+var (
+	attestTopic = common.HexToHash(eas.COINBASE_EAS_ATTEST_TOPIC)
+	revokeTopic = common.HexToHash(eas.COINBASE_EAS_REVOKE_TOPIC)
+	easHash     = common.HexToHash(eas.COINBASE_EAS_HASH)
+	easSchemaID = common.HexToHash(eas.COINBASE_EAS_SCHEMA_ID)
+)
+
+// logToEAS returns the Coinbase EAS event of a log,
+// ok is false if the log is not a Coinbase attestation or revocation
+func logToEAS(topics []common.Hash, data []byte) (e eas.EAS, ok bool) {
+	if len(topics) != 4 || len(data) < common.HashLength {
+		return e, false
+	}
+
+	var easType eas.EAS_TYPE
+	switch topics[0] {
+	case attestTopic:
+		easType = eas.EAS_ATTEST
+	case revokeTopic:
+		easType = eas.EAS_REVOKE
+	default:
+		return e, false
+	}
+
+	if topics[2] != easHash || topics[3] != easSchemaID {
+		return e, false
+	}
+	return eas.EAS{
+		UUID:    common.Hash(data),
+		Account: topics[1],
+		Type:    easType,
+	}, true
+}
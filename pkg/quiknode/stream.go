@@ -0,0 +1,197 @@
+package quiknode
+
+import (
+	"encoding/json"
+	"io"
+
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidPayload = errors.New("invalid payload")
+
+// receiptLog holds the only fields of a receipt log needed for the EAS events
+type receiptLog struct {
+	Topics      []common.Hash   `json:"topics"`
+	Data        hexutil.Bytes   `json:"data"`
+	BlockNumber *hexutil.Uint64 `json:"blockNumber"`
+}
+
+// locatedLog is a receiptLog with the fields locating it on chain
+type locatedLog struct {
+	receiptLog
+	BlockHash common.Hash  `json:"blockHash"`
+	TxHash    common.Hash  `json:"transactionHash"`
+	LogIndex  hexutil.Uint `json:"logIndex"`
+}
+
+// skip is decoded in place of the values we don't need,
+// the decoder validates them without allocating
+type skip struct{}
+
+func (*skip) UnmarshalJSON([]byte) error { return nil }
+
+// DecodePayload streams the payload & returns the Coinbase EAS events of
+// the logs of its matched receipts, like ParsePayload.
+// The matched transactions & the other receipt fields are skipped.
+func DecodePayload(r io.Reader) ([]eas.EAS, error) {
+	d, err := decode(r, walkPayload)
+	if err != nil {
+		return nil, err
+	}
+	return d.output, nil
+}
+
+func walkPayload(d *decoder) error {
+	return object(d.Decoder, func(key string) error {
+		if key != "matchedReceipts" {
+			return d.skip()
+		}
+		return d.receipts()
+	})
+}
+
+// decoder collects the EAS events & the block range of the logs of a payload
+type decoder struct {
+	*json.Decoder
+	output []eas.EAS
+	blocks BlockRange
+
+	// the logs of the events are only collected when located is set
+	located bool
+	events  []EventLog
+}
+
+// decode walks the payload of r
+func decode(r io.Reader, walk func(d *decoder) error) (*decoder, error) {
+	return decodeWith(&decoder{Decoder: json.NewDecoder(r)}, walk)
+}
+
+func decodeWith(d *decoder, walk func(d *decoder) error) (*decoder, error) {
+	if err := walk(d); err != nil {
+		return nil, errors.Wrap(ErrInvalidPayload, err.Error())
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return nil, errors.Wrap(ErrInvalidPayload, "trailing data")
+	}
+	return d, nil
+}
+
+func (d *decoder) skip() error {
+	return d.Decode(&skip{})
+}
+
+// receipts decodes an array of receipts
+func (d *decoder) receipts() error {
+	return array(d.Decoder, func() error {
+		return object(d.Decoder, d.receiptField)
+	})
+}
+
+func (d *decoder) receiptField(key string) error {
+	if key != "logs" {
+		return d.skip()
+	}
+	return d.logs()
+}
+
+// logs decodes an array of logs
+func (d *decoder) logs() error {
+	return array(d.Decoder, d.log)
+}
+
+func (d *decoder) log() error {
+	var l locatedLog
+	if d.located {
+		if err := d.Decode(&l); err != nil {
+			return err
+		}
+	} else if err := d.Decode(&l.receiptLog); err != nil {
+		return err
+	}
+
+	var number uint64
+	if l.BlockNumber != nil {
+		number = uint64(*l.BlockNumber)
+		d.blocks.add(number)
+	}
+	if e, ok := logToEAS(l.Topics, l.Data); ok {
+		d.output = append(d.output, e)
+		if d.located {
+			d.events = append(d.events, EventLog{
+				EAS:         e,
+				BlockNumber: number,
+				BlockHash:   l.BlockHash,
+				TxHash:      l.TxHash,
+				LogIndex:    uint(l.LogIndex),
+			})
+		}
+	}
+	return nil
+}
+
+// open reads the opening delimiter of the next value, 0 for null
+func open(dec *json.Decoder) (json.Delim, error) {
+	tok, err := dec.Token()
+	if err != nil || tok == nil {
+		return 0, err
+	}
+	if d, ok := tok.(json.Delim); ok && (d == '{' || d == '[') {
+		return d, nil
+	}
+	return 0, errors.Errorf("expected object or array, got %v", tok)
+}
+
+// object calls field for the key of each field of the next object,
+// field decodes its value. A null object has no field.
+func object(dec *json.Decoder, field func(key string) error) error {
+	d, err := open(dec)
+	if err != nil || d == 0 {
+		return err
+	}
+	if d != '{' {
+		return errors.New("expected object, got array")
+	}
+	return fields(dec, field)
+}
+
+// fields of an object which opening delimiter was read
+func fields(dec *json.Decoder, field func(key string) error) error {
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if err := field(tok.(string)); err != nil {
+			return err
+		}
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// array calls elem for each element of the next array, elem decodes it.
+// A null array has no element.
+func array(dec *json.Decoder, elem func() error) error {
+	d, err := open(dec)
+	if err != nil || d == 0 {
+		return err
+	}
+	if d != '[' {
+		return errors.New("expected array, got object")
+	}
+	return elems(dec, elem)
+}
+
+// elems of an array which opening delimiter was read
+func elems(dec *json.Decoder, elem func() error) error {
+	for dec.More() {
+		if err := elem(); err != nil {
+			return err
+		}
+	}
+	_, err := dec.Token()
+	return err
+}
@@ -0,0 +1,134 @@
+package quiknode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// scaledPayload returns testPayload.json with its matched receipts
+// & transactions repeated n times, the attestations get distinct UUIDs
+func scaledPayload(t testing.TB, n int) []byte {
+	b, err := os.ReadFile("testPayload.json")
+	require.NoError(t, err)
+
+	var payload map[string][]json.RawMessage
+	require.NoError(t, json.Unmarshal(b, &payload))
+	uuid := "8133f214f7bdaf516f03655db7406ba3c7945e5e4849238e43fdea6ef7a25cd4"
+
+	scaled := make(map[string][]json.RawMessage)
+	for key, values := range payload {
+		for i := 0; i < n; i++ {
+			for _, v := range values {
+				v = json.RawMessage(strings.ReplaceAll(string(v), uuid, fmt.Sprintf("%064x", i)))
+				scaled[key] = append(scaled[key], v)
+			}
+		}
+	}
+	b, err = json.Marshal(scaled)
+	require.NoError(t, err)
+	return b
+}
+
+func parse(b []byte) ([]eas.EAS, error) {
+	payload := new(Payload)
+	if err := json.Unmarshal(b, &payload); err != nil {
+		return nil, err
+	}
+	return ParsePayload(payload)
+}
+
+func Test_DecodePayload(t *testing.T) {
+	for _, n := range []int{1, 100} {
+		b := scaledPayload(t, n)
+		want, err := parse(b)
+		require.NoError(t, err)
+		require.Len(t, want, n)
+
+		got, err := DecodePayload(bytes.NewReader(b))
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	}
+
+	got, err := DecodePayload(bytes.NewReader(scaledPayload(t, 3)))
+	require.NoError(t, err)
+	require.Equal(t, eas.EAS{
+		UUID:    common.HexToHash("0x02"),
+		Account: common.HexToHash("0x000000000000000000000000ff9418c67d18c8e067141bd77be43e32c4c3abe7"),
+		Type:    eas.EAS_ATTEST,
+	}, got[2])
+}
+
+// attestLog returns a Coinbase attestation log with data
+func attestLog(data string) string {
+	topics := []string{eas.COINBASE_EAS_ATTEST_TOPIC, common.HexToHash("0x01").Hex(), eas.COINBASE_EAS_HASH, eas.COINBASE_EAS_SCHEMA_ID}
+	return fmt.Sprintf(`{"topics":["%s"],"data":"%s"}`, strings.Join(topics, `","`), data)
+}
+
+func Test_DecodePayload_Edges(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		payload string
+		events  int
+		err     bool
+	}{
+		{name: "empty object", payload: `{}`},
+		{name: "null receipts", payload: `{"matchedReceipts":null}`},
+		{name: "null logs", payload: `{"matchedReceipts":[{"logs":null}]}`},
+		{name: "short data", payload: `{"matchedReceipts":[{"logs":[` + attestLog("0x01") + `]}]}`},
+		{name: "attestation", payload: `{"other":[1,{"a":null}],"matchedReceipts":[{"status":"0x1","logs":[` + attestLog(common.HexToHash("0x02").Hex()) + `]}]}`, events: 1},
+		{name: "empty", payload: ``, err: true},
+		{name: "truncated", payload: `{"matchedReceipts":[{"logs":[`, err: true},
+		{name: "receipts object", payload: `{"matchedReceipts":{}}`, err: true},
+		{name: "invalid topic", payload: `{"matchedReceipts":[{"logs":[{"topics":["0xzz"]}]}]}`, err: true},
+		{name: "invalid skipped value", payload: `{"matchedTransactions":[tru]}`, err: true},
+		{name: "trailing data", payload: `{}{}`, err: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			events, err := DecodePayload(strings.NewReader(tc.payload))
+			if tc.err {
+				require.ErrorIs(t, err, ErrInvalidPayload)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, events, tc.events)
+		})
+	}
+}
+
+func benchmarkPayload(b *testing.B, n int, decode func([]byte) ([]eas.EAS, error)) {
+	payload := scaledPayload(b, n)
+	b.SetBytes(int64(len(payload)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := decode(payload); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParsePayload(b *testing.B) {
+	for _, n := range []int{1, 100, 1000} {
+		b.Run(fmt.Sprintf("receipts=%d", n), func(b *testing.B) {
+			benchmarkPayload(b, n, parse)
+		})
+	}
+}
+
+func BenchmarkDecodePayload(b *testing.B) {
+	for _, n := range []int{1, 100, 1000} {
+		b.Run(fmt.Sprintf("receipts=%d", n), func(b *testing.B) {
+			benchmarkPayload(b, n, func(payload []byte) ([]eas.EAS, error) {
+				return DecodePayload(bytes.NewReader(payload))
+			})
+		})
+	}
+}
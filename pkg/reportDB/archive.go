@@ -0,0 +1,97 @@
+package reportdb
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/andybalholm/brotli"
+	"github.com/pkg/errors"
+)
+
+// ArchiveEntry is a report of an archive
+// with the public IDs it was stored for
+type ArchiveEntry struct {
+	PublicIDs []string `json:"publicIDs"`
+	Report    Report   `json:"report"`
+}
+
+// Entries returns the reports of the DB in the order they were stored,
+// a report stored for several public IDs is returned once.
+func (r *ReportDB) Entries() []ArchiveEntry {
+	r.mut.RLock()
+	defer r.mut.RUnlock()
+
+	publicIDs := make([]string, 0, len(r.accounts))
+	for publicID := range r.accounts {
+		publicIDs = append(publicIDs, publicID)
+	}
+	sort.Slice(publicIDs, func(i, j int) bool {
+		return r.accounts[publicIDs[i]].ref.Seq < r.accounts[publicIDs[j]].ref.Seq
+	})
+
+	var (
+		entries []ArchiveEntry
+		index   = make(map[string]int)
+	)
+	for _, publicID := range publicIDs {
+		id := r.accounts[publicID].ref.ReportID
+		if i, ok := index[id]; ok {
+			entries[i].PublicIDs = append(entries[i].PublicIDs, publicID)
+			continue
+		}
+		index[id] = len(entries)
+		entries = append(entries, ArchiveEntry{PublicIDs: []string{publicID}, Report: r.reports[id].report})
+	}
+	return entries
+}
+
+// Export writes the reports of the DB to a brotli compressed archive
+func (r *ReportDB) Export(w io.Writer) error {
+	return WriteArchive(w, r.Entries())
+}
+
+// Import stores the reports of a brotli compressed archive in order
+// and returns the number of reports read.
+func (r *ReportDB) Import(rd io.Reader) (int, error) {
+	entries, err := ReadArchive(rd)
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range entries {
+		for _, publicID := range e.PublicIDs {
+			r.Set(publicID, e.Report.GetTimeStamp(), e.Report)
+		}
+	}
+	return len(entries), nil
+}
+
+// WriteArchive writes the entries as brotli compressed JSON lines
+func WriteArchive(w io.Writer, entries []ArchiveEntry) error {
+	bw := brotli.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for i, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			bw.Close()
+			return errors.Wrapf(err, "failed to write archive entry %d", i)
+		}
+	}
+	return errors.Wrap(bw.Close(), "failed to write archive")
+}
+
+// ReadArchive reads the entries of an archive written by WriteArchive
+func ReadArchive(rd io.Reader) ([]ArchiveEntry, error) {
+	var (
+		entries []ArchiveEntry
+		dec     = json.NewDecoder(brotli.NewReader(rd))
+	)
+	for {
+		var e ArchiveEntry
+		if err := dec.Decode(&e); err == io.EOF {
+			return entries, nil
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "failed to read archive entry %d", len(entries))
+		}
+		entries = append(entries, e)
+	}
+}
@@ -0,0 +1,68 @@
+package reportdb
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testReport(i int, ts time.Time) Report {
+	return Report{
+		Header: ReportHeader{
+			NotificationID: "feed",
+			ContentHash:    fmt.Sprintf("hash-%d", i),
+			Nonce:          "nonce",
+			Signature:      fmt.Sprintf("sig-%d", i),
+			Timestamp:      ts.Format(Header_Time_Layout),
+		},
+		Body: fmt.Sprintf(`{"report":%d}`, i),
+	}
+}
+
+func Test_Archive(t *testing.T) {
+	now := time.Now()
+	rDB := NewReportDB()
+
+	// the report 1 is stored for 2 public IDs,
+	// the report 0 is replaced by the report 3
+	reports := []Report{testReport(0, now), testReport(1, now), testReport(2, now), testReport(3, now.Add(time.Second))}
+	for i, publicID := range []string{"a", "b", "c", "d", "a"} {
+		r := reports[[]int{0, 1, 2, 1, 3}[i]]
+		rDB.Set(publicID, r.GetTimeStamp(), r)
+	}
+
+	require.Equal(t, []ArchiveEntry{
+		{PublicIDs: []string{"b", "d"}, Report: reports[1]},
+		{PublicIDs: []string{"c"}, Report: reports[2]},
+		{PublicIDs: []string{"a"}, Report: reports[3]},
+	}, rDB.Entries())
+
+	var archive bytes.Buffer
+	require.NoError(t, rDB.Export(&archive))
+
+	entries, err := ReadArchive(bytes.NewReader(archive.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, rDB.Entries(), entries)
+
+	imported := NewReportDB()
+	n, err := imported.Import(bytes.NewReader(archive.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.Equal(t, rDB.Entries(), imported.Entries())
+	for _, publicID := range []string{"a", "b", "c", "d"} {
+		require.Equal(t, rDB.Get(publicID), imported.Get(publicID))
+	}
+
+	// empty & corrupted archives
+	archive.Reset()
+	require.NoError(t, NewReportDB().Export(&archive))
+	entries, err = ReadArchive(&archive)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	_, err = ReadArchive(bytes.NewReader([]byte("not an archive")))
+	require.Error(t, err)
+}
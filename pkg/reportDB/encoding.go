@@ -0,0 +1,132 @@
+package reportdb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andybalholm/brotli"
+	"github.com/pkg/errors"
+)
+
+// content encodings of the report bodies
+const (
+	EncodingIdentity = "identity"
+	EncodingGzip     = "gzip"
+	EncodingBrotli   = "br"
+)
+
+// MaxDecodedBodySize limits the size of a decompressed body
+const MaxDecodedBodySize = 64 << 20
+
+var ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// Encodings returns the content encodings of the body in the order
+// they were applied, identity is omitted.
+func (h ReportHeader) Encodings() []string {
+	var out []string
+	for _, enc := range strings.Split(h.ContentEncoding, ",") {
+		enc = strings.ToLower(strings.TrimSpace(enc))
+		if enc != "" && enc != EncodingIdentity {
+			out = append(out, enc)
+		}
+	}
+	return out
+}
+
+// Decoded returns the body decompressed according to the content encoding,
+// the raw body is returned as is when the body is not encoded.
+// The raw body is the one signed by the webhook & used for the proofs.
+func (r *Report) Decoded() ([]byte, error) {
+	body := []byte(r.Body)
+	encodings := r.Header.Encodings()
+	for i := len(encodings) - 1; i >= 0; i-- {
+		var err error
+		if body, err = decode(encodings[i], body); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode %s body", encodings[i])
+		}
+	}
+	return body, nil
+}
+
+func decode(encoding string, body []byte) ([]byte, error) {
+	var rd io.Reader
+	switch encoding {
+	case EncodingGzip, "x-gzip":
+		gr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		rd = gr
+	case EncodingBrotli:
+		rd = brotli.NewReader(bytes.NewReader(body))
+	default:
+		return nil, errors.Wrap(ErrUnsupportedEncoding, encoding)
+	}
+
+	out, err := io.ReadAll(io.LimitReader(rd, MaxDecodedBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > MaxDecodedBodySize {
+		return nil, errors.Errorf("body exceeds %d bytes", MaxDecodedBodySize)
+	}
+	return out, nil
+}
+
+// Encode compresses body with the content encoding & returns the raw body
+func Encode(encoding string, body []byte) (string, error) {
+	var (
+		buf bytes.Buffer
+		w   io.WriteCloser
+	)
+	switch strings.ToLower(encoding) {
+	case "", EncodingIdentity:
+		return string(body), nil
+	case EncodingGzip, "x-gzip":
+		w = gzip.NewWriter(&buf)
+	case EncodingBrotli:
+		w = brotli.NewWriter(&buf)
+	default:
+		return "", errors.Wrap(ErrUnsupportedEncoding, encoding)
+	}
+	if _, err := w.Write(body); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// report is the JSON encoding of a Report, a body which is not valid UTF-8,
+// such as a compressed body, is base64 encoded in RawBody to be preserved.
+type report struct {
+	Header  ReportHeader `json:"header"`
+	Body    string       `json:"body"`
+	RawBody []byte       `json:"rawBody,omitempty"`
+}
+
+func (r Report) MarshalJSON() ([]byte, error) {
+	out := report{Header: r.Header, Body: r.Body}
+	if !utf8.ValidString(r.Body) {
+		out.Body, out.RawBody = "", []byte(r.Body)
+	}
+	return json.Marshal(out)
+}
+
+func (r *Report) UnmarshalJSON(data []byte) error {
+	var in report
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	r.Header, r.Body = in.Header, in.Body
+	if in.RawBody != nil {
+		r.Body = string(in.RawBody)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package reportdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Report_Encodings(t *testing.T) {
+	a := common.HexToHash("0x0a")
+	plain := easReport(t, time.Now(), eas.COINBASE_EAS_ATTEST_TOPIC, a)
+	want, _, err := plain.Parse()
+	require.NoError(t, err)
+
+	for _, encoding := range []string{"", "identity", "gzip", "br", "gzip, br", "BR"} {
+		t.Run(encoding, func(t *testing.T) {
+			r := plain
+			r.Header.ContentEncoding = encoding
+			for _, enc := range r.Header.Encodings() {
+				r.Body, err = Encode(enc, []byte(r.Body))
+				require.NoError(t, err)
+			}
+
+			decoded, err := r.Decoded()
+			require.NoError(t, err)
+			require.Equal(t, plain.Body, string(decoded))
+			events, _, err := r.Parse()
+			require.NoError(t, err)
+			require.Equal(t, want, events)
+
+			// the raw body survives the JSON encoding & archives
+			b, err := json.Marshal(r)
+			require.NoError(t, err)
+			var out Report
+			require.NoError(t, json.Unmarshal(b, &out))
+			require.Equal(t, r, out)
+			require.Equal(t, r.ID(), out.ID())
+
+			var archive bytes.Buffer
+			require.NoError(t, WriteArchive(&archive, []ArchiveEntry{{PublicIDs: []string{a.String()}, Report: r}}))
+			entries, err := ReadArchive(&archive)
+			require.NoError(t, err)
+			require.Equal(t, r, entries[0].Report)
+		})
+	}
+
+	// the raw bytes identify the report
+	gz := plain
+	gz.Header.ContentEncoding = EncodingGzip
+	gz.Body, err = Encode(EncodingGzip, []byte(plain.Body))
+	require.NoError(t, err)
+	require.NotEqual(t, plain.ID(), gz.ID())
+
+	unsupported := plain
+	unsupported.Header.ContentEncoding = "deflate"
+	_, _, err = unsupported.Parse()
+	require.ErrorIs(t, err, ErrUnsupportedEncoding)
+	_, err = Encode("deflate", nil)
+	require.ErrorIs(t, err, ErrUnsupportedEncoding)
+
+	corrupted := plain
+	corrupted.Header.ContentEncoding = EncodingGzip
+	_, _, err = corrupted.Parse()
+	require.Error(t, err)
+}
@@ -0,0 +1,274 @@
+package reportdb
+
+import "sync"
+
+// DefaultQueueSize of a subscription
+const DefaultQueueSize = 1024
+
+// Policy of a subscription when its queue is full
+type Policy int
+
+const (
+	// Coalesce keeps at most one queued notification per public ID,
+	// new public IDs wait for room in the queue like Block.
+	// Subscribers reading the latest report of a public ID miss nothing.
+	Coalesce Policy = iota
+	// DropOldest drops the oldest queued notification
+	DropOldest
+	// Block blocks the writer of the report until the subscriber catches up
+	Block
+)
+
+func (p Policy) String() string {
+	switch p {
+	case Coalesce:
+		return "coalesce"
+	case DropOldest:
+		return "drop-oldest"
+	case Block:
+		return "block"
+	default:
+		return "unknown"
+	}
+}
+
+// queue of a subscription, values are delivered in order on c.
+// With Coalesce, merge folds a value into the queued value of the same key
+// & returns false when the queued value should be removed.
+type queue[T any] struct {
+	policy Policy
+	size   int
+	key    func(T) string
+	merge  func(queued *T, v T) bool
+
+	mut     sync.Mutex
+	cond    *sync.Cond
+	items   []*T
+	pending map[string]*T
+	dropped uint64
+	closed  bool
+
+	c    chan T
+	done chan struct{}
+	once sync.Once
+}
+
+func newQueue[T any](policy Policy, size int, key func(T) string, merge func(*T, T) bool) *queue[T] {
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+	q := &queue[T]{
+		policy:  policy,
+		size:    size,
+		key:     key,
+		merge:   merge,
+		pending: make(map[string]*T),
+		c:       make(chan T),
+		done:    make(chan struct{}),
+	}
+	q.cond = sync.NewCond(&q.mut)
+	go q.deliver()
+	return q
+}
+
+// C delivers the notifications, it is closed on Unsubscribe
+func (q *queue[T]) C() <-chan T {
+	return q.c
+}
+
+// Policy of the subscription
+func (q *queue[T]) Policy() Policy {
+	return q.policy
+}
+
+// Dropped returns the number of notifications dropped by DropOldest
+func (q *queue[T]) Dropped() uint64 {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+	return q.dropped
+}
+
+// len returns the number of queued values
+func (q *queue[T]) len() int {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+	return len(q.items)
+}
+
+func (q *queue[T]) close() {
+	q.once.Do(func() {
+		q.mut.Lock()
+		q.closed = true
+		q.items = nil
+		q.cond.Broadcast()
+		q.mut.Unlock()
+		close(q.done)
+	})
+}
+
+func (q *queue[T]) publish(v T) {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	switch q.policy {
+	case Coalesce:
+		if q.coalesce(v) {
+			return
+		}
+		if q.wait(); q.coalesce(v) {
+			return
+		}
+	case DropOldest:
+		if len(q.items) >= q.size {
+			q.items = q.items[1:]
+			q.dropped++
+		}
+	default:
+		q.wait()
+	}
+	if q.closed {
+		return
+	}
+
+	item := &v
+	if q.policy == Coalesce {
+		q.pending[q.key(v)] = item
+	}
+	q.items = append(q.items, item)
+	q.cond.Broadcast()
+}
+
+// coalesce merges v into the queued value of its key if any, q.mut is held
+func (q *queue[T]) coalesce(v T) bool {
+	key := q.key(v)
+	queued, ok := q.pending[key]
+	if !ok {
+		return false
+	}
+	if q.merge == nil || q.merge(queued, v) {
+		return true
+	}
+
+	delete(q.pending, key)
+	for i, item := range q.items {
+		if item == queued {
+			q.items = append(q.items[:i], q.items[i+1:]...)
+			break
+		}
+	}
+	q.cond.Broadcast()
+	return true
+}
+
+// wait for room in the queue, q.mut is held
+func (q *queue[T]) wait() {
+	for len(q.items) >= q.size && !q.closed {
+		q.cond.Wait()
+	}
+}
+
+func (q *queue[T]) deliver() {
+	defer close(q.c)
+	for {
+		q.mut.Lock()
+		for len(q.items) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if q.closed {
+			q.mut.Unlock()
+			return
+		}
+		item := q.items[0]
+		q.items = q.items[1:]
+		if q.policy == Coalesce {
+			delete(q.pending, q.key(*item))
+		}
+		q.cond.Broadcast()
+		q.mut.Unlock()
+
+		select {
+		case q.c <- *item:
+		case <-q.done:
+			return
+		}
+	}
+}
+
+// Subscription to the public IDs of new reports,
+// notifications are queued & delivered in order on C.
+type Subscription struct {
+	*queue[string]
+	rDb *ReportDB
+}
+
+// Subscribe to the public IDs of new reports with a queue of size
+// notifications, DefaultQueueSize if size <= 0.
+func (r *ReportDB) Subscribe(policy Policy, size int) *Subscription {
+	s := &Subscription{
+		queue: newQueue[string](policy, size, func(publicID string) string { return publicID }, nil),
+		rDb:   r,
+	}
+	r.subsMut.Lock()
+	r.subs[s] = struct{}{}
+	r.subsMut.Unlock()
+	return s
+}
+
+// Unsubscribe drops the queued notifications & closes C,
+// writers blocked by the subscription are released.
+func (s *Subscription) Unsubscribe() {
+	s.rDb.subsMut.Lock()
+	delete(s.rDb.subs, s)
+	s.rDb.subsMut.Unlock()
+	s.close()
+}
+
+// SubscribeToNotif forwards the public IDs of new reports to notifChan
+// with the Coalesce policy until UnsubscribeFromNotif is called.
+func (r *ReportDB) SubscribeToNotif(notifChan chan string) {
+	s := r.Subscribe(Coalesce, DefaultQueueSize)
+
+	r.subsMut.Lock()
+	r.notifSubs[notifChan] = append(r.notifSubs[notifChan], s.Unsubscribe)
+	r.subsMut.Unlock()
+
+	go forward(s.queue, notifChan)
+}
+
+// UnsubscribeFromNotif stops the notifications to notifChan,
+// notifChan is not closed.
+func (r *ReportDB) UnsubscribeFromNotif(notifChan chan string) {
+	r.subsMut.Lock()
+	unsubscribe := r.notifSubs[notifChan]
+	delete(r.notifSubs, notifChan)
+	r.subsMut.Unlock()
+
+	for _, fn := range unsubscribe {
+		fn()
+	}
+}
+
+// SendNotification queues publicID for every subscription
+func (r *ReportDB) SendNotification(publicID string) {
+	r.subsMut.Lock()
+	subs := make([]*Subscription, 0, len(r.subs))
+	for s := range r.subs {
+		subs = append(subs, s)
+	}
+	r.subsMut.Unlock()
+
+	for _, s := range subs {
+		s.publish(publicID)
+	}
+}
+
+// forward the values of q to c until q is closed
+func forward[T any](q *queue[T], c chan T) {
+	for v := range q.C() {
+		select {
+		case c <- v:
+		case <-q.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package reportdb
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// receive reads n notifications or fails after a second
+func receive(t *testing.T, c <-chan string, n int) []string {
+	var out []string
+	for len(out) < n {
+		select {
+		case publicID, ok := <-c:
+			require.True(t, ok, "closed subscription")
+			out = append(out, publicID)
+		case <-time.After(time.Second):
+			require.FailNow(t, "missing notifications", "got %d of %d", len(out), n)
+		}
+	}
+	return out
+}
+
+// drain reads the notifications until none is received for 20ms
+func drain(c <-chan string) []string {
+	var out []string
+	for {
+		select {
+		case publicID := <-c:
+			out = append(out, publicID)
+		case <-time.After(20 * time.Millisecond):
+			return out
+		}
+	}
+}
+
+func requireNone(t *testing.T, c <-chan string) {
+	select {
+	case publicID := <-c:
+		require.FailNow(t, "unexpected notification", publicID)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func Test_Notif_DropOldest(t *testing.T) {
+	rDB := NewReportDB()
+	sub := rDB.Subscribe(DropOldest, 4)
+	defer sub.Unsubscribe()
+
+	// the writer never blocks
+	for i := 0; i < 100; i++ {
+		rDB.Set(fmt.Sprint(i), 0, Report{})
+	}
+
+	// a notification may already be out of the queue
+	got := drain(sub.C())
+	require.GreaterOrEqual(t, len(got), 4)
+	require.LessOrEqual(t, len(got), 5)
+	require.Equal(t, []string{"96", "97", "98", "99"}, got[len(got)-4:])
+	require.Equal(t, uint64(100-len(got)), sub.Dropped())
+}
+
+func Test_Notif_Block(t *testing.T) {
+	rDB := NewReportDB()
+	sub := rDB.Subscribe(Block, 2)
+	defer sub.Unsubscribe()
+
+	written := make(chan int, 100)
+	go func() {
+		for i := 0; i < 100; i++ {
+			rDB.Set(fmt.Sprint(i), 0, Report{})
+			written <- i
+		}
+	}()
+
+	// the writer waits for the subscriber,
+	// at most 2 queued & 1 being delivered
+	time.Sleep(20 * time.Millisecond)
+	require.LessOrEqual(t, len(written), 3)
+
+	// reads are not blocked by the writer
+	require.Equal(t, Report{}, rDB.Get("0"))
+
+	got := receive(t, sub.C(), 100)
+	for i, publicID := range got {
+		require.Equal(t, fmt.Sprint(i), publicID)
+	}
+	require.Zero(t, sub.Dropped())
+}
+
+func Test_Notif_Coalesce(t *testing.T) {
+	rDB := NewReportDB()
+	sub := rDB.Subscribe(Coalesce, 2)
+	defer sub.Unsubscribe()
+
+	for i := 0; i < 100; i++ {
+		rDB.Set("a", 0, Report{})
+	}
+	rDB.Set("b", 0, Report{})
+
+	// "a" at most once in the queue & once being delivered
+	got := receive(t, sub.C(), 2)
+	if got[1] == "a" {
+		got = append(got, receive(t, sub.C(), 1)...)
+		require.Equal(t, []string{"a", "a", "b"}, got)
+	} else {
+		require.Equal(t, []string{"a", "b"}, got)
+	}
+	requireNone(t, sub.C())
+}
+
+func Test_Notif_Unsubscribe(t *testing.T) {
+	rDB := NewReportDB()
+	blocking := rDB.Subscribe(Block, 1)
+	other := rDB.Subscribe(DropOldest, 10)
+	defer other.Unsubscribe()
+
+	// the writer is blocked by the full queue
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			rDB.Set("a", 0, Report{})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+		require.FailNow(t, "writer not blocked")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// unsubscribing releases the writer & closes C
+	blocking.Unsubscribe()
+	blocking.Unsubscribe()
+	<-done
+	for range blocking.C() {
+	}
+
+	// the other subscription still gets notified
+	require.NotEmpty(t, receive(t, other.C(), 10))
+	rDB.Set("b", 0, Report{})
+	require.Equal(t, []string{"b"}, receive(t, other.C(), 1))
+
+	// notification channels
+	notifChan := make(chan string)
+	rDB.SubscribeToNotif(notifChan)
+	rDB.Set("c", 0, Report{})
+	require.Equal(t, []string{"c"}, receive(t, notifChan, 1))
+	rDB.UnsubscribeFromNotif(notifChan)
+	rDB.Set("d", 0, Report{})
+	requireNone(t, notifChan)
+}
+
+func Test_Notif_HighVolume(t *testing.T) {
+	const (
+		writers   = 8
+		perWriter = 5000
+		accounts  = 100
+	)
+
+	rDB := NewReportDB()
+	var (
+		block    = rDB.Subscribe(Block, 16)
+		drop     = rDB.Subscribe(DropOldest, 16)
+		coalesce = rDB.Subscribe(Coalesce, accounts)
+		// a subscriber that never reads does not stall the others
+		stalled = rDB.Subscribe(DropOldest, 1)
+	)
+	defer stalled.Unsubscribe()
+
+	type result struct {
+		counts map[string]int
+		total  int
+	}
+	consume := func(sub *Subscription) <-chan result {
+		out := make(chan result, 1)
+		go func() {
+			res := result{counts: make(map[string]int)}
+			for publicID := range sub.C() {
+				res.counts[publicID]++
+				res.total++
+			}
+			out <- res
+		}()
+		return out
+	}
+	blockRes, dropRes, coalesceRes := consume(block), consume(drop), consume(coalesce)
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				rDB.Set(fmt.Sprint((w*perWriter+i)%accounts), 0, Report{})
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	// let the queues drain before unsubscribing
+	require.Eventually(t, func() bool {
+		for _, sub := range []*Subscription{block, drop, coalesce} {
+			if sub.len() > 0 {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	block.Unsubscribe()
+	drop.Unsubscribe()
+	coalesce.Unsubscribe()
+
+	// every notification is delivered to the blocking subscriber
+	res := <-blockRes
+	require.Equal(t, writers*perWriter, res.total)
+	require.Len(t, res.counts, accounts)
+	for _, n := range res.counts {
+		require.Equal(t, writers*perWriter/accounts, n)
+	}
+
+	// the delivered & dropped notifications add up
+	res = <-dropRes
+	require.Equal(t, writers*perWriter, res.total+int(drop.Dropped()))
+
+	// every account is notified at least once
+	res = <-coalesceRes
+	require.Len(t, res.counts, accounts)
+	require.LessOrEqual(t, res.total, writers*perWriter)
+
+	// 1 queued & 1 being delivered
+	require.Eventually(t, func() bool {
+		return stalled.Dropped() == writers*perWriter-2
+	}, time.Second, 10*time.Millisecond)
+}
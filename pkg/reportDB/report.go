@@ -1,61 +1,146 @@
 package reportdb
 
 import (
-	"encoding/json"
-	"errors"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"time"
 
 	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
 	quiknode "github.com/0xBow-io/base-eas-asp/pkg/quiknode"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrIncorrectHeader = errors.New("incorrect header")
+	ErrEmptyBody       = errors.New("empty body")
 )
 
 const Header_Time_Layout string = "2006-01-02 15:04:05.999999999 -0700 MST"
 
+// sources of the reports
+const (
+	// SourceWebhook reports are delivered & signed by the QuickNode webhook
+	SourceWebhook = ""
+	// SourceRPC reports hold logs read from a JSON-RPC node, they are unsigned
+	SourceRPC = "rpc"
+	// SourceBackfill reports fill a gap between webhook deliveries with logs
+	// read from the chain, they are unsigned but verified against the chain
+	SourceBackfill = "backfill"
+)
+
 type ReportHeader struct {
 	NotificationID string `json:"x-qn-notification-id"`
 	ContentHash    string `json:"x-qn-content-hash"`
 	Nonce          string `json:"x-qn-nonce"`
 	Signature      string `json:"x-qn-signature"`
 	Timestamp      string `json:"x-qn-timestamp"`
+	// content encodings of the body, comma separated in the order applied
+	ContentEncoding string `json:"content-encoding,omitempty"`
+	// format of the decoded body, set from the feed configuration
+	Format quiknode.Format `json:"format,omitempty"`
+	// source of the report, SourceWebhook if empty
+	Source string `json:"source,omitempty"`
+}
+
+// Signed returns true if the report carries a webhook signature
+func (h ReportHeader) Signed() bool {
+	return h.Source == SourceWebhook
+}
+
+// ChainVerified returns true if the logs of the report were read from the chain
+func (h ReportHeader) ChainVerified() bool {
+	return h.Source == SourceRPC || h.Source == SourceBackfill
 }
 
 type Report struct {
 	Header ReportHeader `json:"header"`
-	Body   string       `json:"body"` // payload body
+	Body   string       `json:"body"` // raw payload body, compressed if encoded
+}
+
+// ID of the report, the hex encoded sha256 hash of its body
+func (r *Report) ID() string {
+	hash := sha256.Sum256([]byte(r.Body))
+	return hex.EncodeToString(hash[:])
 }
 
+// GetTime returns the time of the header timestamp, see ParseTimestamp
+func (r *Report) GetTime() (time.Time, error) {
+	return ParseTimestamp(r.Header.Timestamp)
+}
+
+// GetTimeStamp returns the Unix time of the header timestamp in seconds,
+// 0 if it is invalid
 func (r *Report) GetTimeStamp() int64 {
-	parsedTime, err := time.Parse(Header_Time_Layout, r.Header.Timestamp)
+	parsedTime, err := r.GetTime()
 	if err != nil {
 		return 0
 	}
 	return parsedTime.Unix()
 }
 
+// GetTimeStampNano returns the Unix time of the header timestamp
+// in nanoseconds, 0 if it is invalid
+func (r *Report) GetTimeStampNano() int64 {
+	parsedTime, err := r.GetTime()
+	if err != nil {
+		return 0
+	}
+	return parsedTime.UnixNano()
+}
+
 // get public IDs & commitments (attested wallet address) from report
 
 func (r *Report) Parse() ([]eas.EAS, int64, error) {
-	// do basic validation
-	if r.GetTimeStamp() == 0 || r.Header.NotificationID == "" || r.Header.ContentHash == "" || r.Header.Nonce == "" || r.Header.Signature == "" {
-		return nil, 0, errors.New("incorrect header")
+	// do basic validation, unsigned reports have no nonce nor signature
+	for _, f := range []struct {
+		name, v string
+		signed  bool
+	}{
+		{"x-qn-notification-id", r.Header.NotificationID, false},
+		{"x-qn-content-hash", r.Header.ContentHash, false},
+		{"x-qn-nonce", r.Header.Nonce, true},
+		{"x-qn-signature", r.Header.Signature, true},
+	} {
+		if f.v == "" && (!f.signed || r.Header.Signed()) {
+			return nil, 0, errors.Wrapf(ErrIncorrectHeader, "missing %s", f.name)
+		}
 	}
 
-	if r.Body == "" {
-		return nil, 0, errors.New("empty body")
+	t, err := r.GetTime()
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to parse x-qn-timestamp")
 	}
+	ts := t.Unix()
 
-	ts := r.GetTimeStamp()
-	if ts == 0 {
-		return nil, ts, errors.New("failed to parse timestamp")
+	if r.Body == "" {
+		return nil, ts, ErrEmptyBody
 	}
 
-	payload := new(quiknode.Payload)
-	err := json.Unmarshal([]byte(r.Body), &payload)
+	body, err := r.Decoded()
 	if err != nil {
-		return nil,
-			r.GetTimeStamp(), err
+		return nil, ts, err
 	}
 
-	out, err := quiknode.ParsePayload(payload)
+	out, err := quiknode.Decode(r.Header.Format, bytes.NewReader(body))
 	return out, ts, err
 }
+
+// Blocks returns the block range of the logs of the report
+func (r *Report) Blocks() (quiknode.BlockRange, error) {
+	body, err := r.Decoded()
+	if err != nil {
+		return quiknode.BlockRange{}, err
+	}
+	_, blocks, err := quiknode.DecodeBlocks(r.Header.Format, bytes.NewReader(body))
+	return blocks, err
+}
+
+// EventLogs returns the EAS events of the report with the logs locating them on chain
+func (r *Report) EventLogs() ([]quiknode.EventLog, error) {
+	body, err := r.Decoded()
+	if err != nil {
+		return nil, err
+	}
+	return quiknode.DecodeEventLogs(r.Header.Format, bytes.NewReader(body))
+}
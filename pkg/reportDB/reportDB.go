@@ -1,60 +1,359 @@
 package reportdb
 
-import "sync"
+import (
+	"context"
+	"sync"
+	"time"
+
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	sDB "github.com/0xBow-io/base-eas-asp/pkg/statedb"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+var ErrReportNotFound = errors.New("report not found")
+
+// EventRef references an EAS event of a stored report
+type EventRef struct {
+	ReportID string `json:"reportID"`
+	Index    int    `json:"index"`
+}
+
+// Event is an EAS event indexed for its account
+type Event struct {
+	EventRef
+	eas.EAS
+}
+
+// PublicIDs returns the distinct accounts of the events, in order
+func PublicIDs(events []eas.EAS) []string {
+	var (
+		publicIDs []string
+		seen      = make(map[string]bool)
+	)
+	for _, e := range events {
+		if publicID := e.Account.String(); !seen[publicID] {
+			seen[publicID] = true
+			publicIDs = append(publicIDs, publicID)
+		}
+	}
+	return publicIDs
+}
+
+// storedReport is a report stored once for every account it is the
+// latest report of, with its EAS events parsed once
+type storedReport struct {
+	report Report
+	events []eas.EAS
+	refs   int
+}
+
+// account indexes the EAS events of the latest report of a public ID
+type account struct {
+	ref ReportRef
+	// report stored before ref
+	prev    ReportRef
+	order   int64
+	indices []int
+	derived sDB.MEMBERSHIP_TYPE
+	// the membership of ref isn't derived yet, retried by Rederive
+	stale bool
+}
 
 type ReportDB struct {
-	data map[string]Report
-	mut  sync.RWMutex
+	// reports by ID & the indexed events of the public IDs
+	reports  map[string]*storedReport
+	accounts map[string]*account
+	next     uint64
+	mut      sync.RWMutex
+
+	// derives the memberships, the membership of the last event if nil
+	policy   *eas.Policy
+	evidence eas.EvidenceSource
+	errs     chan<- error
 
-	// notifications on new reports for public IDs
-	notifChans []chan string
+	// notifications on new reports & membership transitions for public IDs
+	subs                map[*Subscription]struct{}
+	notifSubs           map[chan string][]func()
+	transitionSubs      map[*TransitionSubscription]struct{}
+	transitionNotifSubs map[chan Transition][]func()
+	subsMut             sync.Mutex
+}
+
+// ReportDBConfig of a ReportDB
+type ReportDBConfig struct {
+	// derives the membership of a public ID from the EAS events of its
+	// latest report, the membership of the last event if nil
+	Policy *eas.Policy
+	// attestations of the EvidenceSchemas of Policy, e.g. the country
+	// verifications, nil if Policy has none
+	Evidence eas.EvidenceSource
+	// receives the errors of the derivations retried by Rederive,
+	// nil to discard them
+	Errors chan<- error
 }
 
 func NewReportDB() *ReportDB {
-	return &ReportDB{
-		data: make(map[string]Report),
-	}
+	return NewReportDBWithConfig(ReportDBConfig{})
 }
 
-func (r *ReportDB) SubscribeToNotif(notifChan chan string) {
-	r.notifChans = append(r.notifChans, notifChan)
+// NewReportDBWithConfig derives the memberships of the public IDs by the policy
+func NewReportDBWithConfig(cfg ReportDBConfig) *ReportDB {
+	return &ReportDB{
+		reports:  make(map[string]*storedReport),
+		accounts: make(map[string]*account),
+		policy:   cfg.Policy,
+		evidence: cfg.Evidence,
+		errs:     cfg.Errors,
+
+		subs:                make(map[*Subscription]struct{}),
+		notifSubs:           make(map[chan string][]func()),
+		transitionSubs:      make(map[*TransitionSubscription]struct{}),
+		transitionNotifSubs: make(map[chan Transition][]func()),
+	}
 }
 
+// Get returns the latest report of publicID, an empty report if none
 func (r *ReportDB) Get(publicID string) Report {
 	r.mut.RLock()
 	defer r.mut.RUnlock()
-	if report, ok := r.data[publicID]; ok {
-		return report
+	if acc, ok := r.accounts[publicID]; ok {
+		return r.reports[acc.ref.ReportID].report
 	}
 	return Report{}
 }
 
-func (r *ReportDB) SendNotification(publicID string) {
-	for _, notifChan := range r.notifChans {
-		go func(c chan string) {
-			c <- publicID
-		}(notifChan)
+// GetReport returns the stored report of the ID
+func (r *ReportDB) GetReport(reportID string) (Report, bool) {
+	r.mut.RLock()
+	defer r.mut.RUnlock()
+	if s, ok := r.reports[reportID]; ok {
+		return s.report, true
 	}
+	return Report{}, false
 }
 
+// GetEvents returns the latest report of publicID
+// & its EAS events for publicID, in order
+func (r *ReportDB) GetEvents(publicID string) (Report, []Event, error) {
+	r.mut.RLock()
+	defer r.mut.RUnlock()
+
+	acc, ok := r.accounts[publicID]
+	if !ok {
+		return Report{}, nil, errors.Wrap(ErrReportNotFound, publicID)
+	}
+	s := r.reports[acc.ref.ReportID]
+	events := make([]Event, len(acc.indices))
+	for i, index := range acc.indices {
+		events[i] = Event{
+			EventRef: EventRef{ReportID: acc.ref.ReportID, Index: index},
+			EAS:      s.events[index],
+		}
+	}
+	return s.report, events, nil
+}
+
+// Size returns the number of stored reports
+func (r *ReportDB) Size() int {
+	r.mut.RLock()
+	defer r.mut.RUnlock()
+	return len(r.reports)
+}
+
+// Set stores the report of publicID if it is not older than the latest one
+// & notifies the subscribers, outside of the lock as they may block.
+// A transition is sent when the membership derived from the report differs
+// from the one of the previous reports, if the membership can't be derived
+// the error is sent to Errors & the derivation is retried by Rederive.
 func (r *ReportDB) Set(publicID string, ts int64, report Report) {
+	stored, t, err := r.set(publicID, ts, report)
+	if err != nil {
+		r.report(err)
+	}
+	if !stored {
+		return
+	}
+	r.SendNotification(publicID)
+	if t != nil {
+		r.SendTransition(*t)
+	}
+}
+
+func (r *ReportDB) set(publicID string, ts int64, report Report) (bool, *Transition, error) {
+	id := report.ID()
+
+	// parse reports seen for the first time out of the lock
+	r.mut.RLock()
+	_, seen := r.reports[id]
+	r.mut.RUnlock()
+	var events []eas.EAS
+	if !seen {
+		events, _, _ = report.Parse()
+	}
+
+	order := orderOf(ts, report)
+
+	// the attestations read by the policy are read out of the lock,
+	// the membership is derived by Rederive if they can't be read
+	evidence, err := r.readEvidence(publicID)
+
 	r.mut.Lock()
 	defer r.mut.Unlock()
 
 	// Get the latest report for the public ID
 	// And check for timestamp
-	latestReport, ok := r.data[publicID]
+	acc, ok := r.accounts[publicID]
 	if ok {
 		// if the new report is older than the latest report, ignore it
 		// if the new report is newer than the latest report, update it
-		lastTs := latestReport.GetTimeStamp()
-		if ts < lastTs {
-			return
+		if order < acc.order {
+			return false, nil, nil
 		}
-		r.data[publicID] = report
 	} else {
-		r.data[publicID] = report
+		acc = &account{derived: sDB.NONE}
+		r.accounts[publicID] = acc
 	}
 
-	r.SendNotification(publicID)
+	s, ok := r.reports[id]
+	if !ok {
+		if seen {
+			// released since
+			events, _, _ = report.Parse()
+		}
+		s = &storedReport{report: report, events: events}
+		r.reports[id] = s
+	}
+	s.refs++
+	if !acc.ref.IsZero() {
+		r.release(acc.ref.ReportID)
+	}
+
+	r.next++
+	acc.prev = acc.ref
+	acc.ref = ReportRef{Seq: r.next, Timestamp: ts, ReportID: id}
+	acc.order = order
+	acc.indices = acc.indices[:0]
+	for i, e := range s.events {
+		if e.Account.String() == publicID {
+			acc.indices = append(acc.indices, i)
+		}
+	}
+
+	// the membership derived from the events of the public ID
+	acc.stale = len(acc.indices) > 0 && err != nil
+	if len(acc.indices) == 0 {
+		return true, nil, nil
+	}
+	if err != nil {
+		return true, nil, errors.Wrap(err, "failed to derive the membership of "+publicID)
+	}
+	return true, r.derive(publicID, acc, evidence), nil
+}
+
+// derive the membership of the latest report of the account,
+// the transition is returned if it changed. r.mut is held.
+func (r *ReportDB) derive(publicID string, acc *account, evidence []eas.Evidence) *Transition {
+	s := r.reports[acc.ref.ReportID]
+	m := eas.EasTypeToMembership(s.events[acc.indices[len(acc.indices)-1]].Type)
+	if r.policy != nil {
+		accEvents := make([]eas.EAS, len(acc.indices))
+		for i, index := range acc.indices {
+			accEvents[i] = s.events[index]
+		}
+		m = r.policy.Evaluate(append(eas.EventEvidence(accEvents), evidence...), acc.ref.Timestamp).Membership
+	}
+	if m == acc.derived {
+		return nil
+	}
+	t := &Transition{PublicID: publicID, From: acc.derived, To: m, Old: acc.prev, New: acc.ref}
+	acc.derived = m
+	return t
+}
+
+// Rederive derives the memberships of the public IDs which evidence couldn't
+// be read when their latest report was stored & sends the transitions,
+// the ones which still fail are sent to Errors & retried on the next call.
+func (r *ReportDB) Rederive() {
+	r.mut.RLock()
+	var stale []string
+	for publicID, acc := range r.accounts {
+		if acc.stale {
+			stale = append(stale, publicID)
+		}
+	}
+	r.mut.RUnlock()
+
+	for _, publicID := range stale {
+		evidence, err := r.readEvidence(publicID)
+		if err != nil {
+			r.report(errors.Wrap(err, "failed to derive the membership of "+publicID))
+			continue
+		}
+		var t *Transition
+		r.mut.Lock()
+		// derived by Set meanwhile if not stale
+		if acc, ok := r.accounts[publicID]; ok && acc.stale {
+			acc.stale = false
+			t = r.derive(publicID, acc, evidence)
+		}
+		r.mut.Unlock()
+		if t != nil {
+			r.SendTransition(*t)
+		}
+	}
+}
+
+// HandleRederive calls Rederive every interval until ctx is done
+func (r *ReportDB) HandleRederive(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.Rederive()
+		}
+	}
+}
+
+// report an error of a derivation
+func (r *ReportDB) report(err error) {
+	if r.errs != nil {
+		r.errs <- err
+	}
+}
+
+// readEvidence reads the attestations of the EvidenceSchemas of the policy
+func (r *ReportDB) readEvidence(publicID string) ([]eas.Evidence, error) {
+	if r.policy == nil {
+		return nil, nil
+	}
+	schemas := r.policy.EvidenceSchemas()
+	if len(schemas) == 0 {
+		return nil, nil
+	}
+	if r.evidence == nil {
+		return nil, eas.ErrNoEvidenceSource
+	}
+	return r.evidence.Evidence(common.HexToHash(publicID), schemas)
+}
+
+// orderOf returns the nanosecond timestamp the reports are ordered by,
+// the header timestamp of the report if it is within the second ts
+func orderOf(ts int64, report Report) int64 {
+	if t, err := report.GetTime(); err == nil && t.Unix() == ts {
+		return t.UnixNano()
+	}
+	return ts * int64(time.Second)
+}
+
+// release a reference to the report, r.mut is held
+func (r *ReportDB) release(id string) {
+	if s, ok := r.reports[id]; ok {
+		if s.refs--; s.refs <= 0 {
+			delete(r.reports, id)
+		}
+	}
 }
@@ -0,0 +1,71 @@
+package reportdb
+
+import (
+	"testing"
+	"time"
+
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ReportDB_Events(t *testing.T) {
+	var (
+		a, b  = common.HexToHash("0x0a"), common.HexToHash("0x0b")
+		start = time.Now().Add(-time.Hour)
+		rDB   = NewReportDB()
+	)
+
+	// a batch report with 2 events for a & 1 for b is stored once
+	batch := easReport(t, start, eas.COINBASE_EAS_ATTEST_TOPIC, a, b, a)
+	events, ts, err := batch.Parse()
+	require.NoError(t, err)
+	publicIDs := PublicIDs(events)
+	require.Equal(t, []string{a.String(), b.String()}, publicIDs)
+	for _, publicID := range publicIDs {
+		rDB.Set(publicID, ts, batch)
+	}
+	require.Equal(t, 1, rDB.Size())
+
+	r, aEvents, err := rDB.GetEvents(a.String())
+	require.NoError(t, err)
+	require.Equal(t, batch, r)
+	require.Len(t, aEvents, 2)
+	require.Equal(t, EventRef{ReportID: batch.ID(), Index: 0}, aEvents[0].EventRef)
+	require.Equal(t, EventRef{ReportID: batch.ID(), Index: 2}, aEvents[1].EventRef)
+	require.Equal(t, events[2], aEvents[1].EAS)
+
+	_, bEvents, err := rDB.GetEvents(b.String())
+	require.NoError(t, err)
+	require.Equal(t, []Event{{EventRef: EventRef{ReportID: batch.ID(), Index: 1}, EAS: events[1]}}, bEvents)
+
+	_, _, err = rDB.GetEvents(common.HexToHash("0x0c").String())
+	require.ErrorIs(t, err, ErrReportNotFound)
+
+	// the same body with another header is deduplicated
+	resent := batch
+	resent.Header.Nonce = "resent"
+	rDB.Set(b.String(), ts, resent)
+	require.Equal(t, 1, rDB.Size())
+	require.Equal(t, batch, rDB.Get(b.String()))
+
+	// the batch is released once no account references it
+	next := easReport(t, start.Add(time.Second), eas.COINBASE_EAS_ATTEST_TOPIC, a, b)
+	rDB.Set(a.String(), next.GetTimeStamp(), next)
+	require.Equal(t, 2, rDB.Size())
+	rDB.Set(b.String(), next.GetTimeStamp(), next)
+	require.Equal(t, 1, rDB.Size())
+	_, ok := rDB.GetReport(batch.ID())
+	require.False(t, ok)
+	got, ok := rDB.GetReport(next.ID())
+	require.True(t, ok)
+	require.Equal(t, next, got)
+
+	// a report without event for the account
+	other := easReport(t, start.Add(2*time.Second), eas.COINBASE_EAS_ATTEST_TOPIC, b)
+	rDB.Set(a.String(), other.GetTimeStamp(), other)
+	r, aEvents, err = rDB.GetEvents(a.String())
+	require.NoError(t, err)
+	require.Equal(t, other, r)
+	require.Empty(t, aEvents)
+}
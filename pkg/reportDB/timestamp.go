@@ -0,0 +1,93 @@
+package reportdb
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidTimestamp = errors.New("invalid timestamp")
+
+// layouts of the header timestamps, Header_Time_Layout is the format of
+// time.Time.String() which QuickNode uses for the webhook headers
+var timestampLayouts = []string{
+	Header_Time_Layout,
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999 -0700",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// ParseTimestamp parses a header timestamp with nanosecond precision.
+// Accepted formats are time.Time.String() with or without the monotonic
+// clock reading (m=±...), RFC3339 & Unix timestamps in seconds, with an
+// optional fraction, milliseconds, microseconds or nanoseconds.
+func ParseTimestamp(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, errors.Wrap(ErrInvalidTimestamp, "empty timestamp")
+	}
+	// the monotonic clock reading is only meaningful to the process that wrote it
+	if i := strings.Index(s, " m="); i >= 0 {
+		s = s[:i]
+	}
+
+	if isUnix(s) {
+		return parseUnix(s)
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Wrapf(ErrInvalidTimestamp, "unknown format %q", s)
+}
+
+func isUnix(s string) bool {
+	digits, dot := 0, false
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			digits++
+		case c == '.' && !dot:
+			dot = true
+		default:
+			return false
+		}
+	}
+	return digits > 0
+}
+
+// parseUnix parses a Unix timestamp, the unit of an integer timestamp
+// is derived from its magnitude & a fraction is parsed without rounding
+func parseUnix(s string) (time.Time, error) {
+	sec, frac, _ := strings.Cut(s, ".")
+	n, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(ErrInvalidTimestamp, "unix timestamp %q", s)
+	}
+
+	if frac == "" {
+		switch {
+		case n < 1e11:
+			return time.Unix(n, 0).UTC(), nil
+		case n < 1e14:
+			return time.UnixMilli(n).UTC(), nil
+		case n < 1e17:
+			return time.UnixMicro(n).UTC(), nil
+		default:
+			return time.Unix(0, n).UTC(), nil
+		}
+	}
+
+	if len(frac) > 9 {
+		frac = frac[:9]
+	}
+	nsec, err := strconv.ParseInt(frac+strings.Repeat("0", 9-len(frac)), 10, 64)
+	if err != nil || n >= 1e11 {
+		return time.Time{}, errors.Wrapf(ErrInvalidTimestamp, "unix timestamp %q", s)
+	}
+	return time.Unix(n, nsec).UTC(), nil
+}
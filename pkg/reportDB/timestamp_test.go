@@ -0,0 +1,110 @@
+package reportdb
+
+import (
+	"testing"
+	"time"
+
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseTimestamp(t *testing.T) {
+	want := time.Date(2024, 3, 12, 4, 4, 14, 113308240, time.UTC)
+
+	for _, tc := range []struct {
+		name      string
+		timestamp string
+		want      time.Time
+		err       bool
+	}{
+		{name: "monotonic", timestamp: "2024-03-12 04:04:14.11330824 +0000 UTC m=+17208.257632042", want: want},
+		{name: "negative monotonic", timestamp: "2024-03-12 04:04:14.11330824 +0000 UTC m=-0.5", want: want},
+		{name: "header layout", timestamp: "2024-03-12 04:04:14.11330824 +0000 UTC", want: want},
+		{name: "no fraction", timestamp: "2024-03-12 04:04:14 +0000 UTC", want: want.Truncate(time.Second)},
+		{name: "offset", timestamp: "2024-03-12 05:04:14.11330824 +0100 CET", want: want},
+		{name: "no zone name", timestamp: "2024-03-12 04:04:14.11330824 +0000", want: want},
+		{name: "rfc3339", timestamp: "2024-03-12T04:04:14Z", want: want.Truncate(time.Second)},
+		{name: "rfc3339 nano", timestamp: "2024-03-12T04:04:14.11330824Z", want: want},
+		{name: "rfc3339 offset", timestamp: "2024-03-12T06:04:14.11330824+02:00", want: want},
+		{name: "unix", timestamp: "1710216254", want: want.Truncate(time.Second)},
+		{name: "unix fraction", timestamp: "1710216254.11330824", want: want},
+		{name: "unix fraction truncated", timestamp: "1710216254.1133082401", want: want},
+		{name: "unix millis", timestamp: "1710216254113", want: want.Truncate(time.Millisecond)},
+		{name: "unix micros", timestamp: "1710216254113308", want: want.Truncate(time.Microsecond)},
+		{name: "unix nanos", timestamp: "1710216254113308240", want: want},
+		{name: "spaces", timestamp: " 1710216254 ", want: want.Truncate(time.Second)},
+		{name: "empty", timestamp: "", err: true},
+		{name: "garbage", timestamp: "yesterday", err: true},
+		{name: "dot", timestamp: ".", err: true},
+		{name: "fraction of millis", timestamp: "1710216254113.5", err: true},
+		{name: "negative", timestamp: "-1710216254", err: true},
+		{name: "date only", timestamp: "2024-03-12", err: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseTimestamp(tc.timestamp)
+			if tc.err {
+				require.ErrorIs(t, err, ErrInvalidTimestamp)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, tc.want.Equal(got), "want %s, got %s", tc.want, got)
+		})
+	}
+}
+
+func Test_Report_Parse_Errors(t *testing.T) {
+	r := easReport(t, time.Now(), eas.COINBASE_EAS_ATTEST_TOPIC, common.HexToHash("0x0a"))
+	_, _, err := r.Parse()
+	require.NoError(t, err)
+
+	missing := r
+	missing.Header.Nonce = ""
+	_, _, err = missing.Parse()
+	require.ErrorIs(t, err, ErrIncorrectHeader)
+	require.Contains(t, err.Error(), "x-qn-nonce")
+
+	// unsigned reports have no nonce nor signature
+	unsigned := missing
+	unsigned.Header.Source, unsigned.Header.Signature = SourceRPC, ""
+	_, _, err = unsigned.Parse()
+	require.NoError(t, err)
+	unsigned.Header.ContentHash = ""
+	_, _, err = unsigned.Parse()
+	require.ErrorIs(t, err, ErrIncorrectHeader)
+
+	invalid := r
+	invalid.Header.Timestamp = "yesterday"
+	_, _, err = invalid.Parse()
+	require.ErrorIs(t, err, ErrInvalidTimestamp)
+	require.Zero(t, invalid.GetTimeStamp())
+	require.Zero(t, invalid.GetTimeStampNano())
+
+	empty := r
+	empty.Body = ""
+	_, ts, err := empty.Parse()
+	require.ErrorIs(t, err, ErrEmptyBody)
+	require.Equal(t, r.GetTimeStamp(), ts)
+}
+
+func Test_ReportDB_NanoOrder(t *testing.T) {
+	var (
+		a     = common.HexToHash("0x0a")
+		start = time.Now().Truncate(time.Second).Add(-time.Hour)
+		rDB   = NewReportDB()
+	)
+
+	// reports within the same second are ordered by their nanoseconds
+	later := easReport(t, start.Add(500*time.Millisecond), eas.COINBASE_EAS_ATTEST_TOPIC, a)
+	earlier := easReport(t, start.Add(100*time.Millisecond), revokeTopic, a)
+	require.Equal(t, later.GetTimeStamp(), earlier.GetTimeStamp())
+	require.Less(t, earlier.GetTimeStampNano(), later.GetTimeStampNano())
+
+	rDB.Set(a.String(), later.GetTimeStamp(), later)
+	rDB.Set(a.String(), earlier.GetTimeStamp(), earlier)
+	require.Equal(t, later, rDB.Get(a.String()))
+
+	last := easReport(t, start.Add(900*time.Millisecond), revokeTopic, a)
+	rDB.Set(a.String(), last.GetTimeStamp(), last)
+	require.Equal(t, last, rDB.Get(a.String()))
+}
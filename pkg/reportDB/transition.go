@@ -0,0 +1,121 @@
+package reportdb
+
+import (
+	"fmt"
+
+	sDB "github.com/0xBow-io/base-eas-asp/pkg/statedb"
+)
+
+// ReportRef references a report stored for a public ID
+type ReportRef struct {
+	// order in which the report was stored, starting at 1
+	Seq       uint64 `json:"seq"`
+	Timestamp int64  `json:"timestamp"`
+	ReportID  string `json:"reportID"`
+}
+
+// IsZero returns true if no report is referenced
+func (ref ReportRef) IsZero() bool {
+	return ref.Seq == 0
+}
+
+// Transition of the membership derived from the reports of a public ID,
+// Old references the report stored before New, if any.
+type Transition struct {
+	PublicID string              `json:"publicID"`
+	From     sDB.MEMBERSHIP_TYPE `json:"from"`
+	To       sDB.MEMBERSHIP_TYPE `json:"to"`
+	Old      ReportRef           `json:"old"`
+	New      ReportRef           `json:"new"`
+}
+
+func (t Transition) String() string {
+	return fmt.Sprintf("%s: %s→%s", t.PublicID, t.From, t.To)
+}
+
+// GetMembership returns the membership derived from the reports of publicID
+// & the reference of its latest report
+func (r *ReportDB) GetMembership(publicID string) (sDB.MEMBERSHIP_TYPE, ReportRef) {
+	r.mut.RLock()
+	defer r.mut.RUnlock()
+	if acc, ok := r.accounts[publicID]; ok {
+		return acc.derived, acc.ref
+	}
+	return sDB.NONE, ReportRef{}
+}
+
+// TransitionSubscription to the membership transitions,
+// with Coalesce the queued transitions of a public ID are merged.
+type TransitionSubscription struct {
+	*queue[Transition]
+	rDb *ReportDB
+}
+
+// SubscribeTransitions to the membership transitions with a queue of size
+// transitions, DefaultQueueSize if size <= 0.
+func (r *ReportDB) SubscribeTransitions(policy Policy, size int) *TransitionSubscription {
+	s := &TransitionSubscription{
+		queue: newQueue[Transition](policy, size, func(t Transition) string { return t.PublicID }, mergeTransitions),
+		rDb:   r,
+	}
+	r.subsMut.Lock()
+	r.transitionSubs[s] = struct{}{}
+	r.subsMut.Unlock()
+	return s
+}
+
+// Unsubscribe drops the queued transitions & closes C,
+// writers blocked by the subscription are released.
+func (s *TransitionSubscription) Unsubscribe() {
+	s.rDb.subsMut.Lock()
+	delete(s.rDb.transitionSubs, s)
+	s.rDb.subsMut.Unlock()
+	s.close()
+}
+
+// SubscribeToTransitionNotif forwards the membership transitions to
+// notifChan with the Coalesce policy until UnsubscribeFromTransitionNotif is called.
+func (r *ReportDB) SubscribeToTransitionNotif(notifChan chan Transition) {
+	s := r.SubscribeTransitions(Coalesce, DefaultQueueSize)
+
+	r.subsMut.Lock()
+	r.transitionNotifSubs[notifChan] = append(r.transitionNotifSubs[notifChan], s.Unsubscribe)
+	r.subsMut.Unlock()
+
+	go forward(s.queue, notifChan)
+}
+
+// UnsubscribeFromTransitionNotif stops the transitions to notifChan,
+// notifChan is not closed.
+func (r *ReportDB) UnsubscribeFromTransitionNotif(notifChan chan Transition) {
+	r.subsMut.Lock()
+	unsubscribe := r.transitionNotifSubs[notifChan]
+	delete(r.transitionNotifSubs, notifChan)
+	r.subsMut.Unlock()
+
+	for _, fn := range unsubscribe {
+		fn()
+	}
+}
+
+// SendTransition queues t for every transition subscription
+func (r *ReportDB) SendTransition(t Transition) {
+	r.subsMut.Lock()
+	subs := make([]*TransitionSubscription, 0, len(r.transitionSubs))
+	for s := range r.transitionSubs {
+		subs = append(subs, s)
+	}
+	r.subsMut.Unlock()
+
+	for _, s := range subs {
+		s.publish(t)
+	}
+}
+
+// mergeTransitions folds t into the queued transition of the same public ID,
+// a→b & b→c become a→c, a→b & b→a cancel out.
+func mergeTransitions(queued *Transition, t Transition) bool {
+	queued.To = t.To
+	queued.New = t.New
+	return queued.From != queued.To
+}
@@ -0,0 +1,258 @@
+package reportdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	eas "github.com/0xBow-io/base-eas-asp/pkg/base_eas"
+	sDB "github.com/0xBow-io/base-eas-asp/pkg/statedb"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// easReport returns a report with an EAS event of topic for each account
+func easReport(t *testing.T, ts time.Time, topic string, accounts ...common.Hash) Report {
+	var logs []interface{}
+	for i, account := range accounts {
+		logs = append(logs, map[string]interface{}{
+			"address":         eas.BASE_EAS_ADDR,
+			"topics":          []string{topic, account.Hex(), eas.COINBASE_EAS_HASH, eas.COINBASE_EAS_SCHEMA_ID},
+			"data":            common.BigToHash(common.Big1).Hex(),
+			"blockNumber":     "0xb2bbad",
+			"transactionHash": common.BigToHash(common.Big2).Hex(),
+			"logIndex":        fmt.Sprintf("%#x", i),
+			"removed":         false,
+		})
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"matchedReceipts":     []interface{}{map[string]interface{}{"logs": logs}},
+		"matchedTransactions": []interface{}{map[string]interface{}{"chainId": eas.BASE_CHAIN_ID}},
+	})
+	require.NoError(t, err)
+
+	return Report{
+		Header: ReportHeader{
+			NotificationID: "feed",
+			ContentHash:    ts.String(),
+			Nonce:          "nonce",
+			Signature:      "signature",
+			Timestamp:      ts.Format(Header_Time_Layout),
+		},
+		Body: string(body),
+	}
+}
+
+const revokeTopic = eas.COINBASE_EAS_REVOKE_TOPIC
+
+func Test_Transitions(t *testing.T) {
+	var (
+		a, b  = common.HexToHash("0x0a"), common.HexToHash("0x0b")
+		start = time.Now().Add(-time.Hour)
+		at    = func(i int) time.Time { return start.Add(time.Duration(i) * time.Second) }
+	)
+
+	rDB := NewReportDB()
+	sub := rDB.SubscribeTransitions(Block, 0)
+	defer sub.Unsubscribe()
+	set := func(publicID common.Hash, r Report) {
+		rDB.Set(publicID.String(), r.GetTimeStamp(), r)
+	}
+
+	attest := easReport(t, at(0), eas.COINBASE_EAS_ATTEST_TOPIC, a, b)
+	set(a, attest)
+	set(b, attest)
+	got := receiveTransitions(t, sub, 2)
+	require.Equal(t, Transition{
+		PublicID: a.String(),
+		From:     sDB.NONE,
+		To:       sDB.INCLUSION,
+		New:      ReportRef{Seq: 1, Timestamp: attest.GetTimeStamp(), ReportID: attest.ID()},
+	}, got[0])
+	require.Equal(t, b.String(), got[1].PublicID)
+	require.True(t, got[1].Old.IsZero())
+
+	// the same result, an older report & a report without event for a
+	// are not transitions
+	set(a, easReport(t, at(1), eas.COINBASE_EAS_ATTEST_TOPIC, a))
+	set(a, easReport(t, at(-1), revokeTopic, a))
+	set(a, easReport(t, at(2), revokeTopic, b))
+	requireNoTransition(t, sub)
+
+	// inclusion→exclusion
+	revoke := easReport(t, at(3), revokeTopic, a)
+	set(a, revoke)
+	got = receiveTransitions(t, sub, 1)
+	require.Equal(t, sDB.INCLUSION, got[0].From)
+	require.Equal(t, sDB.EXCLUSION, got[0].To)
+	require.Equal(t, uint64(4), got[0].Old.Seq)
+	require.Equal(t, uint64(5), got[0].New.Seq)
+	require.Equal(t, a.String()+": inclusion→exclusion", got[0].String())
+
+	m, ref := rDB.GetMembership(a.String())
+	require.Equal(t, sDB.EXCLUSION, m)
+	require.Equal(t, got[0].New, ref)
+	m, ref = rDB.GetMembership(common.HexToHash("0x0c").String())
+	require.Equal(t, sDB.NONE, m)
+	require.True(t, ref.IsZero())
+}
+
+func Test_Transitions_Coalesce(t *testing.T) {
+	var (
+		a, b  = common.HexToHash("0x0a"), common.HexToHash("0x0b")
+		start = time.Now().Add(-time.Hour)
+		at    = func(i int) time.Time { return start.Add(time.Duration(i) * time.Second) }
+	)
+
+	rDB := NewReportDB()
+	sub := rDB.SubscribeTransitions(Coalesce, 0)
+	defer sub.Unsubscribe()
+	set := func(publicID common.Hash, r Report) {
+		rDB.Set(publicID.String(), r.GetTimeStamp(), r)
+	}
+
+	// hold the delivery with a first transition
+	set(b, easReport(t, at(0), eas.COINBASE_EAS_ATTEST_TOPIC, b))
+	require.Eventually(t, func() bool { return sub.len() == 0 }, time.Second, time.Millisecond)
+
+	// none→inclusion & inclusion→exclusion are merged
+	set(a, easReport(t, at(1), eas.COINBASE_EAS_ATTEST_TOPIC, a))
+	set(a, easReport(t, at(2), revokeTopic, a))
+	// inclusion→exclusion & exclusion→inclusion cancel out
+	set(b, easReport(t, at(3), revokeTopic, b))
+	set(b, easReport(t, at(4), eas.COINBASE_EAS_ATTEST_TOPIC, b))
+
+	got := receiveTransitions(t, sub, 2)
+	require.Equal(t, b.String()+": none→inclusion", got[0].String())
+	require.Equal(t, a.String()+": none→exclusion", got[1].String())
+	require.True(t, got[1].Old.IsZero())
+	require.Equal(t, uint64(3), got[1].New.Seq)
+	requireNoTransition(t, sub)
+
+	// notification channels
+	notifChan := make(chan Transition)
+	rDB.SubscribeToTransitionNotif(notifChan)
+	set(a, easReport(t, at(5), eas.COINBASE_EAS_ATTEST_TOPIC, a))
+	require.Equal(t, sDB.INCLUSION, (<-notifChan).To)
+	rDB.UnsubscribeFromTransitionNotif(notifChan)
+}
+
+func receiveTransitions(t *testing.T, sub *TransitionSubscription, n int) []Transition {
+	var out []Transition
+	for len(out) < n {
+		select {
+		case tr := <-sub.C():
+			out = append(out, tr)
+		case <-time.After(time.Second):
+			require.FailNow(t, "missing transitions", "got %d of %d", len(out), n)
+		}
+	}
+	return out
+}
+
+func requireNoTransition(t *testing.T, sub *TransitionSubscription) {
+	select {
+	case tr := <-sub.C():
+		require.FailNow(t, "unexpected transition", tr.String())
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+// countries serves the country verifications of the accounts
+type countries struct {
+	codes map[common.Hash]string
+	err   error
+}
+
+func (c *countries) Evidence(account common.Hash, schemas []common.Hash) ([]eas.Evidence, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	code, ok := c.codes[account]
+	if !ok {
+		return nil, nil
+	}
+	return []eas.Evidence{{
+		UID:    account,
+		Schema: common.HexToHash(eas.COINBASE_EAS_COUNTRY_SCHEMA_ID),
+		Fields: map[string]string{"country": code},
+	}}, nil
+}
+
+func Test_Transitions_Policy(t *testing.T) {
+	var (
+		a, b  = common.HexToHash("0x0a"), common.HexToHash("0x0b")
+		start = time.Now().Add(-time.Hour)
+		at    = func(i int) time.Time { return start.Add(time.Duration(i) * time.Second) }
+	)
+	policy := &eas.Policy{
+		Rules: []eas.Rule{
+			{Name: "revoked", Membership: sDB.EXCLUSION, When: eas.Condition{Attestation: &eas.Match{State: eas.StateRevoked}}},
+			{Name: "verified", Membership: sDB.INCLUSION, When: eas.Condition{All: []eas.Condition{
+				{Attestation: &eas.Match{Schema: "account", State: eas.StateValid}},
+				{Attestation: &eas.Match{Schema: "country", State: eas.StateValid, Field: "country", NotIn: []string{"KP"}}},
+			}}},
+		},
+		Default: sDB.PARTIAL_INCLUSION,
+	}
+	require.NoError(t, policy.Validate())
+	src := &countries{codes: map[common.Hash]string{a: "US", b: "KP"}}
+
+	errs := make(chan error, 1)
+	rDB := NewReportDBWithConfig(ReportDBConfig{Policy: policy, Evidence: src, Errors: errs})
+	sub := rDB.SubscribeTransitions(Block, 0)
+	defer sub.Unsubscribe()
+	set := func(publicID common.Hash, r Report) {
+		rDB.Set(publicID.String(), r.GetTimeStamp(), r)
+	}
+
+	// the country of b is denied
+	attest := easReport(t, at(0), eas.COINBASE_EAS_ATTEST_TOPIC, a, b)
+	set(a, attest)
+	set(b, attest)
+	got := receiveTransitions(t, sub, 2)
+	require.Equal(t, a.String()+": none→inclusion", got[0].String())
+	require.Equal(t, b.String()+": none→partial_inclusion", got[1].String())
+
+	// the membership isn't derived while the evidence can't be read,
+	// the derivation is retried by Rederive
+	src.err = eas.ErrAttestationNotFound
+	revoke := easReport(t, at(1), revokeTopic, a)
+	set(a, revoke)
+	require.ErrorIs(t, <-errs, eas.ErrAttestationNotFound)
+	requireNoTransition(t, sub)
+	require.Equal(t, revoke.Body, rDB.Get(a.String()).Body)
+	m, _ := rDB.GetMembership(a.String())
+	require.Equal(t, sDB.INCLUSION, m)
+
+	rDB.Rederive()
+	require.ErrorContains(t, <-errs, "failed to derive the membership of "+a.String())
+	requireNoTransition(t, sub)
+
+	src.err = nil
+	rDB.Rederive()
+	got = receiveTransitions(t, sub, 1)
+	require.Equal(t, a.String()+": inclusion→exclusion", got[0].String())
+	require.Equal(t, revoke.ID(), got[0].New.ReportID)
+	rDB.Rederive()
+	requireNoTransition(t, sub)
+
+	// a newer report is derived by Set
+	src.err = eas.ErrAttestationNotFound
+	set(a, easReport(t, at(2), revokeTopic, a))
+	<-errs
+	src.err = nil
+	set(a, easReport(t, at(3), eas.COINBASE_EAS_ATTEST_TOPIC, a))
+	got = receiveTransitions(t, sub, 1)
+	require.Equal(t, a.String()+": exclusion→inclusion", got[0].String())
+	rDB.Rederive()
+	requireNoTransition(t, sub)
+
+	// the policy requires the evidence source
+	rDB = NewReportDBWithConfig(ReportDBConfig{Policy: policy})
+	set(a, attest)
+	m, ref := rDB.GetMembership(a.String())
+	require.Equal(t, sDB.NONE, m)
+	require.False(t, ref.IsZero())
+}
@@ -3,7 +3,18 @@ package statedb
 type MEMBERSHIP_TYPE string
 
 const (
+	NONE              = MEMBERSHIP_TYPE("none")
 	INCLUSION         = MEMBERSHIP_TYPE("inclusion")
 	EXCLUSION         = MEMBERSHIP_TYPE("exclusion")
 	PARTIAL_INCLUSION = MEMBERSHIP_TYPE("partial_inclusion")
 )
+
+// IsValid returns true for memberships a namespace can be set to
+func (m MEMBERSHIP_TYPE) IsValid() bool {
+	switch m {
+	case INCLUSION, EXCLUSION, PARTIAL_INCLUSION:
+		return true
+	default:
+		return false
+	}
+}
@@ -1,14 +1,120 @@
 package statedb
 
 import (
+	"sync"
+
 	nmt "github.com/0xBow-io/base-eas-asp/pkg/nmt"
+	pp "github.com/0xBow-io/base-eas-asp/pkg/privacy_pool"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrNsNotFound         = errors.New("namespace not found")
+	ErrMembershipMismatch = errors.New("membership does not match the expected value")
+	ErrInvalidMembership  = errors.New("invalid membership type")
 )
 
 type StateDB struct {
+	mut sync.RWMutex
+
 	// Contains event data stored as namespace records
-	namespaceGroups nmt.NamespaceGroups
+	namespaceGroups *nmt.NsGroups
+
+	// membership of every namespace, keyed by NsKey
+	memberships map[string]MEMBERSHIP_TYPE
 }
 
 func NewStateDB() (*StateDB, error) {
-	return nil, nil
+	return &StateDB{
+		namespaceGroups: nmt.NewNsGroups(32),
+		memberships:     make(map[string]MEMBERSHIP_TYPE),
+	}, nil
+}
+
+// NsKey returns the key of a namespace
+// which matches the hex of the EAS account
+func NsKey(ns []byte) string {
+	return common.BytesToHash(ns).Hex()
+}
+
+// AddEvent stores the event as a record of its namespace
+func (db *StateDB) AddEvent(se pp.SerialEvent) error {
+	if err := se.Validate(); err != nil {
+		return errors.Wrap(err, "invalid event")
+	}
+
+	db.mut.Lock()
+	defer db.mut.Unlock()
+
+	if _, _, err := db.namespaceGroups.Add(nmt.Record(se[:])); err != nil {
+		return errors.Wrap(err, "failed to add event")
+	}
+	return nil
+}
+
+func (db *StateDB) NsExists(ns string) bool {
+	db.mut.RLock()
+	defer db.mut.RUnlock()
+	return db.nsExists(ns)
+}
+
+func (db *StateDB) nsExists(ns string) bool {
+	return db.namespaceGroups.GetRecords(common.HexToHash(ns).Bytes()) != nil
+}
+
+// GetMembership returns NONE for namespaces without a membership
+func (db *StateDB) GetMembership(ns string) MEMBERSHIP_TYPE {
+	db.mut.RLock()
+	defer db.mut.RUnlock()
+	if m, ok := db.memberships[ns]; ok {
+		return m
+	}
+	return NONE
+}
+
+// CompareAndSetMembership atomically updates the membership of ns to membership
+// if its current membership is expected.
+func (db *StateDB) CompareAndSetMembership(ns string, expected, membership MEMBERSHIP_TYPE) error {
+	if !membership.IsValid() {
+		return errors.Wrap(ErrInvalidMembership, string(membership))
+	}
+
+	db.mut.Lock()
+	defer db.mut.Unlock()
+
+	if !db.nsExists(ns) {
+		return errors.Wrap(ErrNsNotFound, ns)
+	}
+
+	current, ok := db.memberships[ns]
+	if !ok {
+		current = NONE
+	}
+	if current != expected {
+		return errors.Wrapf(ErrMembershipMismatch, "got %s want %s", current, expected)
+	}
+
+	db.memberships[ns] = membership
+	return nil
+}
+
+// MembershipSet returns a copy of the records of
+// every namespace with the membership m
+func (db *StateDB) MembershipSet(m MEMBERSHIP_TYPE) (*nmt.NsGroups, error) {
+	db.mut.RLock()
+	defer db.mut.RUnlock()
+
+	set := nmt.NewNsGroups(db.namespaceGroups.NamespaceSize())
+	for _, ns := range db.namespaceGroups.ValidateAndSort() {
+		if membership, ok := db.memberships[NsKey(ns)]; !ok || membership != m {
+			continue
+		}
+		for _, rec := range db.namespaceGroups.GetRecords(ns) {
+			if _, _, err := set.Add(rec); err != nil {
+				return nil, errors.Wrap(err, "failed to copy record")
+			}
+		}
+	}
+	return set, nil
 }